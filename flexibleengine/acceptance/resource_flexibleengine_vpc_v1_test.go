@@ -6,28 +6,35 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/config"
 
 	"github.com/chnsz/golangsdk/openstack/networking/v1/vpcs"
+
+	"github.com/FlexibleEngineCloud/terraform-provider-flexibleengine/flexibleengine"
 )
 
 func TestAccVpcV1_basic(t *testing.T) {
 	var vpc vpcs.Vpc
 
+	// the test can be recorded and replayed with OS_RECORDER_MODE, its cassette is not committed
+	// and must be recorded against a real environment before replaying
+	recorder := flexibleengine.NewAccRecorder(t.Name())
+	provider := recorder.Provider()
 	resourceName := "flexibleengine_vpc_v1.vpc_1"
-	rName := fmt.Sprintf("vpc-acc-test-%s", acctest.RandString(5))
+	rName := recorder.RandomName("vpc-acc-test")
 	rNameUpdate := rName + "-updated"
 
 	resource.ParallelTest(t, resource.TestCase{
 		PreCheck:          func() { testAccPreCheck(t) },
-		ProviderFactories: TestAccProviderFactories,
-		CheckDestroy:      testAccCheckVpcV1Destroy,
+		ProviderFactories: recorder.ProviderFactories(),
+		CheckDestroy:      testAccCheckVpcV1DestroyWith(provider),
 		Steps: []resource.TestStep{
 			{
 				Config: testAccVpcV1_basic(rName),
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckVpcV1Exists(resourceName, &vpc),
+					testAccCheckVpcV1ExistsWith(provider, resourceName, &vpc),
 					resource.TestCheckResourceAttr(resourceName, "name", rName),
 					resource.TestCheckResourceAttr(resourceName, "cidr", "192.168.0.0/16"),
 					resource.TestCheckResourceAttr(resourceName, "status", "OK"),
@@ -39,7 +46,7 @@ func TestAccVpcV1_basic(t *testing.T) {
 			{
 				Config: testAccVpcV1_update(rNameUpdate),
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckVpcV1Exists(resourceName, &vpc),
+					testAccCheckVpcV1ExistsWith(provider, resourceName, &vpc),
 					resource.TestCheckResourceAttr(resourceName, "name", rNameUpdate),
 					resource.TestCheckResourceAttr(resourceName, "description", "updated by acc test"),
 					resource.TestCheckResourceAttr(resourceName, "tags.key", "value_updated"),
@@ -98,27 +105,37 @@ func TestAccVpcV1_secondaryCIDR(t *testing.T) {
 }
 
 func testAccCheckVpcV1Destroy(s *terraform.State) error {
-	conf := testAccProvider.Meta().(*config.Config)
-	vpcClient, err := conf.NetworkingV1Client(OS_REGION_NAME)
-	if err != nil {
-		return fmt.Errorf("Error creating FlexibleEngine vpc client: %s", err)
-	}
+	return testAccCheckVpcV1DestroyWith(testAccProvider)(s)
+}
 
-	for _, rs := range s.RootModule().Resources {
-		if rs.Type != "flexibleengine_vpc_v1" {
-			continue
+func testAccCheckVpcV1DestroyWith(provider *schema.Provider) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		conf := provider.Meta().(*config.Config)
+		vpcClient, err := conf.NetworkingV1Client(OS_REGION_NAME)
+		if err != nil {
+			return fmt.Errorf("Error creating FlexibleEngine vpc client: %s", err)
 		}
 
-		_, err := vpcs.Get(vpcClient, rs.Primary.ID).Extract()
-		if err == nil {
-			return fmt.Errorf("Vpc still exists")
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "flexibleengine_vpc_v1" {
+				continue
+			}
+
+			_, err := vpcs.Get(vpcClient, rs.Primary.ID).Extract()
+			if err == nil {
+				return fmt.Errorf("Vpc still exists")
+			}
 		}
-	}
 
-	return nil
+		return nil
+	}
 }
 
 func testAccCheckVpcV1Exists(n string, vpc *vpcs.Vpc) resource.TestCheckFunc {
+	return testAccCheckVpcV1ExistsWith(testAccProvider, n, vpc)
+}
+
+func testAccCheckVpcV1ExistsWith(provider *schema.Provider, n string, vpc *vpcs.Vpc) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
 		if !ok {
@@ -129,7 +146,7 @@ func testAccCheckVpcV1Exists(n string, vpc *vpcs.Vpc) resource.TestCheckFunc {
 			return fmt.Errorf("No ID is set")
 		}
 
-		conf := testAccProvider.Meta().(*config.Config)
+		conf := provider.Meta().(*config.Config)
 		vpcClient, err := conf.NetworkingV1Client(OS_REGION_NAME)
 		if err != nil {
 			return fmt.Errorf("Error creating FlexibleEngine vpc client: %s", err)
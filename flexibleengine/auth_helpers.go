@@ -20,11 +20,7 @@ import (
 func GetCredentials(c *Config) (*awsCredentials.Credentials, error) {
 	// build a chain provider, lazy-evaluated by aws-sdk
 	providers := []awsCredentials.Provider{
-		&awsCredentials.StaticProvider{Value: awsCredentials.Value{
-			AccessKeyID:     c.AccessKey,
-			SecretAccessKey: c.SecretKey,
-			SessionToken:    c.SecurityToken,
-		}},
+		&configCredentialsProvider{config: c},
 		&awsCredentials.EnvProvider{},
 		&awsCredentials.SharedCredentialsProvider{
 			Filename: "",
@@ -95,3 +91,29 @@ func setOptionalEndpoint(cfg *aws.Config) string {
 	}
 	return ""
 }
+
+// configCredentialsProvider provides the credentials of the provider to the S3 session,
+// the temporary credentials are retrieved again before every request.
+type configCredentialsProvider struct {
+	config *Config
+}
+
+func (p *configCredentialsProvider) Retrieve() (awsCredentials.Value, error) {
+	creds, err := currentCredentials(p.config)
+	if err != nil {
+		return awsCredentials.Value{ProviderName: "FlexibleEngineProvider"}, err
+	}
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return awsCredentials.Value{ProviderName: "FlexibleEngineProvider"}, awsCredentials.ErrStaticCredentialsEmpty
+	}
+	return awsCredentials.Value{
+		AccessKeyID:     creds.AccessKey,
+		SecretAccessKey: creds.SecretKey,
+		SessionToken:    creds.SecurityToken,
+		ProviderName:    "FlexibleEngineProvider",
+	}, nil
+}
+
+func (p *configCredentialsProvider) IsExpired() bool {
+	return getConfigExtension(p.config).Credentials != nil
+}
@@ -0,0 +1,205 @@
+package flexibleengine
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/cce/v3/clusters"
+)
+
+// cceKubernetesClient sends requests to the Kubernetes API of a CCE cluster,
+// it authenticates with the client certificate issued by CCE.
+type cceKubernetesClient struct {
+	Endpoint   string
+	HTTPClient *http.Client
+	// Expiry is the time when the client certificate expires
+	Expiry time.Time
+}
+
+// cceKubernetesClientRenewal is the remaining validity of the client certificate within which
+// the cached client is replaced by a new one
+const cceKubernetesClientRenewal = time.Hour
+
+// cceKubernetesStatusError is the Status returned by the Kubernetes API for the failed requests
+type cceKubernetesStatusError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *cceKubernetesStatusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, e.Reason, e.Message)
+}
+
+// isCCEKubernetesStatus reports whether err is a Status of the Kubernetes API with the code
+func isCCEKubernetesStatus(err error, code int) bool {
+	statusErr, ok := err.(*cceKubernetesStatusError)
+	return ok && statusErr.Code == code
+}
+
+// newCCEKubernetesClient issues the certificates of the cluster which are valid for one day.
+// The endpoints whose certificates are verified by the cluster CA are preferred, the certificate of
+// the external endpoint is only skipped to verify if insecure is true.
+func newCCEKubernetesClient(cceClient *golangsdk.ServiceClient, clusterID string,
+	insecure bool) (*cceKubernetesClient, error) {
+	cert, err := issueCCEClusterCert(cceClient, clusterID, 1)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving the certificates of CCE cluster %s: %s", clusterID, err)
+	}
+
+	contextName := selectCCEKubernetesContext(cert, insecure)
+	kubeConf, err := buildCCEClusterKubeConfig(cert, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("error building the kubeconfig of CCE cluster %s: %s", clusterID, err)
+	}
+
+	cluster := kubeConf.Clusters[0].Cluster
+	user := kubeConf.Users[0].User
+	keyPair, err := tls.X509KeyPair([]byte(decodeKubeConfigData(user.ClientCertificateData)),
+		[]byte(decodeKubeConfigData(user.ClientKeyData)))
+	if err != nil {
+		return nil, fmt.Errorf("error loading the client certificate of CCE cluster %s: %s", clusterID, err)
+	}
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("error loading the client certificate of CCE cluster %s: %s", clusterID, err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cluster.InsecureSkipTLSVerify {
+		// the certificate of the external endpoint is not issued by the cluster CA
+		if !insecure {
+			return nil, fmt.Errorf("the certificate of the %s endpoint of CCE cluster %s can not be verified, "+
+				"set insecure_skip_tls_verify to skip the verification", contextName, clusterID)
+		}
+		log.Printf("[WARN] the certificate of the %s endpoint of CCE cluster %s is not verified", contextName, clusterID)
+		tlsConfig.InsecureSkipVerify = true
+	} else {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM([]byte(decodeKubeConfigData(cluster.CertificateAuthorityData))) {
+			return nil, fmt.Errorf("error loading the CA certificate of CCE cluster %s", clusterID)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	return &cceKubernetesClient{
+		Endpoint: strings.TrimSuffix(cluster.Server, "/"),
+		HTTPClient: &http.Client{
+			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		Expiry: leaf.NotAfter,
+	}, nil
+}
+
+// getCCEKubernetesClient returns the client of the cluster cached in the config, so the certificates are
+// not issued for each request. The client is created again when its certificate is about to expire.
+func getCCEKubernetesClient(config *Config, cceClient *golangsdk.ServiceClient, clusterID string,
+	insecure bool) (*cceKubernetesClient, error) {
+	ext := getConfigExtension(config)
+	ext.kubernetesLock.Lock()
+	defer ext.kubernetesLock.Unlock()
+
+	key := fmt.Sprintf("%s/%t", clusterID, insecure)
+	if kubeClient, ok := ext.kubernetesClients[key]; ok && time.Until(kubeClient.Expiry) > cceKubernetesClientRenewal {
+		return kubeClient, nil
+	}
+
+	kubeClient, err := newCCEKubernetesClient(cceClient, clusterID, insecure)
+	if err != nil {
+		return nil, err
+	}
+	if ext.kubernetesClients == nil {
+		ext.kubernetesClients = make(map[string]*cceKubernetesClient)
+	}
+	ext.kubernetesClients[key] = kubeClient
+	return kubeClient, nil
+}
+
+// selectCCEKubernetesContext returns the context of the certificates to access the cluster: the
+// external endpoint verified by the cluster CA, the internal endpoint, and the external endpoint
+// without verification, which is preferred to the internal one only if insecure is true.
+func selectCCEKubernetesContext(cert *clusters.Certificate, insecure bool) string {
+	preferred := []string{"externalTLSVerify", "internal", "external"}
+	if insecure {
+		preferred = []string{"externalTLSVerify", "external", "internal"}
+	}
+	for _, name := range preferred {
+		for _, ctx := range cert.Contexts {
+			if ctx.Name == name {
+				return name
+			}
+		}
+	}
+	return "internal"
+}
+
+// Do sends the request with the body to the path of the Kubernetes API and decodes the JSON response into result,
+// the body is sent as JSON unless contentType is specified.
+func (c *cceKubernetesClient) Do(method, path, contentType string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, ok := body.([]byte)
+		if !ok {
+			var err error
+			if data, err = json.Marshal(body); err != nil {
+				return err
+			}
+		}
+		reqBody = bytes.NewReader(data)
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+
+	req, err := http.NewRequest(method, c.Endpoint+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	log.Printf("[DEBUG] Kubernetes API request: %s %s", method, path)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Kubernetes API response: %s %s: %d", method, path, resp.StatusCode)
+
+	if resp.StatusCode >= 300 {
+		statusErr := &cceKubernetesStatusError{}
+		if json.Unmarshal(data, statusErr) != nil || statusErr.Message == "" {
+			statusErr.Message = strings.TrimSpace(string(data))
+		}
+		statusErr.Code = resp.StatusCode
+		return statusErr
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,53 @@
+package flexibleengine
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestMockCCEKubernetesClient_contexts(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	kube := server.startKubernetes(t, clusterID)
+	cceClient, err := meta.CceV3Client(mockRegion)
+	th.AssertNoErr(t, err)
+
+	internal := map[string]interface{}{"url": "https://192.168.0.10:5443", "type": "Internal"}
+	external := map[string]interface{}{"url": kube.URL, "type": "External"}
+	verified := map[string]interface{}{"url": kube.URL, "type": "External", "__tls_verify": true}
+
+	cases := []struct {
+		name      string
+		endpoints []interface{}
+		insecure  bool
+		endpoint  string
+		err       string
+	}{
+		{"internal", []interface{}{internal}, false, "https://192.168.0.10:5443", ""},
+		{"external not verified", []interface{}{internal, external}, false, "https://192.168.0.10:5443", ""},
+		{"external insecure", []interface{}{internal, external}, true, kube.URL, ""},
+		{"external verified", []interface{}{internal, verified}, false, kube.URL, ""},
+		{"external only", []interface{}{external}, false, "", "set insecure_skip_tls_verify"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server.mu.Lock()
+			server.objects["clusters"][clusterID]["status"].(map[string]interface{})["endpoints"] = c.endpoints
+			server.mu.Unlock()
+
+			kubeClient, err := newCCEKubernetesClient(cceClient, clusterID, c.insecure)
+			if c.err != "" {
+				th.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), c.err))
+				return
+			}
+			th.AssertNoErr(t, err)
+			th.AssertEquals(t, c.endpoint, kubeClient.Endpoint)
+			if c.endpoint == kube.URL {
+				th.AssertNoErr(t, kubeClient.Do(http.MethodGet, "/api/v1/nodes", "", nil, nil))
+			}
+		})
+	}
+}
@@ -12,10 +12,15 @@ package flexibleengine
 import (
 	"fmt"
 	"log"
+	"sort"
+	"time"
 
+	"github.com/chnsz/golangsdk/openstack/compute/v2/extensions/attachinterfaces"
 	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
 	"github.com/chnsz/golangsdk/openstack/ecs/v1/cloudservers"
 	"github.com/chnsz/golangsdk/openstack/networking/v2/ports"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -45,29 +50,9 @@ func expandInstanceNetworks(d *schema.ResourceData, meta interface{}) ([]servers
 
 	networks := d.Get("network").([]interface{})
 	for _, v := range networks {
-		nic := v.(map[string]interface{})
-		networkID := nic["uuid"].(string)
-		networkName := nic["name"].(string)
-		portID := nic["port"].(string)
-
-		if networkID == "" && networkName == "" && portID == "" {
-			return nil, fmt.Errorf(
-				"at least one of network.uuid, network.name, or network.port must be set")
-		}
-
-		// get network ID by Name
-		if networkID == "" && networkName != "" {
-			networkInfo, err := getInstanceNetworkInfo(d, meta, "name", networkName)
-			if err != nil {
-				return nil, err
-			}
-			networkID = networkInfo["uuid"]
-		}
-
-		n := servers.Network{
-			UUID:    networkID,
-			Port:    portID,
-			FixedIP: nic["fixed_ip_v4"].(string),
+		n, err := expandInstanceNetwork(d, meta, v.(map[string]interface{}))
+		if err != nil {
+			return nil, err
 		}
 		instanceNetworks = append(instanceNetworks, n)
 	}
@@ -76,8 +61,36 @@ func expandInstanceNetworks(d *schema.ResourceData, meta interface{}) ([]servers
 	return instanceNetworks, nil
 }
 
+// expandInstanceNetwork builds the servers.Network of a network block.
+func expandInstanceNetwork(d *schema.ResourceData, meta interface{}, nic map[string]interface{}) (servers.Network, error) {
+	networkID := nic["uuid"].(string)
+	networkName := nic["name"].(string)
+	portID := nic["port"].(string)
+
+	if networkID == "" && networkName == "" && portID == "" {
+		return servers.Network{}, fmt.Errorf(
+			"at least one of network.uuid, network.name, or network.port must be set")
+	}
+
+	// get network ID by Name
+	if networkID == "" && networkName != "" {
+		networkInfo, err := getInstanceNetworkInfo(d, meta, "name", networkName)
+		if err != nil {
+			return servers.Network{}, err
+		}
+		networkID = networkInfo["uuid"]
+	}
+
+	return servers.Network{
+		UUID:    networkID,
+		Port:    portID,
+		FixedIP: nic["fixed_ip_v4"].(string),
+	}, nil
+}
+
 // getInstanceAddresses parses a server.Server's Address field into a structured
-// InstanceNIC list struct.
+// InstanceNIC list struct. The NICs are sorted by the networks and one NIC is returned
+// for each port, so the result is stable regardless of the order the NICs are attached.
 func getInstanceAddresses(d *schema.ResourceData, meta interface{}, server *cloudservers.CloudServer) ([]InstanceNIC, error) {
 	config := meta.(*Config)
 	networkingClient, err := config.NetworkingV2Client(GetRegion(d, config))
@@ -85,37 +98,50 @@ func getInstanceAddresses(d *schema.ResourceData, meta interface{}, server *clou
 		return nil, fmt.Errorf("Error creating FlexibleEngine networking client: %s", err)
 	}
 
+	keys := make([]string, 0, len(server.Addresses))
+	for k := range server.Addresses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	allInstanceNics := make([]InstanceNIC, 0)
+	portIndex := make(map[string]int)
 	var networkID string
-	for _, addresses := range server.Addresses {
-		for _, addr := range addresses {
+	for _, k := range keys {
+		for _, addr := range server.Addresses[k] {
 			// Skip if not fixed ip
 			if addr.Type != "fixed" {
 				continue
 			}
 
-			// the response struct cloudservers.Address does not include NetworkID
-			// we should get the network id to aggregate networks
-			p, err := ports.Get(networkingClient, addr.PortID).Extract()
-			if err != nil {
-				log.Printf("[WARN] get Instance Addresses: failed to fetch port %s", addr.PortID)
-				networkID = ""
-			} else {
-				networkID = p.NetworkID
-			}
+			// the IPv4 and IPv6 addresses of a dual-stack port belong to the same NIC
+			index, ok := portIndex[addr.PortID]
+			if !ok {
+				// the response struct cloudservers.Address does not include NetworkID
+				// we should get the network id to aggregate networks
+				p, err := ports.Get(networkingClient, addr.PortID).Extract()
+				if err != nil {
+					log.Printf("[WARN] get Instance Addresses: failed to fetch port %s", addr.PortID)
+					networkID = ""
+				} else {
+					networkID = p.NetworkID
+				}
 
-			instanceNIC := InstanceNIC{
-				NetworkID: networkID,
-				PortID:    addr.PortID,
-				MAC:       addr.MacAddr,
+				allInstanceNics = append(allInstanceNics, InstanceNIC{
+					NetworkID: networkID,
+					PortID:    addr.PortID,
+					MAC:       addr.MacAddr,
+				})
+				index = len(allInstanceNics) - 1
+				portIndex[addr.PortID] = index
 			}
+
+			instanceNIC := &allInstanceNics[index]
 			if addr.Version == "6" {
 				instanceNIC.FixedIPv6 = addr.Addr
 			} else {
 				instanceNIC.FixedIPv4 = addr.Addr
 			}
-
-			allInstanceNics = append(allInstanceNics, instanceNIC)
 		}
 	}
 
@@ -134,6 +160,7 @@ func getAllInstanceNetworks(d *schema.ResourceData) []InstanceNetwork {
 		nic := v.(map[string]interface{})
 		network := InstanceNetwork{
 			UUID:          nic["uuid"].(string),
+			Name:          nic["name"].(string),
 			Port:          nic["port"].(string),
 			FixedIP:       nic["fixed_ip_v4"].(string),
 			AccessNetwork: nic["access_network"].(bool),
@@ -153,42 +180,335 @@ func flattenInstanceNetworks(
 	allInstanceNetworks := getAllInstanceNetworks(d)
 	allInstanceNics, _ := getInstanceAddresses(d, meta, server)
 
-	networks := []map[string]interface{}{}
-	// Loop through all networks and addresses, merge relevant address details.
-	for _, instanceNetwork := range allInstanceNetworks {
-		for i := range allInstanceNics {
-			isExist := false
-			nic := &allInstanceNics[i]
-			// seem port as the unique key
-			if instanceNetwork.Port != "" && instanceNetwork.Port == nic.PortID {
-				nic.Fetched = true
-				isExist = true
-			} else if instanceNetwork.UUID == nic.NetworkID && nic.Fetched == false {
+	// The NICs are matched by the port first, then by the fixed IP, and at last by the network,
+	// so a network block never takes the NIC which is referred to by another block.
+	matched := make([]*InstanceNIC, len(allInstanceNetworks))
+	matchers := []func(network InstanceNetwork, nic *InstanceNIC) bool{
+		func(network InstanceNetwork, nic *InstanceNIC) bool {
+			return network.Port != "" && network.Port == nic.PortID
+		},
+		func(network InstanceNetwork, nic *InstanceNIC) bool {
+			return network.Port == "" && network.FixedIP != "" &&
+				network.UUID == nic.NetworkID && network.FixedIP == nic.FixedIPv4
+		},
+		func(network InstanceNetwork, nic *InstanceNIC) bool {
+			return network.Port == "" && network.FixedIP == "" && network.UUID == nic.NetworkID
+		},
+	}
+	for _, match := range matchers {
+		for i, instanceNetwork := range allInstanceNetworks {
+			if matched[i] != nil {
+				continue
+			}
+			for j := range allInstanceNics {
+				nic := &allInstanceNics[j]
 				// Only use one NIC since it's possible the user defined another NIC
 				// on this same network in another Terraform network block.
-				nic.Fetched = true
-				isExist = true
-			}
-
-			if isExist {
-				v := map[string]interface{}{
-					"uuid":           nic.NetworkID,
-					"port":           nic.PortID,
-					"fixed_ip_v4":    nic.FixedIPv4,
-					"fixed_ip_v6":    nic.FixedIPv6,
-					"mac":            nic.MAC,
-					"access_network": instanceNetwork.AccessNetwork,
+				if !nic.Fetched && match(instanceNetwork, nic) {
+					nic.Fetched = true
+					matched[i] = nic
+					break
 				}
-				networks = append(networks, v)
-				break
 			}
 		}
 	}
 
+	networks := []map[string]interface{}{}
+	for i, instanceNetwork := range allInstanceNetworks {
+		nic := matched[i]
+		if nic == nil {
+			continue
+		}
+		v := map[string]interface{}{
+			"uuid":           nic.NetworkID,
+			"name":           instanceNetwork.Name,
+			"port":           nic.PortID,
+			"fixed_ip_v4":    nic.FixedIPv4,
+			"fixed_ip_v6":    nic.FixedIPv6,
+			"mac":            nic.MAC,
+			"access_network": instanceNetwork.AccessNetwork,
+		}
+		networks = append(networks, v)
+	}
+
 	log.Printf("[DEBUG] flatten Instance Networks: %#v", networks)
 	return networks, nil
 }
 
+// rawInstanceNetworks returns the network blocks in the raw configuration, false is returned
+// if the configuration is not available or any of the blocks is not known yet.
+func rawInstanceNetworks(raw cty.Value) ([]map[string]interface{}, bool) {
+	if raw.IsNull() || !raw.IsKnown() {
+		return nil, false
+	}
+	rawNetworks := raw.GetAttr("network")
+	if !rawNetworks.IsKnown() {
+		return nil, false
+	}
+
+	networks := make([]map[string]interface{}, 0)
+	if rawNetworks.IsNull() {
+		return networks, true
+	}
+	for _, rawNetwork := range rawNetworks.AsValueSlice() {
+		if !rawNetwork.IsWhollyKnown() {
+			return nil, false
+		}
+
+		network := map[string]interface{}{
+			"access_network": false,
+		}
+		for _, key := range []string{"uuid", "name", "port", "fixed_ip_v4", "fixed_ip_v6"} {
+			network[key] = ""
+			if v := rawNetwork.GetAttr(key); !v.IsNull() {
+				network[key] = v.AsString()
+			}
+		}
+		if v := rawNetwork.GetAttr("access_network"); !v.IsNull() {
+			network["access_network"] = v.True()
+		}
+		networks = append(networks, network)
+	}
+	return networks, true
+}
+
+// rawPrimaryInstanceNetwork returns the first network block in the raw configuration, false is returned
+// if the block refers to a NIC which is not known yet. nil is returned if there is no network block.
+func rawPrimaryInstanceNetwork(raw cty.Value) (map[string]interface{}, bool) {
+	if raw.IsNull() || !raw.IsKnown() {
+		return nil, false
+	}
+	rawNetworks := raw.GetAttr("network")
+	if !rawNetworks.IsKnown() {
+		return nil, false
+	}
+	if rawNetworks.IsNull() || rawNetworks.LengthInt() == 0 {
+		return nil, true
+	}
+
+	rawNetwork := rawNetworks.Index(cty.NumberIntVal(0))
+	if !rawNetwork.IsKnown() {
+		return nil, false
+	}
+	network := make(map[string]interface{})
+	for _, key := range []string{"uuid", "name", "port", "fixed_ip_v4"} {
+		v := rawNetwork.GetAttr(key)
+		if !v.IsKnown() {
+			return nil, false
+		}
+		network[key] = ""
+		if !v.IsNull() {
+			network[key] = v.AsString()
+		}
+	}
+	return network, true
+}
+
+// instanceNetworkMatches reports whether the network block refers to the attached NIC.
+func instanceNetworkMatches(network, nic map[string]interface{}) bool {
+	if port := network["port"].(string); port != "" {
+		return port == nic["port"]
+	}
+
+	if uuid := network["uuid"].(string); uuid != "" {
+		if uuid != nic["uuid"] {
+			return false
+		}
+	} else if name := network["name"].(string); name == "" || name != nic["name"] {
+		return false
+	}
+
+	ip := network["fixed_ip_v4"].(string)
+	return ip == "" || ip == nic["fixed_ip_v4"]
+}
+
+// mergeInstanceNetworks pairs the network blocks with the attached NICs in order. The NIC of
+// each block is kept with the computed attributes, the blocks without NIC are returned as the
+// NICs to be attached, and the NICs which are no longer referred to are returned to be detached.
+// The index of the NIC attached for each block is also returned, -1 means a new NIC is required.
+func mergeInstanceNetworks(networks []map[string]interface{}, attached []interface{}) (
+	merged []interface{}, indexes []int, added, removed []map[string]interface{}) {
+
+	used := make([]bool, len(attached))
+	for _, network := range networks {
+		index := -1
+		for i, raw := range attached {
+			if nic, ok := raw.(map[string]interface{}); ok && !used[i] && instanceNetworkMatches(network, nic) {
+				index = i
+				break
+			}
+		}
+		indexes = append(indexes, index)
+
+		if index < 0 {
+			// the attributes which are not specified are left unknown until the NIC is attached
+			v := make(map[string]interface{})
+			for key, value := range network {
+				if value != "" {
+					v[key] = value
+				}
+			}
+			merged = append(merged, v)
+			added = append(added, network)
+			continue
+		}
+
+		used[index] = true
+		v := make(map[string]interface{})
+		for key, value := range attached[index].(map[string]interface{}) {
+			v[key] = value
+		}
+		v["access_network"] = network["access_network"]
+		merged = append(merged, v)
+	}
+
+	for i, raw := range attached {
+		if nic, ok := raw.(map[string]interface{}); ok && !used[i] {
+			removed = append(removed, nic)
+		}
+	}
+	return
+}
+
+// customizeInstanceNetworksDiff plans the changes of the network blocks by the NICs they refer
+// to instead of their positions. The instance is replaced if the primary NIC is changed.
+func customizeInstanceNetworksDiff(d *schema.ResourceDiff) error {
+	oldRaw, _ := d.GetChange("network")
+	attached := oldRaw.([]interface{})
+
+	networks, ok := rawInstanceNetworks(d.GetRawConfig())
+	if !ok {
+		// the blocks which are not known yet are planned by the SDK, the instance is replaced
+		// unless the first block is known to refer to the primary NIC
+		if len(attached) == 0 || !d.HasChange("network") {
+			return nil
+		}
+		primary, known := rawPrimaryInstanceNetwork(d.GetRawConfig())
+		if known && primary != nil {
+			if nic, ok := attached[0].(map[string]interface{}); ok && instanceNetworkMatches(primary, nic) {
+				return nil
+			}
+		}
+		log.Printf("[DEBUG] the primary NIC of instance %s may be changed", d.Id())
+		// the list is planned as unknown, the blocks are not forced new by the unknown attributes
+		if err := d.SetNewComputed("network"); err != nil {
+			return err
+		}
+		return d.ForceNew("network")
+	}
+
+	// the attribute is computed, so removing all the blocks is not a change of its own
+	if !d.HasChange("network") && (len(networks) > 0 || len(attached) == 0) {
+		return nil
+	}
+	merged, indexes, _, _ := mergeInstanceNetworks(networks, attached)
+	if err := d.SetNew("network", merged); err != nil {
+		return err
+	}
+
+	// the first NIC is the primary NIC which can not be detached
+	if len(attached) > 0 && (len(indexes) == 0 || indexes[0] != 0) {
+		log.Printf("[DEBUG] the primary NIC of instance %s is changed", d.Id())
+		return d.ForceNew("network")
+	}
+	return nil
+}
+
+// updateInstanceNetworks detaches the NICs which are removed from the network blocks and
+// attaches the NICs of the new blocks, the primary NIC is kept.
+func updateInstanceNetworks(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	computeClient, err := config.ComputeV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine compute client: %s", err)
+	}
+
+	networks, ok := rawInstanceNetworks(d.GetRawConfig())
+	if !ok {
+		networks = make([]map[string]interface{}, 0)
+		for _, v := range d.Get("network").([]interface{}) {
+			networks = append(networks, v.(map[string]interface{}))
+		}
+	}
+	oldRaw, _ := d.GetChange("network")
+	attached := oldRaw.([]interface{})
+	_, _, added, removed := mergeInstanceNetworks(networks, attached)
+
+	var primaryPort string
+	if len(attached) > 0 {
+		if nic, ok := attached[0].(map[string]interface{}); ok {
+			primaryPort, _ = nic["port"].(string)
+		}
+	}
+	for _, nic := range removed {
+		portID := nic["port"].(string)
+		if portID == "" {
+			continue
+		}
+		// the instance should have been replaced by the plan
+		if portID == primaryPort {
+			return fmt.Errorf("the primary NIC %s of instance %s can not be detached", portID, d.Id())
+		}
+	}
+
+	for _, nic := range removed {
+		portID := nic["port"].(string)
+		if portID == "" {
+			continue
+		}
+
+		log.Printf("[DEBUG] Detaching NIC %s from instance %s", portID, d.Id())
+		stateConf := &resource.StateChangeConf{
+			Pending:    []string{""},
+			Target:     []string{"DETACHED"},
+			Refresh:    computeInterfaceAttachV2DetachFunc(computeClient, d.Id(), portID),
+			Timeout:    d.Timeout(schema.TimeoutUpdate),
+			Delay:      5 * time.Second,
+			MinTimeout: 5 * time.Second,
+		}
+		if _, err := waitForState(config, stateConf); err != nil {
+			return fmt.Errorf("Error detaching NIC %s from instance %s: %s", portID, d.Id(), err)
+		}
+	}
+
+	for _, nic := range added {
+		network, err := expandInstanceNetwork(d, config, nic)
+		if err != nil {
+			return err
+		}
+
+		attachOpts := attachinterfaces.CreateOpts{
+			PortID:    network.Port,
+			NetworkID: network.UUID,
+		}
+		// the fixed IP can only be specified together with the network
+		if network.Port == "" && network.FixedIP != "" {
+			attachOpts.FixedIPs = []attachinterfaces.FixedIP{{IPAddress: network.FixedIP}}
+		}
+
+		log.Printf("[DEBUG] Attaching NIC to instance %s: %#v", d.Id(), attachOpts)
+		attachment, err := attachinterfaces.Create(computeClient, d.Id(), attachOpts).Extract()
+		if err != nil {
+			return fmt.Errorf("Error attaching NIC to instance %s: %s", d.Id(), err)
+		}
+
+		stateConf := &resource.StateChangeConf{
+			Pending:    []string{"ATTACHING"},
+			Target:     []string{"ATTACHED"},
+			Refresh:    computeInterfaceAttachV2AttachFunc(computeClient, d.Id(), attachment.PortID),
+			Timeout:    d.Timeout(schema.TimeoutUpdate),
+			Delay:      5 * time.Second,
+			MinTimeout: 5 * time.Second,
+		}
+		if _, err := waitForState(config, stateConf); err != nil {
+			return fmt.Errorf("Error waiting for NIC %s to be attached to instance %s: %s",
+				attachment.PortID, d.Id(), err)
+		}
+	}
+
+	return nil
+}
+
 // getInstanceAccessAddresses determines the best IP address to communicate
 // with the instance. It does this by looping through all networks and looking
 // for a valid IP address. Priority is given to a network that was flagged as
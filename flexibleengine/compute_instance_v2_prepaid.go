@@ -0,0 +1,57 @@
+package flexibleengine
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
+	"github.com/chnsz/golangsdk/openstack/ecs/v1/cloudservers"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/common"
+)
+
+// createPrePaidComputeInstance creates the prePaid instance by the ECS API and pays the order automatically,
+// so no pay-per-use instance is left when the order fails. The ID of the new instance is returned.
+func createPrePaidComputeInstance(d *schema.ResourceData, config *Config, imageID, flavorID string,
+	networks []servers.Network) (string, error) {
+	region := GetRegion(d, config)
+	ecsV11Client, err := config.ComputeV11Client(region)
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine compute v1.1 client: %s", err)
+	}
+	bssClient, err := config.BssV2Client(region)
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine BSS client: %s", err)
+	}
+
+	createOpts, err := buildCloudServerCreateOpts(d, config, imageID, flavorID, networks, chargingModePrePaid)
+	if err != nil {
+		return "", err
+	}
+	createOpts.ExtendParam = &cloudservers.ServerExtendParam{
+		ChargingMode: chargingModePrePaid,
+		PeriodType:   d.Get("period_unit").(string),
+		PeriodNum:    d.Get("period").(int),
+		IsAutoRenew:  d.Get("auto_renew").(string),
+		IsAutoPay:    "true",
+	}
+	log.Printf("[DEBUG] Create prePaid instance options: %#v", createOpts)
+	// the admin password is set after the options are logged
+	createOpts.AdminPass = d.Get("admin_pass").(string)
+
+	order, err := cloudservers.CreatePrePaid(ecsV11Client, createOpts).ExtractOrderResponse()
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine prePaid instance: %s", err)
+	}
+
+	timeout := d.Timeout(schema.TimeoutCreate)
+	if err := common.WaitOrderComplete(context.Background(), bssClient, order.OrderID, timeout); err != nil {
+		return "", err
+	}
+	serverID, err := common.WaitOrderResourceComplete(context.Background(), bssClient, order.OrderID, timeout)
+	if err != nil {
+		return "", fmt.Errorf("Error retrieving the ID of the prePaid instance: %s", err)
+	}
+	return serverID, nil
+}
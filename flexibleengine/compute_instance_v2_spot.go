@@ -0,0 +1,191 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
+	"github.com/chnsz/golangsdk/openstack/ecs/v1/cloudservers"
+	"github.com/chnsz/golangsdk/openstack/networking/v1/subnets"
+	"github.com/chnsz/golangsdk/openstack/networking/v2/extensions/security/groups"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// chargingModeSpot is the charging_mode of the spot instances, it can not be specified
+const chargingModeSpot = "spot"
+
+func isSpotInstance(d *schema.ResourceData) bool {
+	return d.Get("spot_price").(string) != ""
+}
+
+// buildCloudServerCreateOpts builds the options of the ECS API which creates the spot and the prePaid
+// instances, kind describes the instance in the errors.
+func buildCloudServerCreateOpts(d *schema.ResourceData, config *Config, imageID, flavorID string,
+	networks []servers.Network, kind string) (*cloudservers.CreateOpts, error) {
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("at least one network must be specified for the %s instance", kind)
+	}
+	nics := make([]cloudservers.Nic, len(networks))
+	for i, network := range networks {
+		if network.Port != "" {
+			return nil, fmt.Errorf("network.port can not be specified for the %s instance", kind)
+		}
+		nics[i] = cloudservers.Nic{
+			SubnetId:  network.UUID,
+			IpAddress: network.FixedIP,
+		}
+	}
+
+	vpcClient, err := config.NetworkingV1Client(GetRegion(d, config))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating FlexibleEngine networking v1 client: %s", err)
+	}
+	subnet, err := subnets.Get(vpcClient, networks[0].UUID).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("Error retrieving the subnet %s of the %s instance: %s", networks[0].UUID, kind, err)
+	}
+
+	secGroups, err := getComputeSecGroupIDs(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudservers.CreateOpts{
+		Name:             d.Get("name").(string),
+		ImageRef:         imageID,
+		FlavorRef:        flavorID,
+		KeyName:          d.Get("key_pair").(string),
+		UserData:         []byte(d.Get("user_data").(string)),
+		VpcId:            subnet.VPC_ID,
+		Nics:             nics,
+		SecurityGroups:   secGroups,
+		AvailabilityZone: d.Get("availability_zone").(string),
+		RootVolume: cloudservers.RootVolume{
+			VolumeType: d.Get("system_disk_type").(string),
+		},
+	}, nil
+}
+
+// createSpotComputeInstance creates the spot instance by the ECS API, the compute v2.1 API
+// can not request the spot capacity. The ID of the new instance is returned.
+func createSpotComputeInstance(d *schema.ResourceData, config *Config, imageID, flavorID string,
+	networks []servers.Network) (string, error) {
+	region := GetRegion(d, config)
+	ecsV11Client, err := config.ComputeV11Client(region)
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine compute v1.1 client: %s", err)
+	}
+	ecsClient, err := config.ComputeV1Client(region)
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine compute v1 client: %s", err)
+	}
+
+	createOpts, err := buildCloudServerCreateOpts(d, config, imageID, flavorID, networks, "spot")
+	if err != nil {
+		return "", err
+	}
+	createOpts.ExtendParam = &cloudservers.ServerExtendParam{
+		MarketType:         "spot",
+		SpotPrice:          d.Get("spot_price").(string),
+		SpotDurationHours:  d.Get("spot_duration").(int),
+		InterruptionPolicy: d.Get("interruption_policy").(string),
+	}
+	if createOpts.ExtendParam.SpotDurationHours > 0 {
+		createOpts.ExtendParam.SpotDurationCount = 1
+	}
+	log.Printf("[DEBUG] Create spot instance options: %#v", createOpts)
+	// the admin password is set after the options are logged
+	createOpts.AdminPass = d.Get("admin_pass").(string)
+
+	job, err := cloudservers.Create(ecsV11Client, createOpts).ExtractJobResponse()
+	if err != nil {
+		return "", fmt.Errorf("Error creating FlexibleEngine spot instance: %s", err)
+	}
+
+	if _, err := waitForECSJobSuccess(config, ecsClient, d.Timeout(schema.TimeoutCreate), job.JobID); err != nil {
+		return "", fmt.Errorf("Error waiting for the spot instance to be created: %s", err)
+	}
+	serverID, err := cloudservers.GetJobEntity(ecsClient, job.JobID, "server_id")
+	if err != nil {
+		return "", fmt.Errorf("Error retrieving the ID of the spot instance: %s", err)
+	}
+	return serverID.(string), nil
+}
+
+// customizeCloudServerInstanceDiff checks the new spot and prePaid instances, which are created by the ECS API.
+// The API requires the type of the system disk, and the prePaid instance can not be created with the arguments
+// which are only accepted by the compute v2.1 API.
+func customizeCloudServerInstanceDiff(d *schema.ResourceDiff) error {
+	if d.Id() != "" {
+		return nil
+	}
+
+	kind := "spot"
+	if d.Get("spot_price").(string) == "" {
+		if d.Get("charging_mode").(string) != chargingModePrePaid {
+			return nil
+		}
+		kind = chargingModePrePaid
+		for _, key := range []string{"block_device", "personality", "scheduler_hints"} {
+			if v, ok := d.GetOk(key); ok && v != nil {
+				return fmt.Errorf("`%s` can not be specified for the prePaid instance, create it in postPaid "+
+					"charging mode and change it to prePaid", key)
+			}
+		}
+	}
+	if d.NewValueKnown("system_disk_type") && d.Get("system_disk_type").(string) == "" {
+		return fmt.Errorf("`system_disk_type` must be specified for the %s instance", kind)
+	}
+	return nil
+}
+
+// getComputeSecGroupIDs returns the IDs of security_groups, the ECS API only accepts the IDs
+func getComputeSecGroupIDs(d *schema.ResourceData, config *Config) ([]cloudservers.SecurityGroup, error) {
+	names := resourceComputeSecGroupsV2(d)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	networkingClient, err := config.NetworkingV2Client(GetRegion(d, config))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating FlexibleEngine networking v2 client: %s", err)
+	}
+
+	secGroups := make([]cloudservers.SecurityGroup, len(names))
+	for i, name := range names {
+		pages, err := groups.List(networkingClient, groups.ListOpts{Name: name}).AllPages()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve security groups: %s", err)
+		}
+		allSecGroups, err := groups.ExtractGroups(pages)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve security groups: %s", err)
+		}
+		if len(allSecGroups) != 1 {
+			return nil, fmt.Errorf("Expected one Security Group with name %s, but found %d", name, len(allSecGroups))
+		}
+		secGroups[i] = cloudservers.SecurityGroup{ID: allSecGroups[0].ID}
+	}
+	return secGroups, nil
+}
+
+// checkSpotInstanceReclaimed returns true if the spot instance has been reclaimed by the cloud,
+// the reclaimed instance is deleted at once or left in the DELETED status for a while.
+func checkSpotInstanceReclaimed(d *schema.ResourceData, server *cloudservers.CloudServer, err error) bool {
+	if !isSpotInstance(d) && d.Get("charging_mode").(string) != chargingModeSpot {
+		return false
+	}
+
+	if err != nil {
+		if _, ok := err.(golangsdk.ErrDefault404); !ok {
+			return false
+		}
+	} else if server.Status != "DELETED" && server.Status != "SOFT_DELETED" {
+		return false
+	}
+
+	log.Printf("[WARN] the spot instance %s has been reclaimed, removing it from the state", d.Id())
+	d.SetId("")
+	return true
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/chnsz/golangsdk"
 	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/volumes"
@@ -12,6 +14,7 @@ import (
 	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
 	"github.com/chnsz/golangsdk/openstack/ecs/v1/block_devices"
 	"github.com/chnsz/golangsdk/openstack/ecs/v1/cloudservers"
+	"github.com/chnsz/golangsdk/openstack/ecs/v1/powers"
 	"github.com/chnsz/golangsdk/openstack/imageservice/v2/images"
 	"github.com/chnsz/golangsdk/openstack/networking/v2/networks"
 	"github.com/chnsz/golangsdk/openstack/networking/v2/ports"
@@ -255,6 +258,118 @@ func computeV2StateRefreshFunc(client *golangsdk.ServiceClient, instanceID strin
 	}
 }
 
+// computePowerState returns ON or OFF for the running or stopped instance,
+// the other status such as REBOOT and ERROR is returned as it is.
+func computePowerState(status string) string {
+	switch status {
+	case "ACTIVE":
+		return "ON"
+	case "SHUTOFF":
+		return "OFF"
+	default:
+		return status
+	}
+}
+
+// normalizeChargingMode returns the charging_mode of the charging mode in the instance metadata
+func normalizeChargingMode(mode string) string {
+	switch mode {
+	case "1":
+		return chargingModePrePaid
+	case "2":
+		return chargingModeSpot
+	default:
+		return chargingModePostPaid
+	}
+}
+
+// powerStateOfAction returns the power state of the instance after the power_action is done
+func powerStateOfAction(action string) string {
+	if strings.HasSuffix(action, "OFF") {
+		return "OFF"
+	}
+	return "ON"
+}
+
+// doInstancePowerAction converges the power state of the instance to the power_action by the
+// batch actions of cloudservers, nothing is done if the instance is already in the state.
+// The stopped instance is started for REBOOT and FORCE-REBOOT.
+func doInstancePowerAction(config *Config, ecsClient, computeClient *golangsdk.ServiceClient, instanceID, action string,
+	timeout time.Duration) error {
+	server, err := servers.Get(computeClient, instanceID).Extract()
+	if err != nil {
+		return fmt.Errorf("Error retrieving FlexibleEngine instance %s: %s", instanceID, err)
+	}
+
+	powerOpts := powers.PowerOpts{
+		Servers: []powers.ServerInfo{{ID: instanceID}},
+	}
+	// the type of stop and reboot is required, SOFT is the graceful one
+	powerType := "SOFT"
+	if strings.HasPrefix(action, "FORCE-") {
+		powerType = "HARD"
+	}
+
+	var op string
+	current := computePowerState(server.Status)
+	switch {
+	case current == "OFF" && powerStateOfAction(action) == "ON":
+		op = "os-start"
+	case current == "ON" && powerStateOfAction(action) == "OFF":
+		op = "os-stop"
+		powerOpts.Type = powerType
+	case current == "ON" && strings.HasSuffix(action, "REBOOT"):
+		op = "reboot"
+		powerOpts.Type = powerType
+	default:
+		log.Printf("[DEBUG] the power state of instance %s is %s, skip the power action %s", instanceID, current, action)
+		return nil
+	}
+
+	log.Printf("[DEBUG] doing power action %s(%s) on instance %s", op, powerOpts.Type, instanceID)
+	job, err := powers.PowerAction(ecsClient, powerOpts, op).ExtractJobResponse()
+	if err != nil {
+		return fmt.Errorf("Error doing power action %s on FlexibleEngine instance %s: %s", action, instanceID, err)
+	}
+
+	if _, err := waitForECSJobSuccess(config, ecsClient, timeout, job.JobID); err != nil {
+		return fmt.Errorf("Error waiting for power action %s on instance %s: %s", action, instanceID, err)
+	}
+	return nil
+}
+
+// waitForECSJobSuccess waits for the ECS job to succeed and returns the job status
+func waitForECSJobSuccess(config *Config, ecsClient *golangsdk.ServiceClient, timeout time.Duration,
+	jobID string) (*cloudservers.JobStatus, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{"INIT", "RUNNING"},
+		Target:       []string{"SUCCESS"},
+		Refresh:      ecsJobStateRefreshFunc(ecsClient, jobID),
+		Timeout:      timeout,
+		Delay:        5 * time.Second,
+		PollInterval: 5 * time.Second,
+	}
+	job, err := waitForState(config, stateConf)
+	if err != nil {
+		return nil, err
+	}
+	return job.(*cloudservers.JobStatus), nil
+}
+
+func ecsJobStateRefreshFunc(ecsClient *golangsdk.ServiceClient, jobID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		job := new(cloudservers.JobStatus)
+		_, err := ecsClient.Get(ecsClient.ServiceURL("jobs", jobID), job, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if job.Status == "FAIL" {
+			return job, job.Status, fmt.Errorf("job %s failed with code %s: %s", jobID, job.ErrorCode, job.FailReason)
+		}
+		return job, job.Status, nil
+	}
+}
+
 // getInstanceNetworkInfo will query for network information in order to make
 // an accurate determination of a network's name and a network's ID.
 func getInstanceNetworkInfo(
@@ -6,26 +6,86 @@ import (
 	"crypto/x509"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/chnsz/golangsdk"
 	"github.com/chnsz/golangsdk/auth"
 	huaweisdk "github.com/chnsz/golangsdk/openstack"
 	"github.com/chnsz/golangsdk/openstack/identity/v3/domains"
+	"github.com/chnsz/golangsdk/openstack/obs"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	huaweiconfig "github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/config"
 	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/helper/pathorcontents"
 )
 
+// defaultAssumeRoleDuration is the default validity period in seconds of the agency credentials
+const defaultAssumeRoleDuration = 3600
+
 // PublicType indicates that an endpoint is "public" in service catalog
 const PublicType golangsdk.Availability = golangsdk.AvailabilityPublic
 
 // Config is the alias of huaweicloud Config
 type Config = huaweiconfig.Config
 
+// configExtension holds the FlexibleEngine specific settings which are not supported
+// by huaweicloud Config, it is stored in Config.Metadata.
+type configExtension struct {
+	// RetryPolicy is the backoff policy of the retryable HTTP requests
+	RetryPolicy *backoffPolicy
+	// RateLimiter is shared by all clients to limit the requests per second of each service
+	RateLimiter *serviceRateLimiter
+	// SkipCatalog indicates that the service catalog of IAM is never queried or used
+	SkipCatalog bool
+	// EndpointTemplate is the template of the endpoints which are not customized, it is expanded per region
+	EndpointTemplate string
+	// CustomEndpoints are the endpoints specified in the provider block, they take precedence over the template
+	CustomEndpoints map[string]string
+	// HTTPLog is the configuration of the structured HTTP logs, nil means LogRoundTripper is used
+	HTTPLog *httpLogOptions
+	// AssumeRoleDuration is the validity period in seconds of the temporary credentials of the agency
+	AssumeRoleDuration int
+	// Credentials are the refreshable temporary credentials used to sign the requests
+	Credentials *temporaryCredentials
+	// CredentialProcess is the command which prints the credentials in JSON format
+	CredentialProcess string
+	// CredentialFile is the file which contains the credentials in JSON format
+	CredentialFile string
+	// Recorder is the cassette of the acceptance test which records or replays the requests
+	Recorder *recorderOptions
+	// StateWaitInterval replaces the intervals of the status waits if it is not zero, it is set by the mock
+	// tests because the mock server finishes the jobs at once
+	StateWaitInterval time.Duration
+
+	// projectConfigs caches the configs scoped to the (region, project) pairs
+	projectConfigs map[string]*Config
+	projectLock    sync.Mutex
+
+	// regionConfigs caches the configs with the endpoints generated from EndpointTemplate for other regions
+	regionConfigs map[string]*Config
+	regionLock    sync.Mutex
+
+	// kubernetesClients caches the clients of the Kubernetes API keyed by the CCE clusters
+	kubernetesClients map[string]*cceKubernetesClient
+	kubernetesLock    sync.Mutex
+}
+
+// getConfigExtension returns the extension stored in Config.Metadata,
+// an empty one will be created if it does not exist.
+func getConfigExtension(c *Config) *configExtension {
+	if ext, ok := c.Metadata.(*configExtension); ok {
+		return ext
+	}
+
+	ext := &configExtension{}
+	c.Metadata = ext
+	return ext
+}
+
 // LoadAndValidate overwrites the the c.LoadAndValidate
 func LoadAndValidate(c *Config) error {
 	if c.MaxRetries < 0 {
@@ -34,7 +94,9 @@ func LoadAndValidate(c *Config) error {
 
 	err := fmt.Errorf("Must config token or aksk or username password to be authorized")
 
-	if c.Token != "" {
+	if ext := getConfigExtension(c); ext.CredentialProcess != "" || ext.CredentialFile != "" {
+		err = buildClientByCredentialSource(c)
+	} else if c.Token != "" {
 		err = buildClientByToken(c)
 	} else if c.Password != "" {
 		if c.Username == "" && c.UserID == "" {
@@ -50,6 +112,13 @@ func LoadAndValidate(c *Config) error {
 		return err
 	}
 
+	// switch to the delegated domain if assume role is specified
+	if c.AssumeRoleAgency != "" {
+		if err := buildClientByAgency(c); err != nil {
+			return err
+		}
+	}
+
 	if c.HwClient != nil && c.HwClient.ProjectID != "" {
 		c.RegionProjectIDMap[c.Region] = c.HwClient.ProjectID
 	}
@@ -110,28 +179,6 @@ func generateTLSConfig(c *Config) (*tls.Config, error) {
 	return config, nil
 }
 
-func retryBackoffFunc(ctx context.Context, respErr *golangsdk.ErrUnexpectedResponseCode, e error, retries uint) error {
-	minutes := int(math.Pow(2, float64(retries)))
-	if minutes > 30 { // won't wait more than 30 minutes
-		minutes = 30
-	}
-
-	log.Printf("[WARN] Received StatusTooManyRequests response code, try to sleep %d minutes", minutes)
-	sleep := time.Duration(minutes) * time.Minute
-
-	if ctx != nil {
-		select {
-		case <-time.After(sleep):
-		case <-ctx.Done():
-			return e
-		}
-	} else {
-		time.Sleep(sleep)
-	}
-
-	return nil
-}
-
 func genClient(c *Config, ao golangsdk.AuthOptionsProvider) (*golangsdk.ProviderClient, error) {
 	client, err := huaweisdk.NewClient(ao.GetIdentityEndpoint())
 	if err != nil {
@@ -145,12 +192,49 @@ func genClient(c *Config, ao golangsdk.AuthOptionsProvider) (*golangsdk.Provider
 	if err != nil {
 		return nil, err
 	}
-	transport := &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: config}
+	ext := getConfigExtension(c)
+	var transport http.RoundTripper = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: config}
+	// the acceptance tests can record the requests or replay them without network
+	transport, err = newRecorderRoundTripper(transport, ext.Recorder)
+	if err != nil {
+		return nil, err
+	}
 
+	retryPolicy := ext.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = defaultBackoffPolicy(c.MaxRetries)
+	}
+
+	var rt http.RoundTripper = &huaweiconfig.LogRoundTripper{
+		Rt: transport,
+	}
+	if ext.HTTPLog != nil {
+		rt = &httpLogRoundTripper{
+			Rt:      transport,
+			Options: ext.HTTPLog,
+		}
+	}
+	// every attempt of a request is counted by the rate limiter
+	if ext.RateLimiter.enabled() {
+		rt = &rateLimitRoundTripper{
+			Rt:      rt,
+			Limiter: ext.RateLimiter,
+		}
+	}
+	// every attempt of a request is signed with the latest temporary credentials
+	if ext.Credentials != nil {
+		rt = &credentialRoundTripper{
+			Rt:          rt,
+			Credentials: ext.Credentials,
+		}
+	}
+
+	// the throttled, failed and reset requests are retried by retryRoundTripper,
+	// so the log round trippers only log each attempt and never retries by itself.
 	client.HTTPClient = http.Client{
-		Transport: &huaweiconfig.LogRoundTripper{
-			Rt:         transport,
-			MaxRetries: c.MaxRetries,
+		Transport: &retryRoundTripper{
+			Rt:     rt,
+			Policy: retryPolicy,
 		},
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if client.AKSKAuthOptions.AccessKey != "" {
@@ -163,11 +247,6 @@ func genClient(c *Config, ao golangsdk.AuthOptionsProvider) (*golangsdk.Provider
 		},
 	}
 
-	if c.MaxRetries > 0 {
-		client.MaxBackoffRetries = uint(c.MaxRetries)
-		client.RetryBackoffFunc = retryBackoffFunc
-	}
-
 	// Validate authentication normally.
 	err = huaweisdk.Authenticate(client, ao)
 	if err != nil {
@@ -203,6 +282,11 @@ func buildClientByToken(c *Config) error {
 func buildClientByAKSK(c *Config) error {
 	var pao, dao golangsdk.AKSKAuthOptions
 
+	creds, err := currentCredentials(c)
+	if err != nil {
+		return err
+	}
+
 	pao = golangsdk.AKSKAuthOptions{
 		ProjectName: c.TenantName,
 		ProjectId:   c.TenantID,
@@ -215,11 +299,15 @@ func buildClientByAKSK(c *Config) error {
 
 	for _, ao := range []*golangsdk.AKSKAuthOptions{&pao, &dao} {
 		ao.IdentityEndpoint = c.IdentityEndpoint
-		ao.AccessKey = c.AccessKey
-		ao.SecretKey = c.SecretKey
+		ao.AccessKey = creds.AccessKey
+		ao.SecretKey = creds.SecretKey
 
-		if c.SecurityToken != "" {
-			ao.SecurityToken = c.SecurityToken
+		if creds.SecurityToken != "" {
+			ao.SecurityToken = creds.SecurityToken
+			ao.WithUserCatalog = true
+		}
+		// the clients located by the catalog are built from the custom endpoints instead
+		if getConfigExtension(c).SkipCatalog {
 			ao.WithUserCatalog = true
 		}
 	}
@@ -250,6 +338,116 @@ func buildClientByPassword(c *Config) error {
 	return genClients(c, pao, dao)
 }
 
+// buildClientByCredentialSource builds the clients by the credentials which are provided by
+// the credential process or file, the credentials will be loaded again before they expire.
+func buildClientByCredentialSource(c *Config) error {
+	ext := getConfigExtension(c)
+	refresh := func() (*credentialValue, error) {
+		if ext.CredentialProcess != "" {
+			return runCredentialProcess(ext.CredentialProcess)
+		}
+		return readCredentialFile(ext.CredentialFile)
+	}
+
+	creds, err := newTemporaryCredentials(refresh)
+	if err != nil {
+		return err
+	}
+	ext.Credentials = creds
+	seedConfigCredentials(c, creds)
+
+	return buildClientByAKSK(c)
+}
+
+// seedConfigCredentials sets the first temporary credentials to Config before any resource is applied,
+// for the clients of huaweicloud-sdk-go-v3 which only read Config. The refreshed credentials are read
+// through currentCredentials and never written to the shared Config.
+func seedConfigCredentials(c *Config, creds *temporaryCredentials) {
+	creds.mu.Lock()
+	defer creds.mu.Unlock()
+	c.AccessKey, c.SecretKey, c.SecurityToken = creds.value.AccessKey, creds.value.SecretKey, creds.value.SecurityToken
+}
+
+// buildClientByAgency exchanges the credentials of the current clients for the temporary
+// credentials of the agency, and rebuilds the clients in the delegated domain.
+func buildClientByAgency(c *Config) error {
+	if c.AssumeRoleDomain == "" {
+		return fmt.Errorf("\"assume_role\": the domain_name must be specified")
+	}
+
+	// the IAM client is built by the base credentials and is used to refresh the temporary credentials
+	iamClient, err := c.IAMV3Client(c.Region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine IAM client: %s", err)
+	}
+
+	ext := getConfigExtension(c)
+	if ext.AssumeRoleDuration == 0 {
+		ext.AssumeRoleDuration = defaultAssumeRoleDuration
+	}
+	refresh := func() (*credentialValue, error) {
+		return createAgencyCredentials(iamClient, c.AssumeRoleAgency, c.AssumeRoleDomain, ext.AssumeRoleDuration)
+	}
+	creds, err := newTemporaryCredentials(refresh)
+	if err != nil {
+		return fmt.Errorf("Error assuming agency %s of domain %s: %s", c.AssumeRoleAgency, c.AssumeRoleDomain, err)
+	}
+	ext.Credentials = creds
+	seedConfigCredentials(c, creds)
+
+	// the project and domain are located in the delegated domain
+	c.Token, c.Password = "", ""
+	c.TenantID = ""
+	c.DomainID = ""
+	c.DomainName = c.AssumeRoleDomain
+	return buildClientByAKSK(c)
+}
+
+// createAgencyCredentials creates the temporary AK/SK and security token of an agency
+func createAgencyCredentials(client *golangsdk.ServiceClient, agency, domain string,
+	duration int) (*credentialValue, error) {
+	reqBody := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"assume_role"},
+				"assume_role": map[string]interface{}{
+					"agency_name":      agency,
+					"domain_name":      domain,
+					"duration_seconds": duration,
+				},
+			},
+		},
+	}
+
+	var rst struct {
+		Credential struct {
+			Access        string `json:"access"`
+			Secret        string `json:"secret"`
+			SecurityToken string `json:"securitytoken"`
+			ExpiresAt     string `json:"expires_at"`
+		} `json:"credential"`
+	}
+	url := client.ServiceURL("OS-CREDENTIAL", "securitytokens")
+	_, err := client.Post(url, reqBody, &rst, &golangsdk.RequestOpts{
+		OkCodes: []int{201},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, rst.Credential.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration time of the temporary credentials: %s", err)
+	}
+
+	return &credentialValue{
+		AccessKey:     rst.Credential.Access,
+		SecretKey:     rst.Credential.Secret,
+		SecurityToken: rst.Credential.SecurityToken,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
+
 func genClients(c *Config, pao, dao golangsdk.AuthOptionsProvider) error {
 	client, err := genClient(c, pao)
 	if err != nil {
@@ -295,6 +493,9 @@ func getDomainID(c *Config) (string, error) {
 }
 
 func orchestrationV1Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
+	if sc, err := newCatalogFreeServiceClient(c, "rts", "v1", region); sc != nil || err != nil {
+		return sc, err
+	}
 	return huaweisdk.NewOrchestrationV1(c.HwClient, golangsdk.EndpointOpts{
 		Region:       determineRegion(c, region),
 		Availability: PublicType,
@@ -302,6 +503,9 @@ func orchestrationV1Client(c *Config, region string) (*golangsdk.ServiceClient,
 }
 
 func sdrsV1Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
+	if sc, err := newCatalogFreeServiceClient(c, "sdrs", "v1", region); sc != nil || err != nil {
+		return sc, err
+	}
 	return huaweisdk.NewSDRSV1(c.HwClient, golangsdk.EndpointOpts{
 		Region:       determineRegion(c, region),
 		Availability: PublicType,
@@ -309,6 +513,9 @@ func sdrsV1Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
 }
 
 func otcV1Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
+	if sc, err := newCatalogFreeServiceClient(c, "elb", "v1.0", region); sc != nil || err != nil {
+		return sc, err
+	}
 	return huaweisdk.NewElbV1(c.HwClient, golangsdk.EndpointOpts{
 		Region:       determineRegion(c, region),
 		Availability: PublicType,
@@ -316,6 +523,9 @@ func otcV1Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
 }
 
 func drsV2Client(c *Config, region string) (*golangsdk.ServiceClient, error) {
+	if sc, err := newCatalogFreeServiceClient(c, "evs", "v2", region); sc != nil || err != nil {
+		return sc, err
+	}
 	return huaweisdk.NewDRSServiceV2(c.HwClient, golangsdk.EndpointOpts{
 		Region:       determineRegion(c, region),
 		Availability: PublicType,
@@ -332,6 +542,36 @@ func wafDedicatedv1Client(c *Config, region string) (*golangsdk.ServiceClient, e
 	return wafClient, nil
 }
 
+// objectStorageClient creates the OBS client with the current credentials instead of
+// Config.ObjectStorageClient, which reads the temporary credentials from Config without a lock.
+func objectStorageClient(c *Config, region string) (*obs.ObsClient, error) {
+	return newObjectStorageClient(c, region, false)
+}
+
+// objectStorageClientWithSignature creates the OBS client which signs the requests with the OBS signature
+func objectStorageClientWithSignature(c *Config, region string) (*obs.ObsClient, error) {
+	return newObjectStorageClient(c, region, true)
+}
+
+func newObjectStorageClient(c *Config, region string, withSignature bool) (*obs.ObsClient, error) {
+	creds, err := currentCredentials(c)
+	if err != nil {
+		return nil, err
+	}
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("missing credentials for OBS, need access_key and secret_key values for provider")
+	}
+
+	signature := obs.SignatureV2
+	if withSignature {
+		signature = obs.SignatureObs
+	}
+	return obs.New(creds.AccessKey, creds.SecretKey, getOssEndpoint(c, region),
+		obs.WithSignature(signature), obs.WithSecurityToken(creds.SecurityToken),
+		obs.WithHttpClient(&c.DomainClient.HTTPClient), obs.WithUserAgent("terraform-provider-flexibleengine"),
+		obs.WithProxyFromEnv(true))
+}
+
 func determineRegion(c *Config, region string) string {
 	// If a resource-level region was not specified, and a provider-level region was set,
 	// use the provider-level region.
@@ -342,3 +582,273 @@ func determineRegion(c *Config, region string) string {
 	log.Printf("[DEBUG] FlexibleEngine Region is: %s", region)
 	return region
 }
+
+// projectConfig returns a config whose service clients are scoped to the specified project of the region,
+// so the resources can be managed in several projects of the same region by one provider block.
+// The configs are cached per (region, project) and share the HTTP client and credentials of c.
+func projectConfig(c *Config, region, projectID string) (*Config, error) {
+	region = determineRegion(c, region)
+	if projectID == "" {
+		return c, nil
+	}
+
+	if projectID == defaultProjectIDOfRegion(c, region) {
+		return c, nil
+	}
+
+	// the tokens are scoped to a project, only AK/SK can be used in other projects
+	if creds, err := currentCredentials(c); err != nil || creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("Resource-level project_id can only be specified when using AK/SK authentication")
+	}
+
+	ext := getConfigExtension(c)
+	ext.projectLock.Lock()
+	defer ext.projectLock.Unlock()
+
+	key := region + "/" + projectID
+	if scoped, ok := ext.projectConfigs[key]; ok {
+		return scoped, nil
+	}
+
+	hwClient := *c.HwClient
+	hwClient.ProjectID = projectID
+	hwClient.AKSKAuthOptions.ProjectId = projectID
+	hwClient.AKSKAuthOptions.Region = region
+
+	scoped := *c
+	scoped.HwClient = &hwClient
+	// the project of the region is known, no need to query it by the region name
+	scoped.RegionProjectIDMap = map[string]string{region: projectID}
+	scoped.RPLock = new(sync.Mutex)
+
+	if ext.projectConfigs == nil {
+		ext.projectConfigs = make(map[string]*Config)
+	}
+	ext.projectConfigs[key] = &scoped
+
+	log.Printf("[DEBUG] the clients of region %s are scoped to project %s", region, projectID)
+	return &scoped, nil
+}
+
+// regionConfig returns a config whose endpoints are generated from endpoint_template for the region, so
+// the resources can be managed in other regions than the provider-level one as the catalog does.
+// The configs are cached per region, c is returned if the template is not specified or the region
+// is the region of c.
+func regionConfig(c *Config, region string) (*Config, error) {
+	region = determineRegion(c, region)
+	ext := getConfigExtension(c)
+	if ext.EndpointTemplate == "" || region == c.Region {
+		return c, nil
+	}
+
+	// the tokens are scoped to the project of the provider-level region
+	if creds, err := currentCredentials(c); err != nil || creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("Resource-level region must be the same as Provider-level region " +
+			"when using non AK/SK authentication")
+	}
+
+	ext.regionLock.Lock()
+	defer ext.regionLock.Unlock()
+
+	if scoped, ok := ext.regionConfigs[region]; ok {
+		return scoped, nil
+	}
+
+	endpoints := make(map[string]string, len(ext.CustomEndpoints))
+	for k, v := range ext.CustomEndpoints {
+		endpoints[k] = v
+	}
+	if err := expandEndpointTemplate(endpoints, ext.EndpointTemplate, region); err != nil {
+		return nil, err
+	}
+
+	projectID := c.GetProjectID(region)
+	if projectID == "" {
+		return nil, fmt.Errorf("the project of region %s was not found", region)
+	}
+
+	hwClient := *c.HwClient
+	hwClient.ProjectID = projectID
+	hwClient.AKSKAuthOptions.ProjectId = projectID
+	hwClient.AKSKAuthOptions.Region = region
+
+	scoped := *c
+	scoped.Region = region
+	scoped.Endpoints = endpoints
+	scoped.HwClient = &hwClient
+	scoped.RegionProjectIDMap = map[string]string{region: projectID}
+	scoped.RPLock = new(sync.Mutex)
+
+	if ext.regionConfigs == nil {
+		ext.regionConfigs = make(map[string]*Config)
+	}
+	ext.regionConfigs[region] = &scoped
+
+	log.Printf("[DEBUG] the endpoints of region %s are generated from endpoint_template", region)
+	return &scoped, nil
+}
+
+// defaultProjectIDOfRegion returns the provider-level project of the region, which is loaded
+// by the first service client of the region.
+func defaultProjectIDOfRegion(c *Config, region string) string {
+	c.RPLock.Lock()
+	projectID, ok := c.RegionProjectIDMap[region]
+	c.RPLock.Unlock()
+	if ok || c.HwClient == nil {
+		return projectID
+	}
+
+	if _, err := c.NewServiceClient("ecs", region); err != nil {
+		log.Printf("[WARN] Error loading the project of region %s: %s", region, err)
+	}
+	c.RPLock.Lock()
+	defer c.RPLock.Unlock()
+	return c.RegionProjectIDMap[region]
+}
+
+// projectScopedResource adds the project_id argument to a regional resource, its CRUD and import functions
+// are called with the config scoped to the region and project, so every resource honors project_id and
+// endpoint_template in the same way. The resources which define project_id themselves are only scoped
+// to the region.
+func projectScopedResource(r *schema.Resource) *schema.Resource {
+	if _, ok := r.Schema["region"]; !ok {
+		return r
+	}
+
+	scope := GetProjectConfig
+	_, ownProject := r.Schema["project_id"]
+	if ownProject {
+		scope = getRegionConfig
+	} else {
+		r.Schema["project_id"] = &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+			ForceNew: true,
+		}
+	}
+
+	//lintignore:R009
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+		if f == nil {
+			return nil
+		}
+		return func(d *schema.ResourceData, meta interface{}) error {
+			config, err := scope(d, meta.(*Config))
+			if err != nil {
+				return err
+			}
+			if err := f(d, config); err != nil {
+				return err
+			}
+			if !ownProject {
+				setResourceProjectID(d, config)
+			}
+			return nil
+		}
+	}
+	wrapContext := func(f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics,
+	) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			config, err := scope(d, meta.(*Config))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			diags := f(ctx, d, config)
+			if !diags.HasError() && !ownProject {
+				setResourceProjectID(d, config)
+			}
+			return diags
+		}
+	}
+
+	r.Create, r.Read, r.Update, r.Delete = wrap(r.Create), wrap(r.Read), wrap(r.Update), wrap(r.Delete)
+	r.CreateContext, r.ReadContext = wrapContext(r.CreateContext), wrapContext(r.ReadContext)
+	r.UpdateContext, r.DeleteContext = wrapContext(r.UpdateContext), wrapContext(r.DeleteContext)
+	r.CreateWithoutTimeout, r.ReadWithoutTimeout = wrapContext(r.CreateWithoutTimeout), wrapContext(r.ReadWithoutTimeout)
+	r.UpdateWithoutTimeout, r.DeleteWithoutTimeout = wrapContext(r.UpdateWithoutTimeout), wrapContext(r.DeleteWithoutTimeout)
+
+	// the resource in another project is imported by <project_id>::<import_id>
+	if importer := r.Importer; importer != nil {
+		scoped := &schema.ResourceImporter{}
+		if importer.State != nil {
+			scoped.State = func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				if !ownProject {
+					parseProjectImportID(d)
+				}
+				config, err := scope(d, meta.(*Config))
+				if err != nil {
+					return nil, err
+				}
+				return importer.State(d, config) //lintignore:R009
+			}
+		}
+		if importer.StateContext != nil {
+			scoped.StateContext = func(ctx context.Context, d *schema.ResourceData,
+				meta interface{}) ([]*schema.ResourceData, error) {
+				if !ownProject {
+					parseProjectImportID(d)
+				}
+				config, err := scope(d, meta.(*Config))
+				if err != nil {
+					return nil, err
+				}
+				return importer.StateContext(ctx, d, config)
+			}
+		}
+		r.Importer = scoped
+	}
+	return r
+}
+
+// regionScopedDataSource calls the read functions of a regional data source with the config scoped to
+// the region, so the data sources honor endpoint_template in other regions.
+func regionScopedDataSource(r *schema.Resource) *schema.Resource {
+	if _, ok := r.Schema["region"]; !ok {
+		return r
+	}
+
+	if read := r.Read; read != nil {
+		r.Read = func(d *schema.ResourceData, meta interface{}) error {
+			config, err := getRegionConfig(d, meta.(*Config))
+			if err != nil {
+				return err
+			}
+			return read(d, config)
+		}
+	}
+	wrapContext := func(f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics,
+	) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			config, err := getRegionConfig(d, meta.(*Config))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			return f(ctx, d, config)
+		}
+	}
+	r.ReadContext, r.ReadWithoutTimeout = wrapContext(r.ReadContext), wrapContext(r.ReadWithoutTimeout)
+	return r
+}
+
+// setResourceProjectID records the project of the resource, the provider-level project of the region
+// is recorded if project_id was omitted.
+func setResourceProjectID(d *schema.ResourceData, config *Config) {
+	if d.Id() == "" {
+		return
+	}
+	region := determineRegion(config, GetRegion(d, config))
+	config.RPLock.Lock()
+	projectID, ok := config.RegionProjectIDMap[region]
+	config.RPLock.Unlock()
+	// the resources of the global services have no project
+	if ok {
+		d.Set("project_id", projectID)
+	}
+}
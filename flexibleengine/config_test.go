@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/chnsz/golangsdk"
 	th "github.com/chnsz/golangsdk/testhelper"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
@@ -559,3 +561,112 @@ func TestCheckOssEndpoint(t *testing.T) {
 	expected = "https://oss.eu-west-1.prod-cloud-ocb.orange-business.com/"
 	th.AssertEquals(t, expected, getOssEndpoint(cfg, "eu-west-1"))
 }
+
+func TestProjectConfig(t *testing.T) {
+	cfg := &Config{
+		Region:             "eu-west-0",
+		Cloud:              "prod-cloud-ocb.orange-business.com",
+		AccessKey:          "ak",
+		SecretKey:          "sk",
+		HwClient:           &golangsdk.ProviderClient{ProjectID: "default-project"},
+		RegionProjectIDMap: map[string]string{"eu-west-0": "default-project"},
+		RPLock:             new(sync.Mutex),
+	}
+
+	// the provider-level config is used for the default project
+	scoped, err := projectConfig(cfg, "", "default-project")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, cfg, scoped)
+
+	scoped, err = projectConfig(cfg, "eu-west-0", "dev-project")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "dev-project", scoped.HwClient.ProjectID)
+	th.AssertEquals(t, "default-project", cfg.HwClient.ProjectID)
+
+	client, err := scoped.ComputeV1Client("eu-west-0")
+	th.AssertNoErr(t, err)
+	expected := "https://ecs.eu-west-0.prod-cloud-ocb.orange-business.com/v1/dev-project/"
+	th.AssertEquals(t, expected, client.ResourceBaseURL())
+
+	// the scoped configs are cached per (region, project)
+	cached, err := projectConfig(cfg, "eu-west-0", "dev-project")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, scoped, cached)
+
+	other, err := projectConfig(cfg, "eu-west-0", "prod-project")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, other != scoped)
+
+	// the token is scoped to the default project
+	cfg.AccessKey, cfg.SecretKey = "", ""
+	_, err = projectConfig(cfg, "eu-west-0", "test-project")
+	th.AssertEquals(t, true, err != nil)
+}
+
+func TestProjectScopedResource(t *testing.T) {
+	cfg := &Config{
+		Region:             "eu-west-0",
+		Cloud:              "prod-cloud-ocb.orange-business.com",
+		AccessKey:          "ak",
+		SecretKey:          "sk",
+		HwClient:           &golangsdk.ProviderClient{ProjectID: "default-project"},
+		RegionProjectIDMap: map[string]string{"eu-west-0": "default-project"},
+		RPLock:             new(sync.Mutex),
+	}
+
+	var projectID string
+	r := projectScopedResource(&schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"region": {Type: schema.TypeString, Optional: true, Computed: true},
+		},
+		Read: func(d *schema.ResourceData, meta interface{}) error {
+			projectID = meta.(*Config).HwClient.ProjectID
+			return nil
+		},
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+	})
+	th.AssertEquals(t, true, r.Schema["project_id"].ForceNew)
+
+	// the default project is recorded when project_id is omitted
+	d := r.TestResourceData()
+	d.SetId("resource-id")
+	th.AssertNoErr(t, r.Read(d, cfg))
+	th.AssertEquals(t, "default-project", projectID)
+	th.AssertEquals(t, "default-project", d.Get("project_id"))
+
+	// the resource in another project is imported by <project_id>::<import_id>
+	d = r.TestResourceData()
+	d.SetId("dev-project::resource-id")
+	imported, err := r.Importer.State(d, cfg)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "resource-id", imported[0].Id())
+	th.AssertNoErr(t, r.Read(imported[0], cfg))
+	th.AssertEquals(t, "dev-project", projectID)
+	th.AssertEquals(t, "dev-project", imported[0].Get("project_id"))
+
+	// the import IDs separated by slashes are kept for the importers of the resources
+	d = r.TestResourceData()
+	d.SetId("pool-id/member-id")
+	imported, err = r.Importer.State(d, cfg)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "pool-id/member-id", imported[0].Id())
+	th.AssertEquals(t, "", imported[0].Get("project_id"))
+
+	d = r.TestResourceData()
+	d.SetId("dev-project::pool-id/member-id")
+	imported, err = r.Importer.State(d, cfg)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "pool-id/member-id", imported[0].Id())
+	th.AssertEquals(t, "dev-project", imported[0].Get("project_id"))
+
+	// the resources which are not regional are not changed
+	global := projectScopedResource(&schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString, Required: true},
+		},
+	})
+	_, ok := global.Schema["project_id"]
+	th.AssertEquals(t, false, ok)
+}
@@ -0,0 +1,259 @@
+package flexibleengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chnsz/golangsdk/auth"
+	"github.com/mitchellh/go-homedir"
+)
+
+// credentialsRefreshWindow is how long before the expiration the temporary credentials are refreshed
+const credentialsRefreshWindow = 5 * time.Minute
+
+// credentialValue is a set of temporary AK/SK and security token
+type credentialValue struct {
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+	ExpiresAt     time.Time
+}
+
+// expiring reports whether the credentials are going to expire,
+// the credentials without expiration time never expire.
+func (v credentialValue) expiring() bool {
+	return !v.ExpiresAt.IsZero() && time.Until(v.ExpiresAt) <= credentialsRefreshWindow
+}
+
+// temporaryCredentials holds the temporary credentials which are used to sign all API requests,
+// they are refreshed by the refresh function before they expire.
+type temporaryCredentials struct {
+	mu      sync.Mutex
+	value   credentialValue
+	refresh func() (*credentialValue, error)
+}
+
+func newTemporaryCredentials(refresh func() (*credentialValue, error)) (*temporaryCredentials, error) {
+	creds := &temporaryCredentials{
+		refresh: refresh,
+	}
+
+	if _, err := creds.Get(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// Get returns the current credentials, they will be refreshed if they are going to expire.
+func (tc *temporaryCredentials) Get() (credentialValue, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if tc.value.AccessKey != "" && !tc.value.expiring() {
+		return tc.value, nil
+	}
+	if err := tc.doRefresh(); err != nil {
+		return credentialValue{}, err
+	}
+	return tc.value, nil
+}
+
+// Invalidate refreshes the credentials if they are still the ones identified by accessKey,
+// it is called when a request signed by accessKey was rejected (401).
+func (tc *temporaryCredentials) Invalidate(accessKey string) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	// the credentials have been refreshed by another request
+	if tc.value.AccessKey != accessKey {
+		return nil
+	}
+	return tc.doRefresh()
+}
+
+func (tc *temporaryCredentials) doRefresh() error {
+	value, err := tc.refresh()
+	if err != nil {
+		return fmt.Errorf("error refreshing the temporary credentials: %s", err)
+	}
+
+	log.Printf("[DEBUG] the temporary credentials were refreshed, which will expire at: %s", value.ExpiresAt)
+	tc.value = *value
+	return nil
+}
+
+// currentCredentials returns the AK/SK and security token to sign the requests, the temporary
+// credentials are read through their lock as Config is shared by the resources applied in parallel.
+func currentCredentials(c *Config) (credentialValue, error) {
+	if creds := getConfigExtension(c).Credentials; creds != nil {
+		return creds.Get()
+	}
+	return credentialValue{AccessKey: c.AccessKey, SecretKey: c.SecretKey, SecurityToken: c.SecurityToken}, nil
+}
+
+// credentialRoundTripper satisfies the http.RoundTripper interface and signs the
+// requests with the latest temporary credentials, so the service clients which
+// were created before a refresh keep working.
+type credentialRoundTripper struct {
+	Rt          http.RoundTripper
+	Credentials *temporaryCredentials
+}
+
+// RoundTrip signs the request again with the current credentials and performs it.
+func (crt *credentialRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	// only the requests signed with AK/SK need to be signed again,
+	// the OBS requests have their own signatures and are ignored.
+	if request.Header.Get("X-Sdk-Date") == "" {
+		return crt.Rt.RoundTrip(request)
+	}
+
+	value, err := crt.Credentials.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	req := request.Clone(request.Context())
+	if err := signRequest(req, value); err != nil {
+		return nil, err
+	}
+
+	response, err := crt.Rt.RoundTrip(req)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+	// the request can not be sent again if the body is not rewindable
+	if request.Body != nil && request.GetBody == nil {
+		return response, nil
+	}
+
+	// the credentials may be revoked or expired before the expiration time, refresh and try again
+	log.Printf("[DEBUG] %s %s was rejected with 401, refresh the temporary credentials", request.Method, request.URL)
+	if err := crt.Credentials.Invalidate(value.AccessKey); err != nil {
+		log.Printf("[WARN] %s", err)
+		return response, nil
+	}
+	if value, err = crt.Credentials.Get(); err != nil {
+		return response, nil
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	req = request.Clone(request.Context())
+	if request.GetBody != nil {
+		if req.Body, err = request.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := signRequest(req, value); err != nil {
+		return nil, err
+	}
+	return crt.Rt.RoundTrip(req)
+}
+
+// signRequest signs the request in the same way as golangsdk: the headers which were set
+// after signing are excluded from the signature.
+func signRequest(req *http.Request, value credentialValue) error {
+	unsigned := make(map[string]string)
+	for _, key := range []string{"X-Project-Id", "X-Domain-Id"} {
+		if v := req.Header.Get(key); v != "" {
+			unsigned[key] = v
+		}
+	}
+
+	for _, key := range []string{"Authorization", "X-Sdk-Date", "X-Project-Id", "X-Domain-Id", "X-Security-Token"} {
+		req.Header.Del(key)
+	}
+
+	if err := auth.Sign(req, value.AccessKey, value.SecretKey); err != nil {
+		return err
+	}
+
+	for k, v := range unsigned {
+		req.Header.Set(k, v)
+	}
+	if value.SecurityToken != "" {
+		req.Header.Set("X-Security-Token", value.SecurityToken)
+	}
+	return nil
+}
+
+// credentialProcessTimeout is the maximum time to wait for the credential process
+const credentialProcessTimeout = time.Minute
+
+// externalCredentials is the JSON output of the credential process and the content of the credential file,
+// the expires_at is in RFC3339 format and can be omitted if the credentials never expire.
+type externalCredentials struct {
+	AccessKey     string `json:"access_key"`
+	SecretKey     string `json:"secret_key"`
+	SecurityToken string `json:"security_token"`
+	ExpiresAt     string `json:"expires_at"`
+}
+
+func parseExternalCredentials(data []byte) (*credentialValue, error) {
+	var ext externalCredentials
+	if err := json.Unmarshal(data, &ext); err != nil {
+		return nil, fmt.Errorf("the credentials must be a JSON object: %s", err)
+	}
+	if ext.AccessKey == "" || ext.SecretKey == "" {
+		return nil, fmt.Errorf("access_key and secret_key must be specified in the credentials")
+	}
+
+	value := credentialValue{
+		AccessKey:     ext.AccessKey,
+		SecretKey:     ext.SecretKey,
+		SecurityToken: ext.SecurityToken,
+	}
+	if ext.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, ext.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at of the credentials: %s", err)
+		}
+		value.ExpiresAt = expiresAt
+	}
+	return &value, nil
+}
+
+// runCredentialProcess runs the command by the shell and parses the credentials from its output
+func runCredentialProcess(command string) (*credentialValue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialProcessTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running credential process: %s, stderr: %s", err, stderr.String())
+	}
+	return parseExternalCredentials(output)
+}
+
+// readCredentialFile reads the credentials from the file, which may be rotated by other tools
+func readCredentialFile(path string) (*credentialValue, error) {
+	filePath, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading credential file: %s", err)
+	}
+	return parseExternalCredentials(data)
+}
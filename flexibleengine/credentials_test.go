@@ -0,0 +1,217 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/auth"
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestTemporaryCredentials_refresh(t *testing.T) {
+	var count int
+	refresh := func() (*credentialValue, error) {
+		count++
+		return &credentialValue{
+			AccessKey: fmt.Sprintf("ak-%d", count),
+			SecretKey: "sk",
+			// the first credentials are going to expire
+			ExpiresAt: time.Now().Add(time.Duration(count-1) * time.Hour),
+		}, nil
+	}
+
+	creds, err := newTemporaryCredentials(refresh)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 1, count)
+
+	value, err := creds.Get()
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ak-2", value.AccessKey)
+
+	value, err = creds.Get()
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ak-2", value.AccessKey)
+	th.AssertEquals(t, 2, count)
+}
+
+func TestCredentialRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		th.AssertEquals(t, "new-token", r.Header.Get("X-Security-Token"))
+		th.AssertEquals(t, "project-id", r.Header.Get("X-Project-Id"))
+		if !strings.Contains(r.Header.Get("Authorization"), "Access=new-ak") {
+			t.Errorf("the request was not signed by the new credentials: %s", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	creds, err := newTemporaryCredentials(func() (*credentialValue, error) {
+		return &credentialValue{
+			AccessKey:     "new-ak",
+			SecretKey:     "new-sk",
+			SecurityToken: "new-token",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		}, nil
+	})
+	th.AssertNoErr(t, err)
+
+	// the request was signed by the expired credentials
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	th.AssertNoErr(t, auth.Sign(req, "old-ak", "old-sk"))
+	req.Header.Set("X-Project-Id", "project-id")
+	req.Header.Set("X-Security-Token", "old-token")
+
+	client := http.Client{
+		Transport: &credentialRoundTripper{Rt: http.DefaultTransport, Credentials: creds},
+	}
+	resp, err := client.Do(req)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCreateAgencyCredentials(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	th.Mux.HandleFunc("/OS-CREDENTIAL/securitytokens", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "POST")
+		th.TestJSONRequest(t, r, `
+{
+  "auth": {
+    "identity": {
+      "methods": ["assume_role"],
+      "assume_role": {
+        "agency_name": "ci_agency",
+        "domain_name": "customer",
+        "duration_seconds": 3600
+      }
+    }
+  }
+}`)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `
+{
+  "credential": {
+    "access": "temp-ak",
+    "secret": "temp-sk",
+    "securitytoken": "temp-token",
+    "expires_at": "2026-10-18T08:00:00.000000Z"
+  }
+}`)
+	})
+
+	client := &golangsdk.ServiceClient{
+		ProviderClient: &golangsdk.ProviderClient{},
+		Endpoint:       th.Endpoint(),
+		ResourceBase:   th.Endpoint(),
+	}
+	value, err := createAgencyCredentials(client, "ci_agency", "customer", 3600)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "temp-ak", value.AccessKey)
+	th.AssertEquals(t, "temp-sk", value.SecretKey)
+	th.AssertEquals(t, "temp-token", value.SecurityToken)
+	th.AssertEquals(t, 2026, value.ExpiresAt.Year())
+}
+
+func TestCredentialRoundTripper_unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the credentials were revoked before the expiration time
+		if strings.Contains(r.Header.Get("Authorization"), "Access=ak-1") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var count int
+	creds, err := newTemporaryCredentials(func() (*credentialValue, error) {
+		count++
+		return &credentialValue{
+			AccessKey: fmt.Sprintf("ak-%d", count),
+			SecretKey: "sk",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil
+	})
+	th.AssertNoErr(t, err)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"name":"test"}`))
+	th.AssertNoErr(t, auth.Sign(req, "ak-1", "sk"))
+
+	client := http.Client{
+		Transport: &credentialRoundTripper{Rt: http.DefaultTransport, Credentials: creds},
+	}
+	resp, err := client.Do(req)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, resp.StatusCode)
+	th.AssertEquals(t, 2, count)
+}
+
+func TestCurrentCredentials(t *testing.T) {
+	cfg := &Config{AccessKey: "ak", SecretKey: "sk"}
+	value, err := currentCredentials(cfg)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ak", value.AccessKey)
+
+	var count int
+	creds, err := newTemporaryCredentials(func() (*credentialValue, error) {
+		count++
+		return &credentialValue{
+			AccessKey:     fmt.Sprintf("temp-ak-%d", count),
+			SecretKey:     "temp-sk",
+			SecurityToken: "temp-token",
+			// the credentials are going to expire
+			ExpiresAt: time.Now().Add(time.Minute),
+		}, nil
+	})
+	th.AssertNoErr(t, err)
+	getConfigExtension(cfg).Credentials = creds
+
+	// the temporary credentials are refreshed without changing the shared Config
+	value, err = currentCredentials(cfg)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "temp-ak-2", value.AccessKey)
+	th.AssertEquals(t, "temp-token", value.SecurityToken)
+	th.AssertEquals(t, "ak", cfg.AccessKey)
+	th.AssertEquals(t, "", cfg.SecurityToken)
+}
+
+func TestRunCredentialProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+
+	value, err := runCredentialProcess(`echo '{"access_key":"ak","secret_key":"sk","security_token":"token",` +
+		`"expires_at":"2026-10-18T08:00:00Z"}'`)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ak", value.AccessKey)
+	th.AssertEquals(t, "token", value.SecurityToken)
+	th.AssertEquals(t, true, value.expiring())
+
+	_, err = runCredentialProcess("exit 1")
+	th.AssertEquals(t, true, err != nil)
+}
+
+func TestReadCredentialFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	th.AssertNoErr(t, os.WriteFile(path, []byte(`{"access_key":"ak","secret_key":"sk"}`), 0600))
+
+	// the credentials without expires_at never expire
+	value, err := readCredentialFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ak", value.AccessKey)
+	th.AssertEquals(t, false, value.expiring())
+
+	th.AssertNoErr(t, os.WriteFile(path, []byte(`{"access_key":"ak"}`), 0600))
+	_, err = readCredentialFile(path)
+	th.AssertEquals(t, true, err != nil)
+}
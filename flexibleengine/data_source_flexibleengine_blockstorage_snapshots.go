@@ -0,0 +1,128 @@
+package flexibleengine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/helper/hashcode"
+
+	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/snapshots"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceBlockStorageSnapshots() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceBlockStorageSnapshotsRead,
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"snapshots": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"volume_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"size": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"metadata": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceBlockStorageSnapshotsRead(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	blockStorageClient, err := config.BlockStorageV2Client(region)
+	if err != nil {
+		return diag.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	listOpts := snapshots.ListOpts{
+		VolumeID: d.Get("volume_id").(string),
+		Status:   d.Get("status").(string),
+		Name:     d.Get("name").(string),
+	}
+	pages, err := snapshots.List(blockStorageClient, listOpts).AllPages()
+	if err != nil {
+		return diag.Errorf("Unable to retrieve volume snapshots: %s", err)
+	}
+	allSnapshots, err := snapshots.ExtractSnapshots(pages)
+	if err != nil {
+		return diag.Errorf("Unable to retrieve volume snapshots: %s", err)
+	}
+	log.Printf("[DEBUG] fetching %d volume snapshots.", len(allSnapshots))
+
+	ids := make([]string, len(allSnapshots))
+	result := make([]map[string]interface{}, len(allSnapshots))
+	for i, snapshot := range allSnapshots {
+		ids[i] = snapshot.ID
+		result[i] = map[string]interface{}{
+			"id":          snapshot.ID,
+			"name":        snapshot.Name,
+			"description": snapshot.Description,
+			"volume_id":   snapshot.VolumeID,
+			"size":        snapshot.Size,
+			"status":      snapshot.Status,
+			"metadata":    snapshot.Metadata,
+			"created_at":  snapshot.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	d.SetId(hashcode.Strings(ids))
+	d.Set("region", region)
+	if err := d.Set("snapshots", result); err != nil {
+		return diag.Errorf("Error setting volume snapshots: %s", err)
+	}
+
+	return nil
+}
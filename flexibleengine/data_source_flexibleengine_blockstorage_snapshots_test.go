@@ -0,0 +1,43 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccBlockStorageSnapshotsDataSource_basic(t *testing.T) {
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	dataSourceName := "data.flexibleengine_blockstorage_snapshots.test"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBlockStorageSnapshotsDataSource_basic(rName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "snapshots.#", "1"),
+					resource.TestCheckResourceAttrPair(dataSourceName, "snapshots.0.id",
+						"flexibleengine_blockstorage_snapshot.snapshot_1", "id"),
+					resource.TestCheckResourceAttr(dataSourceName, "snapshots.0.name", rName),
+					resource.TestCheckResourceAttr(dataSourceName, "snapshots.0.status", "available"),
+					resource.TestCheckResourceAttr(dataSourceName, "snapshots.0.metadata.foo", "bar"),
+				),
+			},
+		},
+	})
+}
+
+func testAccBlockStorageSnapshotsDataSource_basic(rName string) string {
+	return fmt.Sprintf(`
+%s
+
+data "flexibleengine_blockstorage_snapshots" "test" {
+  volume_id = flexibleengine_blockstorage_snapshot.snapshot_1.volume_id
+  status    = "available"
+}
+`, testAccBlockStorageSnapshot_basic(rName))
+}
@@ -0,0 +1,238 @@
+package flexibleengine
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/cce/v3/clusters"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v2"
+)
+
+func dataSourceCCEClusterKubeConfig() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCCEClusterKubeConfigRead,
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cluster_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"duration": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ValidateFunc: validation.Any(
+					validation.IntBetween(1, 1827),
+					validation.IntInSlice([]int{-1}),
+				),
+			},
+			"context": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "internal",
+				ValidateFunc: validation.StringInSlice([]string{"internal", "external"}, false),
+			},
+			"kubeconfig": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"host": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cluster_ca_certificate": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"client_certificate": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"client_key": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"expiration_time": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// kubeConfig is the kubeconfig file with only one context
+type kubeConfig struct {
+	APIVersion     string              `yaml:"apiVersion"`
+	Kind           string              `yaml:"kind"`
+	Clusters       []kubeConfigCluster `yaml:"clusters"`
+	Users          []kubeConfigUser    `yaml:"users"`
+	Contexts       []kubeConfigContext `yaml:"contexts"`
+	CurrentContext string              `yaml:"current-context"`
+}
+
+type kubeConfigCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify,omitempty"`
+	} `yaml:"cluster"`
+}
+
+type kubeConfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		ClientCertificateData string `yaml:"client-certificate-data"`
+		ClientKeyData         string `yaml:"client-key-data"`
+	} `yaml:"user"`
+}
+
+type kubeConfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster string `yaml:"cluster"`
+		User    string `yaml:"user"`
+	} `yaml:"context"`
+}
+
+func dataSourceCCEClusterKubeConfigRead(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	cceClient, err := config.CceV3Client(region)
+	if err != nil {
+		return diag.Errorf("Error creating flexibleengine CCE client: %s", err)
+	}
+
+	// the certificates are issued with the duration every time
+	clusterID := d.Get("cluster_id").(string)
+	cert, err := issueCCEClusterCert(cceClient, clusterID, d.Get("duration").(int))
+	if err != nil {
+		return diag.Errorf("Error retrieving the certificates of CCE cluster %s: %s", clusterID, err)
+	}
+
+	contextName := d.Get("context").(string)
+	kubeConf, err := buildCCEClusterKubeConfig(cert, contextName)
+	if err != nil {
+		return diag.Errorf("Error building the kubeconfig of CCE cluster %s: %s", clusterID, err)
+	}
+	kubeconfig, err := yaml.Marshal(kubeConf)
+	if err != nil {
+		return diag.Errorf("Error building the kubeconfig of CCE cluster %s: %s", clusterID, err)
+	}
+
+	cluster := kubeConf.Clusters[0].Cluster
+	user := kubeConf.Users[0].User
+	clientCert := decodeKubeConfigData(user.ClientCertificateData)
+
+	d.SetId(fmt.Sprintf("%s/%s", clusterID, contextName))
+	d.Set("region", region)
+	d.Set("kubeconfig", string(kubeconfig))
+	d.Set("host", cluster.Server)
+	d.Set("cluster_ca_certificate", decodeKubeConfigData(cluster.CertificateAuthorityData))
+	d.Set("client_certificate", clientCert)
+	d.Set("client_key", decodeKubeConfigData(user.ClientKeyData))
+
+	if block, _ := pem.Decode([]byte(clientCert)); block != nil {
+		if parsed, err := x509.ParseCertificate(block.Bytes); err == nil {
+			d.Set("expiration_time", parsed.NotAfter.UTC().Format(time.RFC3339))
+		} else {
+			log.Printf("[WARN] Error parsing the client certificate of CCE cluster %s: %s", clusterID, err)
+		}
+	}
+
+	return nil
+}
+
+// issueCCEClusterCert issues the certificates of the cluster which are valid for the duration in days
+func issueCCEClusterCert(client *golangsdk.ServiceClient, clusterID string, duration int) (*clusters.Certificate, error) {
+	certOpts := map[string]interface{}{
+		"duration": duration,
+	}
+	r := golangsdk.Result{}
+	_, r.Err = client.Post(client.ServiceURL("clusters", clusterID, "clustercert"), certOpts, &r.Body,
+		&golangsdk.RequestOpts{OkCodes: []int{200, 201}})
+	var cert clusters.Certificate
+	if err := r.ExtractInto(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// buildCCEClusterKubeConfig keeps the context and its cluster and user in the certificates
+func buildCCEClusterKubeConfig(cert *clusters.Certificate, contextName string) (*kubeConfig, error) {
+	result := kubeConfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: contextName,
+	}
+
+	var ctx *clusters.CertContexts
+	for i := range cert.Contexts {
+		if cert.Contexts[i].Name == contextName {
+			ctx = &cert.Contexts[i]
+		}
+	}
+	if ctx == nil {
+		if contextName == "external" {
+			return nil, fmt.Errorf("the external context is not found, please bind an EIP to the cluster")
+		}
+		return nil, fmt.Errorf("the %s context is not found", contextName)
+	}
+
+	for _, c := range cert.Clusters {
+		if c.Name == ctx.Context.Cluster {
+			var cluster kubeConfigCluster
+			cluster.Name = c.Name
+			cluster.Cluster.Server = c.Cluster.Server
+			cluster.Cluster.CertificateAuthorityData = c.Cluster.CertAuthorityData
+			// the certificate of the external endpoint is not issued by the cluster CA
+			cluster.Cluster.InsecureSkipTLSVerify = c.Cluster.CertAuthorityData == ""
+			result.Clusters = append(result.Clusters, cluster)
+		}
+	}
+	for _, u := range cert.Users {
+		if u.Name == ctx.Context.User {
+			var user kubeConfigUser
+			user.Name = u.Name
+			user.User.ClientCertificateData = u.User.ClientCertData
+			user.User.ClientKeyData = u.User.ClientKeyData
+			result.Users = append(result.Users, user)
+		}
+	}
+	if len(result.Clusters) == 0 || len(result.Users) == 0 {
+		return nil, fmt.Errorf("the cluster or user of the %s context is not found", contextName)
+	}
+
+	var kubeContext kubeConfigContext
+	kubeContext.Name = ctx.Name
+	kubeContext.Context.Cluster = ctx.Context.Cluster
+	kubeContext.Context.User = ctx.Context.User
+	result.Contexts = []kubeConfigContext{kubeContext}
+
+	return &result, nil
+}
+
+// decodeKubeConfigData decodes the base64 data in the kubeconfig to PEM
+func decodeKubeConfigData(data string) string {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		log.Printf("[WARN] Error decoding the kubeconfig data: %s", err)
+		return ""
+	}
+	return string(decoded)
+}
@@ -0,0 +1,102 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// remoteConsoleActions maps console_type to the server actions which return the remote console
+var remoteConsoleActions = map[string]string{
+	"novnc":  "os-getVNCConsole",
+	"serial": "os-getSerialConsole",
+}
+
+func dataSourceComputeInstanceConsole() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceComputeInstanceConsoleRead,
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"instance_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"lines": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      50,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"console_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "novnc",
+				ValidateFunc: validation.StringInSlice([]string{
+					"novnc", "serial",
+				}, false),
+			},
+			"output": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"console_url": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func dataSourceComputeInstanceConsoleRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	computeClient, err := config.ComputeV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine compute client: %s", err)
+	}
+
+	instanceID := d.Get("instance_id").(string)
+	outputOpts := servers.ShowConsoleOutputOpts{
+		Length: d.Get("lines").(int),
+	}
+	output, err := servers.ShowConsoleOutput(computeClient, instanceID, outputOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("Error retrieving the console output of instance %s: %s", instanceID, err)
+	}
+
+	consoleType := d.Get("console_type").(string)
+	consoleOpts := map[string]interface{}{
+		remoteConsoleActions[consoleType]: map[string]interface{}{
+			"type": consoleType,
+		},
+	}
+	r := golangsdk.Result{}
+	_, r.Err = computeClient.Post(computeClient.ServiceURL("servers", instanceID, "action"), consoleOpts,
+		&r.Body, &golangsdk.RequestOpts{OkCodes: []int{200}})
+	if r.Err != nil {
+		return fmt.Errorf("Error retrieving the %s console of instance %s: %s", consoleType, instanceID, r.Err)
+	}
+
+	url, err := navigateValue(r.Body, []string{"console", "url"}, nil)
+	if err != nil {
+		return fmt.Errorf("Error retrieving the %s console of instance %s: the URL is not found", consoleType, instanceID)
+	}
+	log.Printf("[DEBUG] Retrieved the %s console of instance %s", consoleType, instanceID)
+
+	d.SetId(instanceID)
+	d.Set("region", region)
+	d.Set("output", output)
+	d.Set("console_url", url)
+
+	return nil
+}
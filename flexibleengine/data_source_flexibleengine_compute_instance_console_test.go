@@ -0,0 +1,68 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
+	th "github.com/chnsz/golangsdk/testhelper"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccComputeInstanceConsoleDataSource_basic(t *testing.T) {
+	resourceName := "data.flexibleengine_compute_instance_console.this"
+	var instance servers.Server
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeInstanceConsoleDataSource_basic(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists("flexibleengine_compute_instance_v2.instance_1", &instance),
+					resource.TestCheckResourceAttrPair(resourceName, "id",
+						"flexibleengine_compute_instance_v2.instance_1", "id"),
+					resource.TestCheckResourceAttrSet(resourceName, "output"),
+					resource.TestCheckResourceAttrSet(resourceName, "console_url"),
+				),
+			},
+		},
+	})
+}
+
+func testAccComputeInstanceConsoleDataSource_basic() string {
+	return fmt.Sprintf(`
+%s
+
+data "flexibleengine_compute_instance_console" "this" {
+  instance_id = flexibleengine_compute_instance_v2.instance_1.id
+  lines       = 20
+}
+`, testAccComputeV2Instance_basic)
+}
+
+func TestMockComputeInstanceConsole(t *testing.T) {
+	_, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+	driver.apply(mockComputeInstanceConfig("instance_1"))
+
+	d := readMockDataSource(t, "flexibleengine_compute_instance_console", meta, map[string]interface{}{
+		"instance_id": driver.state.ID,
+		"lines":       3,
+	})
+	th.AssertEquals(t, driver.state.ID, d.Id())
+	th.AssertEquals(t, "boot log line 98\nboot log line 99\nboot log line 100", d.Get("output"))
+	th.AssertEquals(t, true, strings.Contains(d.Get("console_url").(string), "/console/novnc?token="))
+
+	d = readMockDataSource(t, "flexibleengine_compute_instance_console", meta, map[string]interface{}{
+		"instance_id":  driver.state.ID,
+		"console_type": "serial",
+	})
+	th.AssertEquals(t, 50, len(strings.Split(d.Get("output").(string), "\n")))
+	th.AssertEquals(t, true, strings.Contains(d.Get("console_url").(string), "/console/serial?token="))
+
+	driver.destroy()
+}
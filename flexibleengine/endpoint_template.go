@@ -0,0 +1,117 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/config"
+)
+
+// templateCatalogKeys are the keys of the service catalog whose endpoints are generated from
+// endpoint_template, they should be the same as the catalog of the huaweicloud config package.
+var templateCatalogKeys = []string{
+	// global and management services
+	"identity", "iam_no_version", "iam", "identitycenter", "identitystore", "cdn", "eps", "bss", "bssv2",
+	"ces", "cesv2", "cts", "lts", "apm", "smn", "smn-tag", "sms", "tms", "tmsv2", "rms", "organizations",
+	// compute and container services
+	"ecs", "ecsv11", "ecsv21", "autoscaling", "imsv1", "ims", "ccev1", "cce", "cce_addon", "swr", "cci",
+	"cciv1_bata", "ucs", "aom", "fgs", "bms", "aos",
+	// storage services
+	"obs", "evsv1", "evs", "evsv21", "sfs", "sfs-turbo", "cbh", "cbr", "csbs", "vbs", "sdrs",
+	// network services
+	"vpc", "networkv2", "vpcv3", "nat", "natv2", "natv3", "elbv2", "elbv3", "elb", "fwv2", "vpcep", "dns",
+	"dns_region", "workspace", "er", "vpn", "ga", "dc", "cfw",
+	// database services
+	"rdsv1", "rds", "ram", "dds", "geminidb", "geminidbv31", "gaussdb", "opengauss", "drs", "ddm",
+	// security services
+	"aad", "anti-ddos", "kms", "kmsv1", "kmsv3", "waf", "waf-dedicated", "dbss", "hss", "secmaster", "dsc",
+	// enterprise intelligence services
+	"mrs", "mrsv2", "modelarts", "modelartsv2", "dataarts", "dws", "dwsv2", "dli", "dliv2", "dis", "disv3",
+	"css", "cs", "ges", "cloudtable", "cdm", "mls",
+	// application services
+	"apig", "apigv2", "bcs", "cse", "dcsv1", "dcs", "dms", "dmsv2", "servicestage", "servicestagev2", "eg",
+	// other services
+	"iec", "rts", "oms", "scm", "cc", "cpts", "live", "mpc", "iotda", "vod", "cmdb", "codehub", "projectman",
+	"codearts_deploy", "cph", "meeting", "mkt",
+}
+
+// endpointServiceNames are the services whose host names of FlexibleEngine are different
+// from the names in the service catalog.
+var endpointServiceNames = map[string]string{
+	"obs":           "oss",
+	"fgs":           "fgs",
+	"sms":           "sms",
+	"waf-dedicated": "premium-waf",
+}
+
+// endpointServiceName returns the {service} of endpoint_template for the catalog key
+func endpointServiceName(key string) string {
+	if name, ok := endpointServiceNames[key]; ok {
+		return name
+	}
+	if catalog := config.GetServiceCatalog(key); catalog != nil && catalog.Name != "" {
+		return catalog.Name
+	}
+	return key
+}
+
+// expandEndpointTemplate generates the endpoints of the services which are not customized
+// by replacing {service} and {region} in the template.
+func expandEndpointTemplate(endpoints map[string]string, template, region string) error {
+	for _, key := range templateCatalogKeys {
+		if _, ok := endpoints[key]; ok {
+			continue
+		}
+
+		srv := endpointServiceName(key)
+		endpoint := strings.NewReplacer("{service}", srv, "{region}", region).Replace(template)
+		if !strings.HasSuffix(endpoint, "/") {
+			endpoint += "/"
+		}
+
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("the endpoint_template generates an invalid endpoint %q of service %s: %s", endpoint, key, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.ContainsAny(endpoint, "{}") {
+			return fmt.Errorf("the endpoint_template generates an invalid endpoint %q of service %s: "+
+				"it must be an absolute HTTP(S) URL with {service} and {region} only", endpoint, key)
+		}
+		endpoints[key] = endpoint
+	}
+	return nil
+}
+
+// newCatalogFreeServiceClient builds the client of the service which is located by the IAM catalog
+// from the custom endpoint, nil is returned if the endpoint is not specified and skip_catalog is false.
+func newCatalogFreeServiceClient(c *Config, srv, version, region string) (*golangsdk.ServiceClient, error) {
+	// the endpoints of other regions are generated from endpoint_template
+	c, err := regionConfig(c, region)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, ok := c.Endpoints[srv]
+	if !ok {
+		if getConfigExtension(c).SkipCatalog {
+			return nil, fmt.Errorf("the endpoint of service %s can not be resolved without the IAM catalog, "+
+				"please specify it in endpoints or endpoint_template", srv)
+		}
+		return nil, nil
+	}
+
+	// the custom endpoints without endpoint_template are only valid in the provider-level region
+	if region = determineRegion(c, region); region != c.Region {
+		return nil, fmt.Errorf("Resource-level region must be the same as Provider-level region " +
+			"when using customizing endpoints without endpoint_template")
+	}
+
+	sc := &golangsdk.ServiceClient{
+		ProviderClient: c.HwClient,
+		Endpoint:       fmt.Sprintf("%s%s/%s/", endpoint, version, c.HwClient.ProjectID),
+	}
+	sc.ResourceBase = sc.Endpoint
+	return sc, nil
+}
@@ -0,0 +1,127 @@
+package flexibleengine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/config"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestTemplateCatalogKeys(t *testing.T) {
+	for _, key := range templateCatalogKeys {
+		if _, ok := endpointServiceNames[key]; ok {
+			continue
+		}
+		if config.GetServiceCatalog(key) == nil {
+			t.Errorf("the catalog key %s is not found in the huaweicloud config package", key)
+		}
+	}
+}
+
+func TestExpandEndpointTemplate(t *testing.T) {
+	endpoints := map[string]string{
+		"vpc": "https://vpc.custom.example.com/",
+	}
+	err := expandEndpointTemplate(endpoints, "https://{service}.{region}.example.internal", "eu-west-0")
+	th.AssertNoErr(t, err)
+
+	th.AssertEquals(t, "https://ecs.eu-west-0.example.internal/", endpoints["ecs"])
+	th.AssertEquals(t, "https://ecs.eu-west-0.example.internal/", endpoints["ecsv21"])
+	th.AssertEquals(t, "https://iam.eu-west-0.example.internal/", endpoints["identity"])
+	th.AssertEquals(t, "https://oss.eu-west-0.example.internal/", endpoints["obs"])
+	th.AssertEquals(t, "https://premium-waf.eu-west-0.example.internal/", endpoints["waf-dedicated"])
+	// the custom endpoints take precedence over the template
+	th.AssertEquals(t, "https://vpc.custom.example.com/", endpoints["vpc"])
+
+	// the error names the service which can not be resolved
+	err = expandEndpointTemplate(map[string]string{}, "{service}.{zone}.example.internal", "eu-west-0")
+	th.AssertEquals(t, true, err != nil)
+	th.AssertEquals(t, true, strings.Contains(err.Error(), "of service identity"))
+}
+
+func TestMockServer_endpointTemplate(t *testing.T) {
+	server := newMockServer(t)
+
+	// all endpoints including the identity one are generated from the template
+	conf := server.configure(t, map[string]interface{}{
+		"auth_url":          "",
+		"endpoint_template": server.URL + "/{service}/",
+		"skip_catalog":      true,
+		"endpoints": map[string]interface{}{
+			"obs": "http://" + mockOBSHost + "/",
+		},
+	})
+	th.AssertEquals(t, server.URL+"/iam/v3", conf.IdentityEndpoint)
+
+	client, err := conf.NetworkingV1Client(mockRegion)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, server.URL+"/vpc/v1/", client.ResourceBaseURL())
+
+	// the clients located by the catalog are built from the template
+	client, err = sdrsV1Client(conf, mockRegion)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, server.URL+"/sdrs/v1/"+mockProjectID+"/", client.ResourceBaseURL())
+
+	for _, path := range server.paths {
+		if strings.HasSuffix(path, "/auth/catalog") {
+			t.Fatalf("the catalog should not be queried when skip_catalog is true")
+		}
+	}
+}
+
+func TestMockServer_endpointTemplateRegions(t *testing.T) {
+	server := newMockServer(t)
+	meta := server.configure(t, map[string]interface{}{
+		"auth_url":          "",
+		"endpoint_template": server.URL + "/{service}/",
+		"skip_catalog":      true,
+		"endpoints": map[string]interface{}{
+			"obs": "http://" + mockOBSHost + "/",
+		},
+	})
+
+	// the endpoints are generated from the template for the region of the resource
+	driver := newMockResourceDriver(t, "flexibleengine_blockstorage_volume_v2", meta)
+	driver.apply(map[string]interface{}{
+		"region":            mockOtherRegion,
+		"name":              "volume_1",
+		"size":              10,
+		"volume_type":       "SSD",
+		"availability_zone": mockAZ,
+	})
+	th.AssertEquals(t, mockOtherRegion, driver.state.Attributes["region"])
+	th.AssertEquals(t, mockOtherProjectID, driver.state.Attributes["project_id"])
+
+	var found bool
+	for _, path := range server.paths {
+		if strings.HasPrefix(path, "/evs/v2/"+mockOtherProjectID+"/") {
+			found = true
+		}
+	}
+	th.AssertEquals(t, true, found)
+
+	client, err := sdrsV1Client(meta, mockOtherRegion)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, server.URL+"/sdrs/v1/"+mockOtherProjectID+"/", client.ResourceBaseURL())
+	driver.destroy()
+}
+
+func TestMockServer_skipCatalog(t *testing.T) {
+	server := newMockServer(t)
+	conf := server.configure(t, map[string]interface{}{
+		"skip_catalog": true,
+	})
+
+	_, err := orchestrationV1Client(conf, mockRegion)
+	th.AssertEquals(t, true, err != nil)
+	th.AssertEquals(t, true, strings.Contains(err.Error(), "service rts"))
+
+	// the template must contain the {service} placeholder
+	raw := server.providerConfig()
+	raw["endpoint_template"] = server.URL + "/ecs/"
+	diags := Provider().Validate(terraform.NewResourceConfigRaw(raw))
+	th.AssertEquals(t, true, diags.HasError())
+}
@@ -0,0 +1,204 @@
+package flexibleengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	httpLogFormatText = "text"
+	httpLogFormatJSON = "json"
+)
+
+// defaultHTTPLogRedactKeys are the JSON keys whose values are always redacted in the HTTP logs
+var defaultHTTPLogRedactKeys = append([]string{
+	"admin_pwd", "manager_admin_pwd", "user_pwd", "db_user_pwd", "new_password",
+}, sensitiveBodyKeys...)
+
+// requestIDHeaders are the response headers which carry the request ID of the services
+var requestIDHeaders = []string{
+	"X-Request-Id", "X-Openstack-Request-Id", "X-Compute-Request-Id", "X-Obs-Request-Id",
+}
+
+// httpLogOptions is the configuration of the structured HTTP logs
+type httpLogOptions struct {
+	// Services are the service catalog names to be logged, empty means all services
+	Services map[string]bool
+	// IncludeBodies indicates whether to log the JSON bodies of the requests and responses
+	IncludeBodies bool
+	// Format is the format of the records, text or json
+	Format string
+	// RedactKeys are the JSON keys whose values are redacted in the bodies
+	RedactKeys []string
+
+	// hosts is the map of the custom endpoint hosts and their service names
+	hosts map[string]string
+}
+
+func newHTTPLogOptions(services []string, includeBodies bool, format string, redactKeys []string,
+	endpoints map[string]string) *httpLogOptions {
+	opts := &httpLogOptions{
+		Services:      make(map[string]bool, len(services)),
+		IncludeBodies: includeBodies,
+		Format:        format,
+		RedactKeys:    append(append([]string{}, defaultHTTPLogRedactKeys...), redactKeys...),
+	}
+	for _, srv := range services {
+		opts.Services[srv] = true
+	}
+	opts.hosts = endpointServiceHosts(endpoints, func(srv string) bool {
+		return opts.Services[srv]
+	})
+	return opts
+}
+
+// enabled reports whether the requests of the service should be logged.
+func (o *httpLogOptions) enabled(srv string) bool {
+	return len(o.Services) == 0 || o.Services[srv]
+}
+
+// httpLogRecord is the structured record of an HTTP request
+type httpLogRecord struct {
+	Service      string      `json:"service"`
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Status       int         `json:"status,omitempty"`
+	LatencyMs    int64       `json:"latency_ms"`
+	RequestID    string      `json:"request_id,omitempty"`
+	RequestBody  interface{} `json:"request_body,omitempty"`
+	ResponseBody interface{} `json:"response_body,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// String returns the record in the text format.
+func (r *httpLogRecord) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s status=%d latency=%dms", r.Service, r.Method, r.URL, r.Status, r.LatencyMs)
+	if r.RequestID != "" {
+		fmt.Fprintf(&b, " request_id=%s", r.RequestID)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, " error=%q", r.Error)
+	}
+	if r.RequestBody != nil {
+		fmt.Fprintf(&b, "\nrequest body: %s", formatHTTPLogBody(r.RequestBody))
+	}
+	if r.ResponseBody != nil {
+		fmt.Fprintf(&b, "\nresponse body: %s", formatHTTPLogBody(r.ResponseBody))
+	}
+	return b.String()
+}
+
+// httpLogRoundTripper satisfies the http.RoundTripper interface and emits one structured
+// record per request of the selected services, it replaces LogRoundTripper when http_log is set.
+type httpLogRoundTripper struct {
+	Rt      http.RoundTripper
+	Options *httpLogOptions
+}
+
+// RoundTrip performs the HTTP request and logs the record of it.
+func (lrt *httpLogRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	srv := serviceNameOfURL(lrt.Options.hosts, request.URL)
+	if !lrt.Options.enabled(srv) {
+		return lrt.Rt.RoundTrip(request)
+	}
+
+	record := &httpLogRecord{
+		Service: srv,
+		Method:  request.Method,
+		URL:     request.URL.String(),
+	}
+
+	if lrt.Options.IncludeBodies && isJSONContent(request.Header) {
+		body, err := readRequestBody(request)
+		if err != nil {
+			return nil, err
+		}
+		record.RequestBody = lrt.redactBody(body)
+	}
+
+	start := time.Now()
+	response, err := lrt.Rt.RoundTrip(request)
+	record.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if response != nil {
+		record.Status = response.StatusCode
+		record.RequestID = getRequestID(response.Header)
+
+		if lrt.Options.IncludeBodies && isJSONContent(response.Header) && response.Body != nil {
+			body, readErr := io.ReadAll(response.Body)
+			response.Body.Close()
+			if readErr != nil {
+				return nil, readErr
+			}
+			response.Body = io.NopCloser(bytes.NewReader(body))
+			record.ResponseBody = lrt.redactBody(body)
+		}
+	}
+
+	lrt.log(record)
+	return response, err
+}
+
+func (lrt *httpLogRoundTripper) log(record *httpLogRecord) {
+	if lrt.Options.Format != httpLogFormatJSON {
+		log.Printf("[DEBUG] HTTP %s", record)
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("[WARN] failed to marshal the HTTP log record: %s", err)
+		return
+	}
+	log.Printf("[DEBUG] HTTP %s", data)
+}
+
+// redactBody decodes the JSON body and redacts the sensitive values, the body which is not
+// valid JSON is replaced by its length so that the secrets in it will never be logged.
+func (lrt *httpLogRoundTripper) redactBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+	return redactJSON(data, lrt.Options.RedactKeys)
+}
+
+func formatHTTPLogBody(body interface{}) string {
+	if s, ok := body.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Sprintf("%v", body)
+	}
+	return string(data)
+}
+
+func isJSONContent(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Type"), "json")
+}
+
+func getRequestID(header http.Header) string {
+	for _, key := range requestIDHeaders {
+		if v := header.Get(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
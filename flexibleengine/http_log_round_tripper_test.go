@@ -0,0 +1,115 @@
+package flexibleengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestServiceNameOfURL(t *testing.T) {
+	endpoints := map[string]string{
+		"obs": "https://oss.eu-west-0.prod-cloud-ocb.orange-business.com/",
+	}
+	hosts := endpointServiceHosts(endpoints, func(string) bool { return false })
+
+	u, _ := url.Parse("https://cce.eu-west-0.prod-cloud-ocb.orange-business.com/api/v3/projects")
+	th.AssertEquals(t, "cce", serviceNameOfURL(hosts, u))
+
+	// the virtual hosted buckets belong to the obs service
+	u, _ = url.Parse("https://bucket-1.oss.eu-west-0.prod-cloud-ocb.orange-business.com/object")
+	th.AssertEquals(t, "obs", serviceNameOfURL(hosts, u))
+}
+
+func TestHTTPLogRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// the requests to the mock server belong to the service named by the custom endpoint
+	opts := newHTTPLogOptions([]string{"cce"}, true, httpLogFormatJSON, []string{"kubeconfig"},
+		map[string]string{"cce": server.URL + "/"})
+	client := http.Client{
+		Transport: &httpLogRoundTripper{Rt: http.DefaultTransport, Options: opts},
+	}
+
+	reqBody := `{"node":{"admin_pass":"Secret123","user_pwd":"Secret456","kubeconfig":"abc","name":"node-1"}}`
+	request, _ := http.NewRequest("POST", server.URL+"/api/v3/nodes", strings.NewReader(reqBody))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	th.AssertNoErr(t, err)
+	defer response.Body.Close()
+
+	// the body is still readable by the caller
+	respBody, err := io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, reqBody, string(respBody))
+
+	output := buf.String()
+	th.AssertEquals(t, false, strings.Contains(output, "Secret123"))
+	th.AssertEquals(t, false, strings.Contains(output, "Secret456"))
+	th.AssertEquals(t, false, strings.Contains(output, `"abc"`))
+
+	index := strings.Index(output, "[DEBUG] HTTP ")
+	th.AssertEquals(t, true, index >= 0)
+	line := strings.TrimSpace(strings.SplitN(output[index+len("[DEBUG] HTTP "):], "\n", 2)[0])
+
+	var record httpLogRecord
+	th.AssertNoErr(t, json.Unmarshal([]byte(line), &record))
+	th.AssertEquals(t, "cce", record.Service)
+	th.AssertEquals(t, "POST", record.Method)
+	th.AssertEquals(t, http.StatusCreated, record.Status)
+	th.AssertEquals(t, "req-123", record.RequestID)
+
+	node := record.RequestBody.(map[string]interface{})["node"].(map[string]interface{})
+	th.AssertEquals(t, redactedValue, node["admin_pass"])
+	th.AssertEquals(t, redactedValue, node["user_pwd"])
+	th.AssertEquals(t, redactedValue, node["kubeconfig"])
+	th.AssertEquals(t, "node-1", node["name"])
+}
+
+func TestHTTPLogRoundTripper_services(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	opts := newHTTPLogOptions([]string{"ecs"}, false, httpLogFormatText, nil,
+		map[string]string{"vpc": server.URL + "/"})
+	client := http.Client{
+		Transport: &httpLogRoundTripper{Rt: http.DefaultTransport, Options: opts},
+	}
+
+	// the requests of the other services are not logged
+	response, err := client.Get(server.URL + "/v1/vpcs")
+	th.AssertNoErr(t, err)
+	response.Body.Close()
+	th.AssertEquals(t, false, strings.Contains(buf.String(), "[DEBUG] HTTP "))
+
+	opts.Services["vpc"] = true
+	response, err = client.Get(server.URL + "/v1/vpcs")
+	th.AssertNoErr(t, err)
+	response.Body.Close()
+	th.AssertEquals(t, true, strings.Contains(buf.String(), "[DEBUG] HTTP vpc GET "+server.URL+"/v1/vpcs status=204"))
+}
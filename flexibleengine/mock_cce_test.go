@@ -0,0 +1,421 @@
+package flexibleengine
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// addCCECluster adds an available CCE cluster with the version, the clusters are not
+// created with the mock server as the cluster resource waits minutes for the creation.
+func (s *mockServer) addCCECluster(name, version string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.objects["clusters"] == nil {
+		s.objects["clusters"] = make(map[string]map[string]interface{})
+	}
+	id := s.newID("cluster")
+	s.objects["clusters"][id] = map[string]interface{}{
+		"kind":       "Cluster",
+		"apiVersion": "v3",
+		"metadata":   map[string]interface{}{"uid": id, "name": name},
+		"spec": map[string]interface{}{
+			"type":    "VirtualMachine",
+			"flavor":  "cce.s1.small",
+			"version": version,
+			"hostNetwork": map[string]interface{}{
+				"vpc": "vpc-0001", "subnet": "subnet-0001", "SecurityGroup": "secgroup-0001",
+			},
+			"containerNetwork": map[string]interface{}{"mode": "overlay_l2", "cidr": "172.16.0.0/16"},
+			"authentication":   map[string]interface{}{"mode": "rbac"},
+		},
+		"status": map[string]interface{}{
+			"phase": "Available",
+			"endpoints": []interface{}{
+				map[string]interface{}{"url": "https://192.168.0.10:5443", "type": "Internal"},
+			},
+		},
+	}
+	return id
+}
+
+// serveCCECluster handles the cluster APIs, the pre-upgrade check and upgrade tasks are finished at once
+func (s *mockServer) serveCCECluster(w http.ResponseWriter, r *http.Request, parts []string,
+	body map[string]interface{}) {
+	if len(parts) < 2 {
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+		return
+	}
+	cluster, ok := s.objects["clusters"][parts[1]]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "cluster %s could not be found", parts[1])
+		return
+	}
+	spec := cluster["spec"].(map[string]interface{})
+
+	path := strings.Join(parts[2:], "/")
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		s.writeJSON(w, http.StatusOK, cluster)
+	case path == "" && r.Method == http.MethodPut:
+		update, _ := body["spec"].(map[string]interface{})
+		for k, v := range update {
+			spec[k] = v
+		}
+		s.writeJSON(w, http.StatusOK, cluster)
+	case path == "operation/hibernate" && r.Method == http.MethodPost:
+		cluster["status"].(map[string]interface{})["phase"] = "Hibernation"
+		w.WriteHeader(http.StatusOK)
+	case path == "operation/awake" && r.Method == http.MethodPost:
+		cluster["status"].(map[string]interface{})["phase"] = "Available"
+		w.WriteHeader(http.StatusOK)
+	case path == "clustercert" && r.Method == http.MethodPost:
+		s.issueClusterCert(w, cluster, body)
+	case path == "operation/precheck" && r.Method == http.MethodPost:
+		opts, _ := body["spec"].(map[string]interface{})
+		task := s.newCCETask(map[string]interface{}{"targetVersion": opts["targetVersion"]})
+		status := task["status"].(map[string]interface{})
+		// the target version must be newer than the version of the cluster
+		item := map[string]interface{}{"name": "ClusterVersion", "status": "Success"}
+		if !mockVersionNewer(fmt.Sprint(opts["targetVersion"]), fmt.Sprint(spec["version"])) {
+			item["status"] = "Failed"
+			item["message"] = fmt.Sprintf("can not upgrade from %v to %v", spec["version"], opts["targetVersion"])
+			status["phase"] = "Failed"
+		}
+		status["clusterCheckResult"] = map[string]interface{}{"checkItemStatus": []interface{}{item}}
+		s.writeJSON(w, http.StatusOK, task)
+	case path == "operation/upgrade" && r.Method == http.MethodPost:
+		opts, _ := body["spec"].(map[string]interface{})
+		action, _ := opts["clusterUpgradeAction"].(map[string]interface{})
+		var checked bool
+		for _, task := range s.objects["cce_tasks"] {
+			status := task["status"].(map[string]interface{})
+			checked = checked || (task["spec"].(map[string]interface{})["targetVersion"] == action["targetVersion"] &&
+				status["phase"] == "Success" && status["clusterCheckResult"] != nil)
+		}
+		if !checked {
+			s.writeError(w, http.StatusBadRequest, "the cluster must be checked before upgrading to %v", action["targetVersion"])
+			return
+		}
+		if phase := cluster["status"].(map[string]interface{})["phase"]; phase != "Available" {
+			s.writeError(w, http.StatusBadRequest, "the cluster in %v status can not be upgraded", phase)
+			return
+		}
+		task := s.newCCETask(action)
+		// the upgrade fails if a failure message is set on the cluster, otherwise it is paused
+		// between the batches once
+		if message, ok := cluster["__upgrade_failure"]; ok {
+			task["status"] = map[string]interface{}{"phase": "Failed", "message": message}
+		} else {
+			task["status"] = map[string]interface{}{"phase": "Pause"}
+			spec["version"] = action["targetVersion"]
+		}
+		s.writeJSON(w, http.StatusOK, task)
+	case len(parts) == 6 && parts[2] == "operation" && parts[4] == "tasks" && r.Method == http.MethodGet:
+		task, ok := s.objects["cce_tasks"][parts[5]]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "task %s could not be found", parts[5])
+			return
+		}
+		s.writeJSON(w, http.StatusOK, task)
+		if status := task["status"].(map[string]interface{}); status["phase"] == "Pause" {
+			status["phase"] = "Success"
+		}
+	case len(parts) == 4 && parts[2] == "nodepools":
+		s.serveCCENodePool(w, r, parts[3], body)
+	case path == "nodes" && r.Method == http.MethodGet:
+		items := []interface{}{}
+		for _, node := range s.objects["cce_nodes"] {
+			items = append(items, node)
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "List", "apiVersion": "v3", "items": items})
+	case path == "nodes/reset" && r.Method == http.MethodPost:
+		s.resetCCENodes(w, body)
+	case len(parts) >= 4 && parts[2] == "nodes":
+		s.serveCCENode(w, r, parts[3:])
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// addCCENodePool adds a node pool with the nodes created from the template
+func (s *mockServer) addCCENodePool(name string, count int, template map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.initCCENodes()
+	id := s.newID("pool")
+	pool := map[string]interface{}{
+		"kind":       "NodePool",
+		"apiVersion": "v3",
+		"metadata":   map[string]interface{}{"uid": id, "name": name},
+		"spec": map[string]interface{}{
+			"type":             "vm",
+			"initialNodeCount": float64(count),
+			"nodeTemplate":     template,
+			"autoscaling":      map[string]interface{}{},
+		},
+		"status":       map[string]interface{}{"phase": ""},
+		"__peak_nodes": 0,
+	}
+	s.objects["cce_nodepools"][id] = pool
+	s.syncCCENodePool(pool)
+	return id
+}
+
+// addCCENode adds an active node which is not in any node pool, the spec is
+// the same as the node template of the node pools.
+func (s *mockServer) addCCENode(name, privateIP string, spec map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.initCCENodes()
+	id := s.newID("node")
+	s.objects["cce_nodes"][id] = map[string]interface{}{
+		"kind":       "Node",
+		"apiVersion": "v3",
+		"metadata":   map[string]interface{}{"uid": id, "name": name, "annotations": map[string]interface{}{}},
+		"spec":       spec,
+		"status": map[string]interface{}{
+			"phase": "Active", "privateIP": privateIP, "serverId": s.newID("server"),
+		},
+		"__resets": 0,
+	}
+	return id
+}
+
+func (s *mockServer) initCCENodes() {
+	if s.objects["cce_nodepools"] == nil {
+		s.objects["cce_nodepools"] = make(map[string]map[string]interface{})
+		s.objects["cce_nodes"] = make(map[string]map[string]interface{})
+		s.objects["cce_deleted_nodes"] = make(map[string]map[string]interface{})
+	}
+}
+
+// resetCCENodes reinstalls the nodes with the OS in the request at once
+func (s *mockServer) resetCCENodes(w http.ResponseWriter, body map[string]interface{}) {
+	if body["kind"] != "List" || body["apiversion"] != "v3" {
+		s.writeError(w, http.StatusBadRequest, "the kind or apiversion is invalid")
+		return
+	}
+	nodeList, _ := body["nodeList"].([]interface{})
+	for _, raw := range nodeList {
+		item, _ := raw.(map[string]interface{})
+		node, ok := s.objects["cce_nodes"][fmt.Sprint(item["nodeID"])]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "node %v could not be found", item["nodeID"])
+			return
+		}
+		spec, _ := item["spec"].(map[string]interface{})
+		node["spec"].(map[string]interface{})["os"] = spec["os"]
+		node["__lifecycle"] = spec["lifecycle"]
+		node["__resets"] = node["__resets"].(int) + 1
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"jobid": s.newID("job")})
+}
+
+// syncCCENodePool creates the nodes with the current template until the node count is reached,
+// the nodes are created at once.
+func (s *mockServer) syncCCENodePool(pool map[string]interface{}) {
+	id := pool["metadata"].(map[string]interface{})["uid"].(string)
+	spec := pool["spec"].(map[string]interface{})
+	var nodeCount int
+	for _, node := range s.objects["cce_nodes"] {
+		if node["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["kubernetes.io/node-pool.id"] == id {
+			nodeCount++
+		}
+	}
+
+	for ; nodeCount < int(spec["initialNodeCount"].(float64)); nodeCount++ {
+		// the spec of the node is a copy of the current template
+		nodeSpec := make(map[string]interface{})
+		for k, v := range spec["nodeTemplate"].(map[string]interface{}) {
+			nodeSpec[k] = v
+		}
+		nodeID := s.newID("node")
+		s.objects["cce_nodes"][nodeID] = map[string]interface{}{
+			"kind":       "Node",
+			"apiVersion": "v3",
+			"metadata": map[string]interface{}{
+				"uid":         nodeID,
+				"name":        nodeID,
+				"annotations": map[string]interface{}{"kubernetes.io/node-pool.id": id},
+			},
+			"spec": nodeSpec,
+			"status": map[string]interface{}{
+				"phase": "Active", "privateIP": fmt.Sprintf("192.168.1.%d", s.counter%250+1),
+			},
+			"__pool_id": id,
+		}
+	}
+	if nodeCount > pool["__peak_nodes"].(int) {
+		pool["__peak_nodes"] = nodeCount
+	}
+	pool["status"].(map[string]interface{})["currentNode"] = nodeCount
+}
+
+func (s *mockServer) serveCCENodePool(w http.ResponseWriter, r *http.Request, id string, body map[string]interface{}) {
+	pool, ok := s.objects["cce_nodepools"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "node pool %s could not be found", id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, pool)
+	case http.MethodPut:
+		spec := pool["spec"].(map[string]interface{})
+		update, _ := body["spec"].(map[string]interface{})
+		if count, ok := update["initialNodeCount"]; ok {
+			spec["initialNodeCount"] = count
+		}
+		if autoscaling, ok := update["autoscaling"]; ok {
+			spec["autoscaling"] = autoscaling
+		}
+		template := spec["nodeTemplate"].(map[string]interface{})
+		newTemplate, _ := update["nodeTemplate"].(map[string]interface{})
+		for k, v := range newTemplate {
+			template[k] = v
+		}
+		if metadata, ok := body["metadata"].(map[string]interface{}); ok && metadata["name"] != nil {
+			pool["metadata"].(map[string]interface{})["name"] = metadata["name"]
+		}
+		s.syncCCENodePool(pool)
+		s.writeJSON(w, http.StatusOK, pool)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method %s is not allowed", r.Method)
+	}
+}
+
+// serveCCENode handles the nodes, the deleted nodes scale in their node pools if any
+func (s *mockServer) serveCCENode(w http.ResponseWriter, r *http.Request, parts []string) {
+	node, ok := s.objects["cce_nodes"][parts[0]]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "node %s could not be found", parts[0])
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.writeJSON(w, http.StatusOK, node)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(s.objects["cce_nodes"], parts[0])
+		s.objects["cce_deleted_nodes"][parts[0]] = node
+		if poolID, ok := node["__pool_id"].(string); ok {
+			pool := s.objects["cce_nodepools"][poolID]
+			spec := pool["spec"].(map[string]interface{})
+			spec["initialNodeCount"] = spec["initialNodeCount"].(float64) - 1
+			s.syncCCENodePool(pool)
+		}
+		s.writeJSON(w, http.StatusOK, node)
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// issueClusterCert issues the kubeconfig of the cluster, the certificates are valid for the duration
+// and the external context is returned if the cluster has an external endpoint.
+func (s *mockServer) issueClusterCert(w http.ResponseWriter, cluster map[string]interface{},
+	body map[string]interface{}) {
+	duration, _ := body["duration"].(float64)
+	if duration == -1 {
+		duration = 1827
+	}
+	if duration < 1 || duration > 1827 {
+		s.writeError(w, http.StatusBadRequest, "the duration %v is invalid", body["duration"])
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(s.counter)),
+		Subject:      pkix.Name{CommonName: "user"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Duration(duration) * 24 * time.Hour),
+	}
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+
+	clusters := []interface{}{}
+	contexts := []interface{}{}
+	status := cluster["status"].(map[string]interface{})
+	for _, raw := range status["endpoints"].([]interface{}) {
+		endpoint := raw.(map[string]interface{})
+		name := strings.ToLower(fmt.Sprint(endpoint["type"]))
+		server := map[string]interface{}{"server": endpoint["url"]}
+		if ca, ok := cluster["__ca"]; ok && name == "internal" {
+			server["certificate-authority-data"] = ca
+		} else if name == "internal" {
+			server["certificate-authority-data"] = certData
+		} else {
+			server["insecure-skip-tls-verify"] = true
+		}
+		clusters = append(clusters, map[string]interface{}{"name": name + "Cluster", "cluster": server})
+		contexts = append(contexts, map[string]interface{}{
+			"name": name, "context": map[string]interface{}{"cluster": name + "Cluster", "user": "user"},
+		})
+
+		// the external endpoint is also verified by the cluster CA if the certificate of the server is issued by it
+		if ca, ok := cluster["__ca"]; ok && name == "external" && endpoint["__tls_verify"] == true {
+			clusters = append(clusters, map[string]interface{}{
+				"name": "externalClusterTLSVerify", "cluster": map[string]interface{}{
+					"server": endpoint["url"], "certificate-authority-data": ca,
+				},
+			})
+			contexts = append(contexts, map[string]interface{}{
+				"name": "externalTLSVerify", "context": map[string]interface{}{
+					"cluster": "externalClusterTLSVerify", "user": "user",
+				},
+			})
+		}
+	}
+
+	s.writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"kind":       "Config",
+		"apiVersion": "v1",
+		"clusters":   clusters,
+		"users": []interface{}{
+			map[string]interface{}{
+				"name": "user",
+				"user": map[string]interface{}{"client-certificate-data": certData, "client-key-data": keyData},
+			},
+		},
+		"contexts":        contexts,
+		"current-context": "internal",
+	})
+}
+
+func (s *mockServer) newCCETask(spec map[string]interface{}) map[string]interface{} {
+	if s.objects["cce_tasks"] == nil {
+		s.objects["cce_tasks"] = make(map[string]map[string]interface{})
+	}
+	id := s.newID("task")
+	task := map[string]interface{}{
+		"metadata": map[string]interface{}{"uid": id},
+		"spec":     spec,
+		"status":   map[string]interface{}{"phase": "Success"},
+	}
+	s.objects["cce_tasks"][id] = task
+	return task
+}
+
+// mockVersionNewer reports whether the Kubernetes version v1.x is newer than the other one
+func mockVersionNewer(version, other string) bool {
+	var major, minor, otherMajor, otherMinor int
+	fmt.Sscanf(version, "v%d.%d", &major, &minor)
+	fmt.Sscanf(other, "v%d.%d", &otherMajor, &otherMinor)
+	return major > otherMajor || (major == otherMajor && minor > otherMinor)
+}
@@ -0,0 +1,211 @@
+package flexibleengine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+// mockUnknownValue is the value of the configuration which is not known until apply,
+// e.g. an attribute of a resource which is not created yet.
+const mockUnknownValue = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+// mockResourceDriver drives the CRUD functions of a resource against the mock server
+// in the same order as terraform plan and apply, without the terraform binary.
+type mockResourceDriver struct {
+	t        *testing.T
+	resource *schema.Resource
+	meta     interface{}
+	state    *terraform.InstanceState
+}
+
+// newMockTest starts a mock server for the test which runs in parallel and returns the provider meta
+// which sends the requests to it.
+func newMockTest(t *testing.T) (*mockServer, *Config) {
+	t.Parallel()
+	server := newMockServer(t)
+	return server, server.configure(t, nil)
+}
+
+func newMockResourceDriver(t *testing.T, name string, meta interface{}) *mockResourceDriver {
+	r, ok := Provider().ResourcesMap[name]
+	if !ok {
+		t.Fatalf("resource %s was not found in the provider", name)
+	}
+	return &mockResourceDriver{t: t, resource: r, meta: meta}
+}
+
+// plan returns the difference between the state and the configuration
+func (m *mockResourceDriver) plan(raw map[string]interface{}) *terraform.InstanceDiff {
+	// the raw configuration is passed with the prior state like Terraform does
+	state := m.state
+	if state != nil {
+		state = state.DeepCopy()
+		state.RawConfig = m.rawConfig(raw)
+	}
+
+	diff, err := m.resource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), m.meta)
+	if err != nil {
+		m.t.Fatalf("error planning %v: %s", raw, err)
+	}
+	if diff != nil {
+		diff.RawConfig = m.rawConfig(raw)
+	}
+	return diff
+}
+
+// rawConfig converts the configuration to the value returned by ResourceData.GetRawConfig
+func (m *mockResourceDriver) rawConfig(raw map[string]interface{}) cty.Value {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		m.t.Fatalf("error marshaling %v: %s", raw, err)
+	}
+	val, err := ctyjson.Unmarshal(data, m.resource.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		m.t.Fatalf("error converting %v to the raw configuration: %s", raw, err)
+	}
+	val, _ = cty.Transform(val, func(_ cty.Path, v cty.Value) (cty.Value, error) {
+		if v.Type() == cty.String && v.IsKnown() && !v.IsNull() && v.AsString() == mockUnknownValue {
+			return cty.UnknownVal(cty.String), nil
+		}
+		return v, nil
+	})
+	return val
+}
+
+// apply creates or updates the resource with the configuration
+func (m *mockResourceDriver) apply(raw map[string]interface{}) {
+	diff := m.plan(raw)
+	if diff == nil {
+		return
+	}
+
+	state, diags := m.resource.Apply(context.Background(), m.state, diff, m.meta)
+	if diags.HasError() {
+		m.t.Fatalf("error applying %v: %v", raw, diags)
+	}
+	m.state = state
+
+	// the configuration has been applied completely
+	if diff := m.plan(raw); diff != nil && !diff.Empty() {
+		m.t.Fatalf("the plan is not empty after applying: %v", diff)
+	}
+}
+
+// refresh reads the resource and returns the refreshed state
+func (m *mockResourceDriver) refresh() *terraform.InstanceState {
+	state, diags := m.resource.RefreshWithoutUpgrade(context.Background(), m.state, m.meta)
+	if diags.HasError() {
+		m.t.Fatalf("error refreshing %s: %v", m.state.ID, diags)
+	}
+	return state
+}
+
+// importState imports the resource with id and reads it
+func (m *mockResourceDriver) importState(id string) *terraform.InstanceState {
+	d := m.resource.Data(&terraform.InstanceState{ID: id})
+
+	var results []*schema.ResourceData
+	var err error
+	importer := m.resource.Importer
+	if importer.StateContext != nil {
+		results, err = importer.StateContext(context.Background(), d, m.meta)
+	} else {
+		results, err = importer.State(d, m.meta) //lintignore:R009
+	}
+	if err != nil {
+		m.t.Fatalf("error importing %s: %s", id, err)
+	}
+	th.AssertEquals(m.t, 1, len(results))
+
+	state, diags := m.resource.RefreshWithoutUpgrade(context.Background(), results[0].State(), m.meta)
+	if diags.HasError() {
+		m.t.Fatalf("error reading the imported %s: %v", id, diags)
+	}
+	return state
+}
+
+// destroy deletes the resource
+func (m *mockResourceDriver) destroy() {
+	_, diags := m.resource.Apply(context.Background(), m.state, &terraform.InstanceDiff{Destroy: true}, m.meta)
+	if diags.HasError() {
+		m.t.Fatalf("error destroying %s: %v", m.state.ID, diags)
+	}
+	m.state = nil
+}
+
+// readMockDataSource reads the data source with the configuration
+func readMockDataSource(t *testing.T, name string, meta interface{}, raw map[string]interface{}) *schema.ResourceData {
+	ds, ok := Provider().DataSourcesMap[name]
+	if !ok {
+		t.Fatalf("data source %s was not found in the provider", name)
+	}
+
+	d := schema.TestResourceDataRaw(t, ds.Schema, raw)
+	if ds.ReadContext != nil {
+		if diags := ds.ReadContext(context.Background(), d, meta); diags.HasError() {
+			t.Fatalf("error reading %s: %v", name, diags)
+		}
+		return d
+	}
+	if err := ds.Read(d, meta); err != nil { //lintignore:R009
+		t.Fatalf("error reading %s: %s", name, err)
+	}
+	return d
+}
+
+// mockResourceStep is a configuration applied by testMockResource
+type mockResourceStep struct {
+	config map[string]interface{}
+	// attrs are the expected attributes of the state after applying the configuration
+	attrs map[string]string
+	// check verifies the objects in the mock server after applying the configuration
+	check func(t *testing.T, server *mockServer, state *terraform.InstanceState)
+}
+
+// mockResourceCase is the lifecycle of a resource which is run by testMockResource
+type mockResourceCase struct {
+	resource string
+	steps    []mockResourceStep
+	// importAttrs are compared between the applied state and the imported state
+	importAttrs []string
+	// collections are the mock collections which are empty once the resource is destroyed
+	collections []string
+}
+
+// testMockResource applies the steps of the case in order, imports the resource and destroys it.
+func testMockResource(t *testing.T, c mockResourceCase) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, c.resource, meta)
+
+	for _, step := range c.steps {
+		driver.apply(step.config)
+		for k, v := range step.attrs {
+			if got := driver.state.Attributes[k]; got != v {
+				t.Fatalf("expected %s to be %q after applying %v, got %q", k, v, step.config, got)
+			}
+		}
+		if step.check != nil {
+			step.check(t, server, driver.state)
+		}
+	}
+
+	if len(c.importAttrs) > 0 {
+		imported := driver.importState(driver.state.ID)
+		for _, k := range c.importAttrs {
+			th.AssertEquals(t, driver.state.Attributes[k], imported.Attributes[k])
+		}
+	}
+
+	driver.destroy()
+	for _, collection := range c.collections {
+		th.AssertEquals(t, 0, len(server.objects[collection]))
+	}
+}
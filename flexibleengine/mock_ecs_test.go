@@ -0,0 +1,531 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// serveServer handles the compute v2.1 APIs of the servers
+func (s *mockServer) serveServer(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	if len(parts) == 1 && r.Method == http.MethodPost {
+		s.createServer(w, body)
+		return
+	}
+
+	if len(parts) == 1 {
+		s.serveCollection(w, r, "servers", "", body)
+		return
+	}
+
+	server, ok := s.objects["servers"][parts[1]]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "server %s could not be found", parts[1])
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method == http.MethodDelete {
+			for _, port := range server["ports"].([]string) {
+				delete(s.objects["ports"], port)
+			}
+			for _, volume := range server["volumes"].([]string) {
+				delete(s.objects["volumes"], volume)
+			}
+		}
+		s.serveCollection(w, r, "servers", parts[1], body)
+		return
+	}
+
+	metadata := server["metadata"].(map[string]interface{})
+	switch {
+	case parts[2] == "action" && r.Method == http.MethodPost:
+		s.serverAction(w, server, body)
+	case parts[2] == "metadata" && len(parts) == 3:
+		if changes, ok := body["metadata"].(map[string]interface{}); ok {
+			if r.Method == http.MethodPut {
+				metadata = make(map[string]interface{})
+				server["metadata"] = metadata
+			}
+			for k, v := range changes {
+				metadata[k] = v
+			}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"metadata": metadata})
+	case parts[2] == "metadata" && len(parts) == 4 && r.Method == http.MethodDelete:
+		delete(metadata, parts[3])
+		w.WriteHeader(http.StatusNoContent)
+	case parts[2] == "os-interface" && len(parts) == 3 && r.Method == http.MethodPost:
+		s.attachInterface(w, server, body)
+	case parts[2] == "os-interface" && len(parts) == 4:
+		s.serveInterface(w, r, server, parts[3])
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// attachInterface attaches the port or a new port of the network to the server
+func (s *mockServer) attachInterface(w http.ResponseWriter, server, body map[string]interface{}) {
+	opts, _ := body["interfaceAttachment"].(map[string]interface{})
+	portID, _ := opts["port_id"].(string)
+	if portID == "" {
+		ip := fmt.Sprintf("192.168.0.%d", s.counter%250+2)
+		if fixedIPs, ok := opts["fixed_ips"].([]interface{}); ok && len(fixedIPs) > 0 {
+			ip, _ = fixedIPs[0].(map[string]interface{})["ip_address"].(string)
+		}
+		port := s.createObject("ports", map[string]interface{}{
+			"network_id":   opts["net_id"],
+			"mac_address":  fmt.Sprintf("fa:16:3e:00:00:%02x", s.counter%256),
+			"fixed_ips":    []interface{}{map[string]interface{}{"subnet_id": opts["net_id"], "ip_address": ip}},
+			"device_owner": "compute:nova",
+		})
+		portID = port["id"].(string)
+	} else if _, ok := s.objects["ports"][portID]; !ok {
+		s.writeError(w, http.StatusBadRequest, "port %s could not be found", portID)
+		return
+	}
+
+	server["ports"] = append(server["ports"].([]string), portID)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"interfaceAttachment": s.renderInterface(portID)})
+}
+
+// serveInterface gets or detaches the port of the server, the port created by the server is deleted
+func (s *mockServer) serveInterface(w http.ResponseWriter, r *http.Request, server map[string]interface{}, portID string) {
+	ports := server["ports"].([]string)
+	index := -1
+	for i, id := range ports {
+		if id == portID {
+			index = i
+		}
+	}
+	if index < 0 {
+		s.writeError(w, http.StatusNotFound, "port %s is not attached to server %s", portID, server["id"])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"interfaceAttachment": s.renderInterface(portID)})
+	case http.MethodDelete:
+		server["ports"] = append(ports[:index:index], ports[index+1:]...)
+		if s.objects["ports"][portID]["device_owner"] == "compute:nova" {
+			delete(s.objects["ports"], portID)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+func (s *mockServer) renderInterface(portID string) map[string]interface{} {
+	port := s.objects["ports"][portID]
+	return map[string]interface{}{
+		"port_id":    portID,
+		"net_id":     port["network_id"],
+		"mac_addr":   port["mac_address"],
+		"fixed_ips":  port["fixed_ips"],
+		"port_state": "ACTIVE",
+	}
+}
+
+// createServer creates the server by the compute v2.1 API
+func (s *mockServer) createServer(w http.ResponseWriter, body map[string]interface{}) {
+	opts, _ := body["server"].(map[string]interface{})
+	if opts == nil {
+		s.writeError(w, http.StatusBadRequest, "server must be specified")
+		return
+	}
+
+	server := s.addServer(opts)
+	s.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"server": map[string]interface{}{"id": server["id"], "adminPass": opts["adminPass"]},
+	})
+}
+
+// createCloudServer creates the server by the ECS v1.1 API, the options are converted to
+// the compute v2.1 options and the spot options are saved for the assertions
+func (s *mockServer) createCloudServer(w http.ResponseWriter, body map[string]interface{}) {
+	opts, _ := body["server"].(map[string]interface{})
+	if opts == nil {
+		s.writeError(w, http.StatusBadRequest, "server must be specified")
+		return
+	}
+	if _, ok := s.objects["vpcs"][fmt.Sprint(opts["vpcid"])]; !ok {
+		s.writeError(w, http.StatusBadRequest, "VPC %v could not be found", opts["vpcid"])
+		return
+	}
+
+	rootVolume, _ := opts["root_volume"].(map[string]interface{})
+	if rootVolume == nil || rootVolume["volumetype"] == nil || rootVolume["volumetype"] == "" {
+		s.writeError(w, http.StatusBadRequest, "root_volume.volumetype must be specified")
+		return
+	}
+
+	networks := []interface{}{}
+	nics, _ := opts["nics"].([]interface{})
+	for _, raw := range nics {
+		nic := raw.(map[string]interface{})
+		networks = append(networks, map[string]interface{}{"uuid": nic["subnet_id"], "fixed_ip": nic["ip_address"]})
+	}
+	opts["networks"] = networks
+
+	securityGroups := []interface{}{}
+	groups, _ := opts["security_groups"].([]interface{})
+	for _, raw := range groups {
+		id := raw.(map[string]interface{})["id"]
+		group, ok := s.objects["security-groups"][fmt.Sprint(id)]
+		if !ok {
+			s.writeError(w, http.StatusBadRequest, "security group %v could not be found", id)
+			return
+		}
+		securityGroups = append(securityGroups, map[string]interface{}{"name": group["name"]})
+	}
+	opts["security_groups"] = securityGroups
+
+	server := s.addServer(opts)
+	server["root_volume"] = rootVolume
+	if extendParam, ok := opts["extendparam"].(map[string]interface{}); ok {
+		server["extendparam"] = extendParam
+		if extendParam["marketType"] == "spot" {
+			server["charging_mode"] = "2"
+		}
+
+		// the prePaid server is paid by the order and returned by the BSS API
+		if extendParam["chargingMode"] == chargingModePrePaid {
+			server["charging_mode"] = "1"
+			server["period_type"] = float64(prePaidPeriodTypes[fmt.Sprint(extendParam["periodType"])])
+			server["period_num"] = extendParam["periodNum"]
+			server["auto_renew"] = extendParam["isAutoRenew"] == "true"
+			order := s.createObject("orders", map[string]interface{}{
+				"status": 5, "resource_ids": []interface{}{server["id"]},
+			})
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{"order_id": order["id"]})
+			return
+		}
+	}
+
+	job := s.createObject("jobs", map[string]interface{}{
+		"status": "SUCCESS",
+		"entities": map[string]interface{}{
+			"sub_jobs": []interface{}{
+				map[string]interface{}{
+					"status":   "SUCCESS",
+					"entities": map[string]interface{}{"server_id": server["id"]},
+				},
+			},
+		},
+	})
+	job["job_id"] = job["id"]
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": job["id"]})
+}
+
+// createServerImage creates the system image and the data images of the server
+func (s *mockServer) createServerImage(w http.ResponseWriter, body map[string]interface{}) {
+	server, ok := s.objects["servers"][fmt.Sprint(body["instance_id"])]
+	if !ok {
+		s.writeError(w, http.StatusBadRequest, "server %v could not be found", body["instance_id"])
+		return
+	}
+
+	image := s.createObject("images", map[string]interface{}{
+		"name":          body["name"],
+		"status":        "active",
+		"visibility":    "private",
+		"min_disk":      40,
+		"__description": body["description"],
+		"__data_origin": fmt.Sprintf("instance,%s", server["id"]),
+	})
+
+	// each data image is created by a sub job
+	subJobs := []interface{}{}
+	dataImages, _ := body["data_images"].([]interface{})
+	for _, raw := range dataImages {
+		dataImage := raw.(map[string]interface{})
+		if _, ok := s.objects["volumes"][fmt.Sprint(dataImage["volume_id"])]; !ok {
+			s.writeError(w, http.StatusBadRequest, "volume %v could not be found", dataImage["volume_id"])
+			return
+		}
+		image := s.createObject("images", map[string]interface{}{
+			"name":          dataImage["name"],
+			"status":        "active",
+			"visibility":    "private",
+			"__data_origin": fmt.Sprintf("volume,%s", dataImage["volume_id"]),
+		})
+		subJobs = append(subJobs, map[string]interface{}{
+			"status":   "SUCCESS",
+			"entities": map[string]interface{}{"image_id": image["id"], "image_name": image["name"]},
+		})
+	}
+
+	job := s.createObject("jobs", map[string]interface{}{
+		"status":   "SUCCESS",
+		"entities": map[string]interface{}{"image_id": image["id"], "sub_jobs_result": subJobs},
+	})
+	job["job_id"] = job["id"]
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": job["id"]})
+}
+
+// addServer adds the server and a port for each network
+func (s *mockServer) addServer(opts map[string]interface{}) map[string]interface{} {
+	ports := []string{}
+	networks, _ := opts["networks"].([]interface{})
+	for _, raw := range networks {
+		network := raw.(map[string]interface{})
+		if portID, ok := network["port"].(string); ok && portID != "" {
+			ports = append(ports, portID)
+			continue
+		}
+
+		ip, _ := network["fixed_ip"].(string)
+		if ip == "" {
+			ip = fmt.Sprintf("192.168.0.%d", s.counter%250+2)
+		}
+		port := s.createObject("ports", map[string]interface{}{
+			"network_id":  network["uuid"],
+			"mac_address": fmt.Sprintf("fa:16:3e:00:00:%02x", s.counter%256),
+			"fixed_ips":   []interface{}{map[string]interface{}{"subnet_id": network["uuid"], "ip_address": ip}},
+		})
+		ports = append(ports, port["id"].(string))
+	}
+
+	securityGroups := []interface{}{}
+	if groups, ok := opts["security_groups"].([]interface{}); ok {
+		securityGroups = groups
+	}
+	metadata, _ := opts["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	az, _ := opts["availability_zone"].(string)
+	if az == "" {
+		az = mockAZ
+	}
+
+	// the system disk is created from the image and deleted together with the server
+	systemDisk := s.createObject("volumes", map[string]interface{}{
+		"name":        fmt.Sprintf("%s-volume-0000", opts["name"]),
+		"size":        40,
+		"volume_type": "SSD",
+		"bootable":    "true",
+	})
+	systemDisk["status"] = "in-use"
+
+	server := s.createObject("servers", map[string]interface{}{
+		"name":              opts["name"],
+		"status":            "ACTIVE",
+		"image":             map[string]interface{}{"id": opts["imageRef"]},
+		"flavor":            map[string]interface{}{"id": opts["flavorRef"]},
+		"key_name":          opts["key_name"],
+		"metadata":          metadata,
+		"security_groups":   securityGroups,
+		"availability_zone": az,
+		"auto_recovery":     "false",
+		"ports":             ports,
+		"volumes":           []string{systemDisk["id"].(string)},
+	})
+	return server
+}
+
+func (s *mockServer) serverAction(w http.ResponseWriter, server, body map[string]interface{}) {
+	for action, raw := range body {
+		params, _ := raw.(map[string]interface{})
+		switch action {
+		case "os-start":
+			server["status"] = "ACTIVE"
+		case "os-stop":
+			server["status"] = "SHUTOFF"
+		case "reboot", "changePassword":
+		case "os-getConsoleOutput":
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{"output": mockConsoleOutput(params["length"])})
+			return
+		case "os-getVNCConsole", "os-getSerialConsole":
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{
+				"console": map[string]interface{}{
+					"type": params["type"],
+					"url":  fmt.Sprintf("%s/console/%s?token=%s", s.URL, params["type"], s.newID("token")),
+				},
+			})
+			return
+		case "resize":
+			server["flavor"] = map[string]interface{}{"id": params["flavorRef"]}
+			server["status"] = "VERIFY_RESIZE"
+		case "confirmResize":
+			server["status"] = "ACTIVE"
+		case "addSecurityGroup":
+			groups := server["security_groups"].([]interface{})
+			server["security_groups"] = append(groups, map[string]interface{}{"name": params["name"]})
+		case "removeSecurityGroup":
+			groups := []interface{}{}
+			for _, g := range server["security_groups"].([]interface{}) {
+				if g.(map[string]interface{})["name"] != params["name"] {
+					groups = append(groups, g)
+				}
+			}
+			server["security_groups"] = groups
+		default:
+			s.writeError(w, http.StatusBadRequest, "the action %s is not supported", action)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// mockConsoleOutput returns the last lines of a boot log which has 100 lines
+func mockConsoleOutput(length interface{}) string {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("boot log line %d", i+1)
+	}
+	if n, ok := length.(float64); ok && int(n) < len(lines) {
+		lines = lines[len(lines)-int(n):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cloudServersAction starts, stops or reboots the servers in batch
+func (s *mockServer) cloudServersAction(w http.ResponseWriter, body map[string]interface{}) {
+	for action, raw := range body {
+		params, _ := raw.(map[string]interface{})
+		servers, _ := params["servers"].([]interface{})
+		for _, item := range servers {
+			id, _ := item.(map[string]interface{})["id"].(string)
+			server, ok := s.objects["servers"][id]
+			if !ok {
+				s.writeError(w, http.StatusNotFound, "server %s could not be found", id)
+				return
+			}
+
+			switch action {
+			case "os-start":
+				server["status"] = "ACTIVE"
+			case "os-stop":
+				server["status"] = "SHUTOFF"
+			case "reboot":
+				reboots, _ := server["reboots"].(int)
+				server["reboots"] = reboots + 1
+			default:
+				s.writeError(w, http.StatusBadRequest, "the action %s is not supported", action)
+				return
+			}
+		}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": s.newID("job")})
+}
+
+// changeServerOS changes the image of the server, the options are saved for the assertions
+func (s *mockServer) changeServerOS(w http.ResponseWriter, id string, body map[string]interface{}) {
+	server, ok := s.objects["servers"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "server %s could not be found", id)
+		return
+	}
+	opts, _ := body["os-change"].(map[string]interface{})
+	imageID, _ := opts["imageid"].(string)
+	if _, ok := s.objects["images"][imageID]; !ok {
+		s.writeError(w, http.StatusBadRequest, "image %s could not be found", imageID)
+		return
+	}
+
+	server["image"] = map[string]interface{}{"id": imageID}
+	server["os-change"] = opts
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": s.newID("job")})
+}
+
+// serveCloudServer handles the ECS v1 APIs which return the details of a server and its disks
+func (s *mockServer) serveCloudServer(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method != http.MethodGet || len(parts) < 2 {
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+		return
+	}
+
+	server, ok := s.objects["servers"][parts[1]]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "server %s could not be found", parts[1])
+		return
+	}
+
+	volumes := []interface{}{}
+	for i, id := range server["volumes"].([]string) {
+		volume := map[string]interface{}{
+			"id":         id,
+			"volumeId":   id,
+			"serverId":   server["id"],
+			"bootIndex":  i,
+			"device":     fmt.Sprintf("/dev/vd%c", 'a'+i),
+			"pciAddress": fmt.Sprintf("0000:02:%02d.0", i+1),
+		}
+		if len(parts) == 4 && parts[2] == "block_device" && parts[3] == id {
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{"volumeAttachment": volume})
+			return
+		}
+		volumes = append(volumes, map[string]interface{}{
+			"id": id, "bootIndex": fmt.Sprint(i), "device": volume["device"], "delete_on_termination": "true",
+		})
+	}
+	if len(parts) != 2 {
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+		return
+	}
+
+	addresses := make(map[string][]interface{})
+	for _, portID := range server["ports"].([]string) {
+		port, ok := s.objects["ports"][portID]
+		if !ok {
+			continue
+		}
+
+		networkID := port["network_id"].(string)
+		for _, raw := range port["fixed_ips"].([]interface{}) {
+			addresses[networkID] = append(addresses[networkID], map[string]interface{}{
+				"version":                 "4",
+				"addr":                    raw.(map[string]interface{})["ip_address"],
+				"OS-EXT-IPS-MAC:mac_addr": port["mac_address"],
+				"OS-EXT-IPS:port_id":      portID,
+				"OS-EXT-IPS:type":         "fixed",
+			})
+		}
+		for _, eip := range s.objects["publicips"] {
+			if eip["port_id"] == portID {
+				addresses[networkID] = append(addresses[networkID], map[string]interface{}{
+					"version":            "4",
+					"addr":               eip["public_ip_address"],
+					"OS-EXT-IPS:port_id": portID,
+					"OS-EXT-IPS:type":    "floating",
+				})
+			}
+		}
+	}
+
+	flavor := server["flavor"].(map[string]interface{})
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"server": map[string]interface{}{
+			"id":                                   server["id"],
+			"name":                                 server["name"],
+			"status":                               server["status"],
+			"tenant_id":                            mockProjectID,
+			"key_name":                             server["key_name"],
+			"addresses":                            addresses,
+			"flavor":                               map[string]interface{}{"id": flavor["id"], "name": flavor["id"]},
+			"image":                                server["image"],
+			"metadata":                             map[string]interface{}{"charging_mode": server["charging_mode"]},
+			"security_groups":                      server["security_groups"],
+			"OS-EXT-AZ:availability_zone":          server["availability_zone"],
+			"os-extended-volumes:volumes_attached": volumes,
+		},
+	})
+}
+
+func (s *mockServer) serveAutoRecovery(w http.ResponseWriter, r *http.Request, id string, body map[string]interface{}) {
+	server, ok := s.objects["servers"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "server %s could not be found", id)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		server["auto_recovery"] = body["support_auto_recovery"]
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"support_auto_recovery": server["auto_recovery"]})
+}
@@ -0,0 +1,262 @@
+package flexibleengine
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockKubernetes is an in-memory fake of the Kubernetes API of a CCE cluster, the requests
+// must be authenticated with a client certificate as the certificates issued by CCE.
+type mockKubernetes struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	nodes map[string]map[string]interface{}
+	// pods are keyed by namespace/name
+	pods map[string]map[string]interface{}
+	// refusals are the number of evictions refused by the PodDisruptionBudgets of each pod
+	refusals map[string]int
+	// evicted are the evicted pods in order
+	evicted []string
+	// objects are the objects applied with server-side apply, keyed by their paths
+	objects  map[string]map[string]interface{}
+	uids     int
+	versions int
+}
+
+// mockKubernetesResources are the resources served by the discovery API, keyed by the group paths
+var mockKubernetesResources = map[string][]interface{}{
+	"/api/v1": {
+		map[string]interface{}{"name": "configmaps", "namespaced": true, "kind": "ConfigMap"},
+		map[string]interface{}{"name": "namespaces", "namespaced": false, "kind": "Namespace"},
+		map[string]interface{}{"name": "nodes", "namespaced": false, "kind": "Node"},
+		map[string]interface{}{"name": "pods", "namespaced": true, "kind": "Pod"},
+		map[string]interface{}{"name": "pods/eviction", "namespaced": true, "kind": "Eviction"},
+		map[string]interface{}{"name": "secrets", "namespaced": true, "kind": "Secret"},
+	},
+	"/apis/rbac.authorization.k8s.io/v1": {
+		map[string]interface{}{"name": "clusterroles", "namespaced": false, "kind": "ClusterRole"},
+	},
+}
+
+// startKubernetes starts the Kubernetes API of the cluster, the internal endpoint of the cluster
+// points to it and the certificate of the server is issued as the CA of the cluster.
+func (s *mockServer) startKubernetes(t *testing.T, clusterID string) *mockKubernetes {
+	k := &mockKubernetes{
+		nodes:    make(map[string]map[string]interface{}),
+		pods:     make(map[string]map[string]interface{}),
+		refusals: make(map[string]int),
+		objects:  make(map[string]map[string]interface{}),
+	}
+	k.Server = httptest.NewUnstartedServer(http.HandlerFunc(k.serveHTTP))
+	k.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	k.StartTLS()
+	t.Cleanup(k.Close)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cluster := s.objects["clusters"][clusterID]
+	cluster["status"].(map[string]interface{})["endpoints"] = []interface{}{
+		map[string]interface{}{"url": k.URL, "type": "Internal"},
+	}
+	cluster["__ca"] = base64.StdEncoding.EncodeToString(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: k.Certificate().Raw}))
+	return k
+}
+
+// addNode adds a schedulable node with the internal IP
+func (k *mockKubernetes) addNode(name, internalIP string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.nodes[name] = map[string]interface{}{
+		"kind":     "Node",
+		"metadata": map[string]interface{}{"name": name},
+		"spec":     map[string]interface{}{},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{
+				map[string]interface{}{"type": "InternalIP", "address": internalIP},
+				map[string]interface{}{"type": "Hostname", "address": name},
+			},
+		},
+	}
+}
+
+// addPod adds a running pod on the node, the pod is owned by the kind if it is not empty
+// and the first evictions of the pod are refused for the refusals times.
+func (k *mockKubernetes) addPod(namespace, name, nodeName, owner string, refusals int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	metadata := map[string]interface{}{"name": name, "namespace": namespace}
+	if owner != "" {
+		metadata["ownerReferences"] = []interface{}{
+			map[string]interface{}{"kind": owner, "name": name + "-owner"},
+		}
+	}
+	k.pods[namespace+"/"+name] = map[string]interface{}{
+		"kind":     "Pod",
+		"metadata": metadata,
+		"spec":     map[string]interface{}{"nodeName": nodeName},
+		"status":   map[string]interface{}{"phase": "Running"},
+	}
+	k.refusals[namespace+"/"+name] = refusals
+}
+
+func (k *mockKubernetes) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		k.writeStatus(w, http.StatusUnauthorized, "Unauthorized", "the client certificate is required")
+		return
+	}
+
+	var body map[string]interface{}
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			k.writeStatus(w, http.StatusBadRequest, "BadRequest", fmt.Sprintf("invalid JSON body: %s", err))
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case mockKubernetesResources[r.URL.Path] != nil && r.Method == http.MethodGet:
+		k.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"kind": "APIResourceList", "resources": mockKubernetesResources[r.URL.Path],
+		})
+	case r.Method == http.MethodPatch && r.Header.Get("Content-Type") == "application/apply-patch+yaml":
+		k.applyObject(w, r, parts, body)
+	case k.objects[r.URL.Path] != nil && r.Method == http.MethodGet:
+		k.writeJSON(w, http.StatusOK, k.objects[r.URL.Path])
+	case k.objects[r.URL.Path] != nil && r.Method == http.MethodDelete:
+		delete(k.objects, r.URL.Path)
+		k.writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "Status", "status": "Success"})
+	case r.URL.Path == "/api/v1/nodes" && r.Method == http.MethodGet:
+		items := []interface{}{}
+		for _, node := range k.nodes {
+			items = append(items, node)
+		}
+		k.writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "NodeList", "items": items})
+	case len(parts) == 4 && parts[2] == "nodes" && r.Method == http.MethodPatch:
+		node, ok := k.nodes[parts[3]]
+		if !ok {
+			k.writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("nodes %q not found", parts[3]))
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			k.writeStatus(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", "the patch type is not supported")
+			return
+		}
+		spec, _ := body["spec"].(map[string]interface{})
+		for key, v := range spec {
+			node["spec"].(map[string]interface{})[key] = v
+		}
+		k.writeJSON(w, http.StatusOK, node)
+	case r.URL.Path == "/api/v1/pods" && r.Method == http.MethodGet:
+		nodeName := strings.TrimPrefix(r.URL.Query().Get("fieldSelector"), "spec.nodeName=")
+		items := []interface{}{}
+		for _, pod := range k.pods {
+			if pod["spec"].(map[string]interface{})["nodeName"] == nodeName {
+				items = append(items, pod)
+			}
+		}
+		k.writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "PodList", "items": items})
+	case len(parts) == 7 && parts[2] == "namespaces" && parts[4] == "pods" && parts[6] == "eviction" &&
+		r.Method == http.MethodPost:
+		k.evictPod(w, parts[3]+"/"+parts[5])
+	default:
+		k.writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("the API %s %s is not supported", r.Method, r.URL.Path))
+	}
+}
+
+// applyObject replaces the object with the body, the fields which are not in the body are removed
+// as the fields managed by the same field manager.
+func (k *mockKubernetes) applyObject(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	if r.URL.Query().Get("fieldManager") == "" {
+		k.writeStatus(w, http.StatusBadRequest, "BadRequest", "PATCH requests with apply must specify a fieldManager")
+		return
+	}
+	metadata, _ := body["metadata"].(map[string]interface{})
+	if metadata == nil || metadata["name"] != parts[len(parts)-1] {
+		k.writeStatus(w, http.StatusBadRequest, "BadRequest", "the name of the object does not match the URL")
+		return
+	}
+	if len(parts) > 4 && parts[len(parts)-4] == "namespaces" {
+		namespace := parts[len(parts)-3]
+		if v, ok := metadata["namespace"]; ok && v != namespace {
+			k.writeStatus(w, http.StatusBadRequest, "BadRequest", "the namespace of the object does not match the URL")
+			return
+		}
+		metadata["namespace"] = namespace
+	}
+
+	if old, ok := k.objects[r.URL.Path]; ok {
+		metadata["uid"] = old["metadata"].(map[string]interface{})["uid"]
+	} else {
+		k.uids++
+		metadata["uid"] = fmt.Sprintf("%08d-0000-0000-0000-000000000000", k.uids)
+	}
+	// the stringData of the Secrets is write-only and merged into data
+	if stringData, ok := body["stringData"].(map[string]interface{}); ok && body["kind"] == "Secret" {
+		data, _ := body["data"].(map[string]interface{})
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		for key, value := range stringData {
+			data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+		}
+		body["data"] = data
+		delete(body, "stringData")
+	}
+
+	k.versions++
+	metadata["resourceVersion"] = fmt.Sprint(k.versions)
+	k.objects[r.URL.Path] = body
+	k.writeJSON(w, http.StatusOK, body)
+}
+
+// evictPod deletes the pod at once unless the eviction is refused by the PodDisruptionBudget
+func (k *mockKubernetes) evictPod(w http.ResponseWriter, key string) {
+	if _, ok := k.pods[key]; !ok {
+		k.writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("pods %q not found", key))
+		return
+	}
+	if k.refusals[key] > 0 {
+		k.refusals[key]--
+		k.writeStatus(w, http.StatusTooManyRequests, "TooManyRequests",
+			"Cannot evict pod as it would violate the pod's disruption budget.")
+		return
+	}
+
+	delete(k.pods, key)
+	k.evicted = append(k.evicted, key)
+	k.writeJSON(w, http.StatusCreated, map[string]interface{}{"kind": "Status", "status": "Success"})
+}
+
+func (k *mockKubernetes) writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (k *mockKubernetes) writeStatus(w http.ResponseWriter, code int, reason, message string) {
+	k.writeJSON(w, code, map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"message":    message,
+		"reason":     reason,
+		"code":       code,
+	})
+}
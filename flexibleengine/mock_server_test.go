@@ -0,0 +1,1106 @@
+package flexibleengine
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/chnsz/golangsdk/openstack/obs"
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+const (
+	mockRegion      = "eu-west-0"
+	mockProjectID   = "0123456789abcdef0123456789abcdef"
+	mockDomainID    = "fedcba9876543210fedcba9876543210"
+	mockDomainName  = "mock-domain"
+	mockAccessKey   = "MOCKACCESSKEY"
+	mockSecretKey   = "MOCKSECRETKEY"
+	mockUserName    = "mock-user"
+	mockPassword    = "mock-password"
+	mockImageID     = "b1f5d5a0-0c4b-4b6a-9a0e-4f1c5d0a0001"
+	mockImageName   = "OBS Ubuntu 20.04"
+	mockFlavorID    = "s3.large.2"
+	mockAZ          = "eu-west-0a"
+	mockTokenPrefix = "mock-token-"
+
+	// mockOtherRegion is another region of the mock domain whose project is mockOtherProjectID
+	mockOtherRegion    = "eu-west-1"
+	mockOtherProjectID = "00112233445566778899aabbccddeeff"
+
+	// mockOBSHost is the domain name of the OBS endpoint, the requests of the
+	// bucket domain names such as bucket.obs.eu-west-0.mock.test are sent to the mock server.
+	mockOBSHost = "obs." + mockRegion + ".mock.test"
+)
+
+// mockServices are the services served by the mock server, each of them is
+// reachable with a path prefix which is the key of the provider endpoints.
+var mockServices = []string{"iam", "ecs", "vpc", "evs", "ims", "bss", "cce"}
+
+// mockCollections maps the collections of the mock APIs to the keys of a
+// single object in the request and response bodies.
+var mockCollections = map[string]string{
+	"vpcs":                 "vpc",
+	"subnets":              "subnet",
+	"publicips":            "publicip",
+	"bandwidths":           "bandwidth",
+	"ports":                "port",
+	"networks":             "network",
+	"security-groups":      "security_group",
+	"security-group-rules": "security_group_rule",
+	"volumes":              "volume",
+	"cloudvolumes":         "volume",
+	"snapshots":            "snapshot",
+	"servers":              "server",
+	"cloudservers":         "server",
+	"images":               "image",
+	"jobs":                 "job",
+}
+
+// mockServer is an in-memory fake of the FlexibleEngine APIs which are used by the
+// core resources: IAM token issuing, ECS, VPC, subnet, EIP, EVS, security groups and OBS.
+// The resources become ready at once, so their CRUD functions can be tested without
+// any cloud account.
+type mockServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	counter int
+	tokens  map[string]bool
+	objects map[string]map[string]map[string]interface{}
+	tags    map[string][]interface{}
+	buckets map[string]map[string][]byte
+	// paths are the paths of all requests in order
+	paths []string
+}
+
+// newMockServer starts a mock server which is closed when the test finishes.
+func newMockServer(t *testing.T) *mockServer {
+	s := &mockServer{
+		tokens:  make(map[string]bool),
+		objects: make(map[string]map[string]map[string]interface{}),
+		tags:    make(map[string][]interface{}),
+		buckets: make(map[string]map[string][]byte),
+	}
+	s.objects["images"] = map[string]map[string]interface{}{
+		mockImageID: {
+			"id":         mockImageID,
+			"name":       mockImageName,
+			"status":     "active",
+			"visibility": "public",
+			"min_disk":   40,
+		},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// providerConfig returns the provider arguments which point to the mock server
+func (s *mockServer) providerConfig() map[string]interface{} {
+	endpoints := map[string]interface{}{
+		"obs": fmt.Sprintf("http://%s/", mockOBSHost),
+	}
+	for _, srv := range mockServices {
+		endpoints[srv] = fmt.Sprintf("%s/%s/", s.URL, srv)
+	}
+
+	return map[string]interface{}{
+		"region":      mockRegion,
+		"auth_url":    s.URL + "/iam/v3",
+		"domain_name": mockDomainName,
+		"access_key":  mockAccessKey,
+		"secret_key":  mockSecretKey,
+		"max_retries": 0,
+		"endpoints":   endpoints,
+	}
+}
+
+// configure returns the provider meta which sends all requests to the mock server
+func (s *mockServer) configure(t *testing.T, args map[string]interface{}) *Config {
+	raw := s.providerConfig()
+	for k, v := range args {
+		raw[k] = v
+	}
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(raw))
+	if diags.HasError() {
+		t.Fatalf("error configuring the provider with the mock server: %v", diags)
+	}
+
+	// the mock server finishes the jobs at once, so the status is polled without any delay
+	config := p.Meta().(*Config)
+	getConfigExtension(config).StateWaitInterval = 10 * time.Millisecond
+
+	// the OBS clients share the HTTP client of the domain
+	client := &config.DomainClient.HTTPClient
+	client.Transport = &mockOBSRoundTripper{Rt: client.Transport, Addr: s.Listener.Addr().String()}
+	return config
+}
+
+// mockOBSRoundTripper sends the requests of the OBS domain names to the mock server,
+// the OBS client always uses the bucket domain names unless the endpoint is an IP address,
+// and the provider can not use an IP address as the OBS endpoint.
+type mockOBSRoundTripper struct {
+	Rt   http.RoundTripper
+	Addr string
+}
+
+func (rt *mockOBSRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(request.URL.Hostname(), mockOBSHost) {
+		request = request.Clone(request.Context())
+		// the Host header is kept to find the bucket
+		request.URL.Host = rt.Addr
+	}
+	return rt.Rt.RoundTrip(request)
+}
+
+func (s *mockServer) newID(prefix string) string {
+	s.counter++
+	return fmt.Sprintf("%s-%04d-0000-0000-%012d", prefix, s.counter, s.counter)
+}
+
+func (s *mockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paths = append(s.paths, r.URL.Path)
+	if strings.HasSuffix(mockHostname(r), mockOBSHost) {
+		s.serveOBS(w, r)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	service := parts[0]
+	// remove the version and project ID from the path, the CCE paths are api/v3/projects/{project_id}
+	parts = parts[1:]
+	if len(parts) > 0 && parts[0] == "api" {
+		parts = parts[1:]
+	}
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "v") {
+		parts = parts[1:]
+	}
+	if len(parts) > 1 && parts[0] == "projects" && (parts[1] == mockProjectID || parts[1] == mockOtherProjectID) {
+		parts = parts[1:]
+	}
+	if len(parts) > 0 && (parts[0] == mockProjectID || parts[0] == mockOtherProjectID) {
+		parts = parts[1:]
+	}
+
+	var body map[string]interface{}
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid JSON body: %s", err)
+			return
+		}
+	}
+
+	if service == "iam" {
+		s.serveIAM(w, r, parts, body)
+		return
+	}
+
+	if !s.authorized(r) {
+		s.writeError(w, http.StatusUnauthorized, "the request is not authenticated")
+		return
+	}
+
+	switch {
+	case len(parts) == 0:
+		s.writeError(w, http.StatusNotFound, "the API %s is not supported", r.URL.Path)
+	case service == "bss":
+		s.serveBSS(w, r, parts, body)
+	case len(parts) >= 3 && parts[2] == "tags":
+		s.serveTags(w, r, parts, body)
+	case service == "ecs" && len(parts) == 3 && parts[0] == "cloudservers" && parts[2] == "autorecovery":
+		s.serveAutoRecovery(w, r, parts[1], body)
+	case service == "ecs" && len(parts) == 2 && parts[0] == "cloudservers" && parts[1] == "action":
+		s.cloudServersAction(w, body)
+	case service == "ecs" && len(parts) == 3 && parts[0] == "cloudservers" && parts[2] == "changeos":
+		s.changeServerOS(w, parts[1], body)
+	case service == "ecs" && len(parts) == 1 && parts[0] == "cloudservers" && r.Method == http.MethodPost:
+		s.createCloudServer(w, body)
+	case service == "ecs" && parts[0] == "cloudservers":
+		s.serveCloudServer(w, r, parts)
+	case (service == "ecs" || service == "ims") && len(parts) == 2 && parts[0] == "jobs":
+		// the jobs are finished at once
+		if job, ok := s.objects["jobs"][parts[1]]; ok {
+			s.writeJSON(w, http.StatusOK, job)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": parts[1], "status": "SUCCESS"})
+	case service == "cce" && len(parts) == 2 && parts[0] == "jobs":
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"kind": "Job", "metadata": map[string]interface{}{"uid": parts[1]},
+			"status": map[string]interface{}{"phase": "Success"},
+		})
+	case service == "ims" && len(parts) == 2 && parts[0] == "cloudimages" && parts[1] == "action":
+		s.createServerImage(w, body)
+	case service == "ims" && len(parts) == 1 && parts[0] == "cloudimages" && r.Method == http.MethodGet:
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"images": s.listObjects("images", r)})
+	case service == "ecs" && parts[0] == "servers":
+		s.serveServer(w, r, parts, body)
+	case service == "evs" && len(parts) == 3 && parts[0] == "volumes" && parts[2] == "action":
+		s.volumeAction(w, parts[1], body)
+	case service == "evs" && len(parts) == 1 && parts[0] == "snapshots" && r.Method == http.MethodPost:
+		s.createSnapshot(w, body)
+	case service == "evs" && len(parts) == 3 && parts[0] == "snapshots" && parts[2] == "metadata":
+		s.updateSnapshotMetadata(w, r, parts[1], body)
+	case service == "evs" && len(parts) == 3 && parts[0] == "os-vendor-snapshots" && parts[2] == "rollback":
+		s.rollbackSnapshot(w, parts[1], body)
+	case service == "cce" && parts[0] == "clusters":
+		s.serveCCECluster(w, r, parts, body)
+	case service == "vpc" && parts[0] == "publicips":
+		s.servePublicIP(w, r, parts, body)
+	case service == "vpc" && parts[0] == "bandwidths" && len(parts) == 2 && r.Method == http.MethodPut:
+		s.updateBandwidth(w, parts[1], body)
+	case service == "vpc" && parts[0] == "vpcs" && len(parts) == 4 && parts[2] == "subnets":
+		// the subnets are updated and deleted with the VPC ID
+		s.serveCollection(w, r, "subnets", parts[3], body)
+	case service == "vpc" && parts[0] == "security-groups" && r.Method == http.MethodPost:
+		s.createSecurityGroup(w, body)
+	case service == "vpc" && parts[0] == "security-groups" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.getSecurityGroup(w, parts[1])
+	case len(parts) <= 2 && mockCollections[parts[0]] != "":
+		var id string
+		if len(parts) == 2 {
+			id = parts[1]
+		}
+		s.serveCollection(w, r, parts[0], id, body)
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// authorized checks the token issued by the mock server or the AK/SK signature
+func (s *mockServer) authorized(r *http.Request) bool {
+	if s.tokens[r.Header.Get("X-Auth-Token")] {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Authorization"), "Access="+mockAccessKey)
+}
+
+func (s *mockServer) serveIAM(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	path := strings.Join(parts, "/")
+	if path == "auth/tokens" && r.Method == http.MethodPost {
+		s.issueToken(w, body)
+		return
+	}
+
+	if !s.authorized(r) {
+		s.writeError(w, http.StatusUnauthorized, "the request is not authenticated")
+		return
+	}
+
+	project := map[string]interface{}{
+		"id": mockProjectID, "name": mockRegion, "domain_id": mockDomainID, "enabled": true,
+	}
+	switch path {
+	case "projects", "auth/projects":
+		projects := []interface{}{}
+		if name := r.URL.Query().Get("name"); name == "" || name == mockRegion {
+			projects = append(projects, project)
+		}
+		if name := r.URL.Query().Get("name"); name == "" || name == mockOtherRegion {
+			projects = append(projects, map[string]interface{}{
+				"id": mockOtherProjectID, "name": mockOtherRegion, "domain_id": mockDomainID, "enabled": true,
+			})
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"projects": projects})
+	case "auth/domains", "domains":
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"domains": []interface{}{
+				map[string]interface{}{"id": mockDomainID, "name": mockDomainName, "enabled": true},
+			},
+		})
+	case "auth/catalog":
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"catalog": []interface{}{}})
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// issueToken issues a token for the mock user, the token is scoped to the project
+// unless the domain scope is requested.
+func (s *mockServer) issueToken(w http.ResponseWriter, body map[string]interface{}) {
+	auth, _ := body["auth"].(map[string]interface{})
+	var password string
+	if identity, ok := auth["identity"].(map[string]interface{}); ok {
+		if p, ok := identity["password"].(map[string]interface{}); ok {
+			if user, ok := p["user"].(map[string]interface{}); ok {
+				password, _ = user["password"].(string)
+			}
+		}
+	}
+	if password != mockPassword {
+		s.writeError(w, http.StatusUnauthorized, "the username or password is wrong")
+		return
+	}
+
+	domain := map[string]interface{}{"id": mockDomainID, "name": mockDomainName}
+	token := map[string]interface{}{
+		"methods":    []string{"password"},
+		"expires_at": time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339),
+		"user":       map[string]interface{}{"id": "mock-user-id", "name": mockUserName, "domain": domain},
+		"catalog":    []interface{}{},
+	}
+	if scope, ok := auth["scope"].(map[string]interface{}); ok && scope["project"] != nil {
+		token["project"] = map[string]interface{}{"id": mockProjectID, "name": mockRegion, "domain": domain}
+	} else {
+		token["domain"] = domain
+	}
+
+	s.counter++
+	tokenID := fmt.Sprintf("%s%d", mockTokenPrefix, s.counter)
+	s.tokens[tokenID] = true
+
+	w.Header().Set("X-Subject-Token", tokenID)
+	s.writeJSON(w, http.StatusCreated, map[string]interface{}{"token": token})
+}
+
+// serveCollection creates, lists, gets, updates and deletes the objects of a collection
+func (s *mockServer) serveCollection(w http.ResponseWriter, r *http.Request, collection, id string,
+	body map[string]interface{}) {
+	key := mockCollections[collection]
+	if s.objects[collection] == nil {
+		s.objects[collection] = make(map[string]map[string]interface{})
+	}
+	objects := s.objects[collection]
+
+	if id == "" {
+		switch r.Method {
+		case http.MethodPost:
+			object, _ := body[key].(map[string]interface{})
+			if object == nil {
+				s.writeError(w, http.StatusBadRequest, "%s must be specified", key)
+				return
+			}
+			object = s.createObject(collection, object)
+			s.writeJSON(w, mockCreatedCode(collection), map[string]interface{}{key: object})
+		case http.MethodGet:
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{
+				strings.ReplaceAll(collection, "-", "_"): s.listObjects(collection, r),
+			})
+		default:
+			s.writeError(w, http.StatusMethodNotAllowed, "%s is not allowed", r.Method)
+		}
+		return
+	}
+
+	object, ok := objects[id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "%s %s could not be found", key, id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if collection == "images" {
+			// the images are not wrapped in the response
+			s.writeJSON(w, http.StatusOK, object)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{key: object})
+		if collection == "volumes" {
+			s.finishVolumeRetype(object)
+		}
+	case http.MethodPut:
+		if changes, ok := body[key].(map[string]interface{}); ok {
+			for k, v := range changes {
+				object[k] = v
+			}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{key: object})
+	case http.MethodDelete:
+		delete(objects, id)
+		delete(s.tags, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "%s is not allowed", r.Method)
+	}
+}
+
+// createObject stores a new object with the ID and the status of a ready resource
+func (s *mockServer) createObject(collection string, object map[string]interface{}) map[string]interface{} {
+	if s.objects[collection] == nil {
+		s.objects[collection] = make(map[string]map[string]interface{})
+	}
+
+	id := s.newID(strings.TrimSuffix(mockCollections[collection], "s"))
+	object["id"] = id
+	object["tenant_id"] = mockProjectID
+	switch collection {
+	case "vpcs":
+		object["status"] = "OK"
+		object["routes"] = []interface{}{}
+	case "subnets":
+		object["status"] = "ACTIVE"
+		object["neutron_network_id"] = id
+		object["neutron_subnet_id"] = id
+	case "volumes", "cloudvolumes":
+		object["status"] = "available"
+		object["attachments"] = []interface{}{}
+		if _, ok := object["metadata"]; !ok {
+			object["metadata"] = map[string]interface{}{}
+		}
+	case "snapshots":
+		object["status"] = "available"
+		if _, ok := object["metadata"]; !ok {
+			object["metadata"] = map[string]interface{}{}
+		}
+	case "bandwidths", "ports", "networks":
+		object["status"] = "ACTIVE"
+	}
+
+	s.objects[collection][id] = object
+	return object
+}
+
+// listObjects returns the objects of a collection which match all query parameters
+func (s *mockServer) listObjects(collection string, r *http.Request) []interface{} {
+	query := r.URL.Query()
+	ids := make([]string, 0, len(s.objects[collection]))
+	for id := range s.objects[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		object := s.objects[collection][id]
+		matched := true
+		for k, v := range query {
+			if value, ok := object[k]; ok && fmt.Sprint(value) != v[0] {
+				matched = false
+			}
+		}
+		if matched {
+			result = append(result, s.render(collection, object))
+		}
+	}
+	return result
+}
+
+// render fills the fields which are derived from the other collections
+func (s *mockServer) render(collection string, object map[string]interface{}) map[string]interface{} {
+	if collection != "security-groups" {
+		return object
+	}
+
+	rules := []interface{}{}
+	for _, rule := range s.objects["security-group-rules"] {
+		if rule["security_group_id"] == object["id"] {
+			rules = append(rules, rule)
+		}
+	}
+	object["security_group_rules"] = rules
+	return object
+}
+
+func (s *mockServer) createSecurityGroup(w http.ResponseWriter, body map[string]interface{}) {
+	group, _ := body["security_group"].(map[string]interface{})
+	if group == nil {
+		s.writeError(w, http.StatusBadRequest, "security_group must be specified")
+		return
+	}
+	group = s.createObject("security-groups", group)
+
+	// the new security groups allow all outbound traffic
+	for _, ethertype := range []string{"IPv4", "IPv6"} {
+		s.createObject("security-group-rules", map[string]interface{}{
+			"security_group_id": group["id"],
+			"direction":         "egress",
+			"ethertype":         ethertype,
+		})
+	}
+	s.writeJSON(w, http.StatusCreated, map[string]interface{}{"security_group": s.render("security-groups", group)})
+}
+
+func (s *mockServer) getSecurityGroup(w http.ResponseWriter, id string) {
+	group, ok := s.objects["security-groups"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "security group %s could not be found", id)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"security_group": s.render("security-groups", group)})
+}
+
+// servePublicIP allocates the EIPs together with their bandwidths and binds them to the ports
+func (s *mockServer) servePublicIP(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	if len(parts) == 1 && r.Method == http.MethodPost {
+		eip, _ := body["publicip"].(map[string]interface{})
+		bandwidth, _ := body["bandwidth"].(map[string]interface{})
+		if eip == nil || bandwidth == nil {
+			s.writeError(w, http.StatusBadRequest, "publicip and bandwidth must be specified")
+			return
+		}
+
+		bandwidth = s.createObject("bandwidths", bandwidth)
+		eip = s.createObject("publicips", map[string]interface{}{
+			"type":                 eip["type"],
+			"status":               "DOWN",
+			"public_ip_address":    fmt.Sprintf("192.0.2.%d", s.counter%250+1),
+			"bandwidth_id":         bandwidth["id"],
+			"bandwidth_name":       bandwidth["name"],
+			"bandwidth_size":       bandwidth["size"],
+			"bandwidth_share_type": bandwidth["share_type"],
+			"ip_version":           4,
+		})
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"publicip": eip})
+		return
+	}
+
+	if len(parts) == 2 && r.Method == http.MethodPut {
+		eip, ok := s.objects["publicips"][parts[1]]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "publicip %s could not be found", parts[1])
+			return
+		}
+
+		changes, _ := body["publicip"].(map[string]interface{})
+		portID, _ := changes["port_id"].(string)
+		eip["port_id"] = portID
+		eip["status"] = "DOWN"
+		if portID != "" {
+			eip["status"] = "ACTIVE"
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"publicip": eip})
+		return
+	}
+
+	var id string
+	if len(parts) == 2 {
+		id = parts[1]
+	}
+	if id != "" && r.Method == http.MethodDelete {
+		if eip, ok := s.objects["publicips"][id]; ok {
+			delete(s.objects["bandwidths"], fmt.Sprint(eip["bandwidth_id"]))
+		}
+	}
+	s.serveCollection(w, r, "publicips", id, body)
+}
+
+// volumeAction handles the volume actions, they are completed at once
+func (s *mockServer) volumeAction(w http.ResponseWriter, id string, body map[string]interface{}) {
+	volume, ok := s.objects["volumes"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "volume %s could not be found", id)
+		return
+	}
+
+	switch {
+	case body["os-extend"] != nil:
+		opts, _ := body["os-extend"].(map[string]interface{})
+		volume["size"] = opts["new_size"]
+	case body["os-retype"] != nil:
+		opts, _ := body["os-retype"].(map[string]interface{})
+		if opts["migration_policy"] != "on-demand" {
+			s.writeError(w, http.StatusBadRequest, "the volume can not be migrated to %v", opts["new_type"])
+			return
+		}
+		// the volume is retyped when it is read, see finishVolumeRetype
+		volume["retype_to"] = opts["new_type"]
+		volume["retype_from_status"] = volume["status"]
+		volume["status"] = "retyping"
+	default:
+		s.writeError(w, http.StatusBadRequest, "the volume action is not supported")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// finishVolumeRetype finishes the retype of the volume after it is read in the retyping status,
+// the volume is put back to its status with the old type if retype_fails is set.
+func (s *mockServer) finishVolumeRetype(volume map[string]interface{}) {
+	if volume["status"] != "retyping" {
+		return
+	}
+	if volume["retype_fails"] != true {
+		volume["volume_type"] = volume["retype_to"]
+	}
+	volume["status"] = volume["retype_from_status"]
+	delete(volume, "retype_to")
+	delete(volume, "retype_from_status")
+}
+
+// createSnapshot creates the snapshot of a volume, the in-use volumes can only be snapshotted by force
+func (s *mockServer) createSnapshot(w http.ResponseWriter, body map[string]interface{}) {
+	opts, _ := body["snapshot"].(map[string]interface{})
+	volume, ok := s.objects["volumes"][fmt.Sprint(opts["volume_id"])]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "volume %v could not be found", opts["volume_id"])
+		return
+	}
+	if volume["status"] != "available" && opts["force"] != true {
+		s.writeError(w, http.StatusBadRequest, "volume %v is %v, force must be specified", volume["id"], volume["status"])
+		return
+	}
+
+	opts["size"] = volume["size"]
+	snapshot := s.createObject("snapshots", opts)
+	s.writeJSON(w, http.StatusAccepted, map[string]interface{}{"snapshot": snapshot})
+}
+
+func (s *mockServer) updateSnapshotMetadata(w http.ResponseWriter, r *http.Request, id string,
+	body map[string]interface{}) {
+	snapshot, ok := s.objects["snapshots"][id]
+	if !ok || r.Method != http.MethodPut {
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+		return
+	}
+
+	// the metadata is replaced
+	metadata, _ := body["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	snapshot["metadata"] = metadata
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"metadata": metadata})
+}
+
+// rollbackSnapshot rolls back the volume from its snapshot, the volume must be detached
+func (s *mockServer) rollbackSnapshot(w http.ResponseWriter, id string, body map[string]interface{}) {
+	snapshot, ok := s.objects["snapshots"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "snapshot %s could not be found", id)
+		return
+	}
+	opts, _ := body["rollback"].(map[string]interface{})
+	if opts["volume_id"] != snapshot["volume_id"] {
+		s.writeError(w, http.StatusBadRequest, "snapshot %s does not belong to volume %v", id, opts["volume_id"])
+		return
+	}
+	volume := s.objects["volumes"][fmt.Sprint(opts["volume_id"])]
+	if volume["status"] != "available" {
+		s.writeError(w, http.StatusBadRequest, "volume %v is %v", volume["id"], volume["status"])
+		return
+	}
+
+	volume["__snapshot_id"] = id
+	if name, ok := opts["name"]; ok {
+		volume["name"] = name
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]interface{}{"rollback": map[string]interface{}{"volume_id": volume["id"]}})
+}
+
+// prePaidCollections are the collections of the resources which can be changed to prepaid
+var prePaidCollections = []string{"servers", "volumes", "publicips"}
+
+// serveBSS handles the orders which change the resources to prepaid and unsubscribe them,
+// the orders are completed at once.
+func (s *mockServer) serveBSS(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	path := strings.Join(parts, "/")
+	switch {
+	case path == "orders/subscriptions/resources/to-period" && r.Method == http.MethodPost:
+		ids, _ := body["resource_ids"].([]interface{})
+		order := s.createObject("orders", map[string]interface{}{"status": 5, "resource_ids": ids})
+		for _, id := range ids {
+			object, collection := s.prePaidObject(id.(string))
+			if object == nil {
+				s.writeError(w, http.StatusBadRequest, "resource %s could not be found", id)
+				return
+			}
+			object["charging_mode"] = "1"
+			object["period_type"] = body["period_type"]
+			object["period_num"] = body["period_num"]
+
+			// the order is returned in the metadata of the volumes and the billing info of the EIPs
+			switch collection {
+			case "volumes":
+				object["metadata"].(map[string]interface{})["orderID"] = order["id"]
+			case "publicips":
+				object["billing_info"] = fmt.Sprintf("%s:product:region:mock", order["id"])
+			}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"order_ids": []interface{}{order["id"]}})
+	case path == "orders/suscriptions/resources/query" && r.Method == http.MethodPost:
+		ids, _ := body["resource_ids"].([]interface{})
+		if orderID, ok := body["order_id"].(string); ok {
+			ids, _ = s.objects["orders"][orderID]["resource_ids"].([]interface{})
+		}
+		data := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			object, _ := s.prePaidObject(id.(string))
+			if object == nil || object["charging_mode"] != "1" {
+				continue
+			}
+
+			// the resources are subscribed at the beginning of the year
+			effective := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			num := int(object["period_num"].(float64))
+			expire := effective.AddDate(0, num, 0)
+			if object["period_type"] == float64(3) {
+				expire = effective.AddDate(num, 0, 0)
+			}
+			policy := 0
+			if object["auto_renew"] == true {
+				policy = 3
+			}
+			data = append(data, map[string]interface{}{
+				"resource_id":    id,
+				"effective_time": effective.Format(time.RFC3339),
+				"expire_time":    expire.Format(time.RFC3339),
+				"expire_policy":  policy,
+			})
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"data": data, "total_count": len(data)})
+	case len(parts) == 4 && path == "orders/customer-orders/details/"+parts[3] && r.Method == http.MethodGet:
+		order, ok := s.objects["orders"][parts[3]]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "order %s could not be found", parts[3])
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"order_info": order})
+	case path == "orders/subscriptions/resources/unsubscribe" && r.Method == http.MethodPost:
+		ids, _ := body["resource_ids"].([]interface{})
+		for _, id := range ids {
+			object, collection := s.prePaidObject(id.(string))
+			if object == nil || object["charging_mode"] != "1" {
+				s.writeError(w, http.StatusBadRequest, "resource %s is not a prePaid resource", id)
+				return
+			}
+
+			// the resources are deleted together with the ones belonging to them
+			switch collection {
+			case "servers":
+				for _, port := range object["ports"].([]string) {
+					delete(s.objects["ports"], port)
+				}
+				for _, volume := range object["volumes"].([]string) {
+					delete(s.objects["volumes"], volume)
+				}
+			case "publicips":
+				delete(s.objects["bandwidths"], fmt.Sprint(object["bandwidth_id"]))
+			}
+			delete(s.objects[collection], id.(string))
+		}
+		order := s.createObject("orders", map[string]interface{}{"status": 5, "resource_ids": ids})
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"order_ids": []interface{}{order["id"]}})
+	case len(parts) == 5 && path == "orders/subscriptions/resources/autorenew/"+parts[4]:
+		object, _ := s.prePaidObject(parts[4])
+		if object == nil || object["charging_mode"] != "1" {
+			s.writeError(w, http.StatusBadRequest, "resource %s is not a prePaid resource", parts[4])
+			return
+		}
+		object["auto_renew"] = r.Method == http.MethodPost
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// prePaidObject returns the object which can be changed to prepaid and its collection
+func (s *mockServer) prePaidObject(id string) (map[string]interface{}, string) {
+	for _, collection := range prePaidCollections {
+		if object, ok := s.objects[collection][id]; ok {
+			return object, collection
+		}
+	}
+	return nil, ""
+}
+
+// updateBandwidth updates the bandwidth and the EIPs which use it
+func (s *mockServer) updateBandwidth(w http.ResponseWriter, id string, body map[string]interface{}) {
+	bandwidth, ok := s.objects["bandwidths"][id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "bandwidth %s could not be found", id)
+		return
+	}
+
+	changes, _ := body["bandwidth"].(map[string]interface{})
+	for k, v := range changes {
+		bandwidth[k] = v
+	}
+	for _, eip := range s.objects["publicips"] {
+		if eip["bandwidth_id"] == id {
+			eip["bandwidth_name"] = bandwidth["name"]
+			eip["bandwidth_size"] = bandwidth["size"]
+		}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"bandwidth": bandwidth})
+}
+
+// serveTags handles the tags of the VPC, EIP, EVS and ECS resources
+func (s *mockServer) serveTags(w http.ResponseWriter, r *http.Request, parts []string, body map[string]interface{}) {
+	id := parts[1]
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		tags := s.tags[id]
+		if tags == nil {
+			tags = []interface{}{}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		s.updateTags(id, "create", body["tag"])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[3] == "action" && r.Method == http.MethodPost:
+		tags, _ := body["tags"].([]interface{})
+		action, _ := body["action"].(string)
+		for _, tag := range tags {
+			s.updateTags(id, action, tag)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && r.Method == http.MethodDelete:
+		s.updateTags(id, "delete", map[string]interface{}{"key": parts[3]})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusNotFound, "the API %s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+func (s *mockServer) updateTags(id, action string, raw interface{}) {
+	tag, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	tags := []interface{}{}
+	for _, t := range s.tags[id] {
+		if t.(map[string]interface{})["key"] != tag["key"] {
+			tags = append(tags, t)
+		}
+	}
+	if action == "create" {
+		tags = append(tags, map[string]interface{}{"key": tag["key"], "value": tag["value"]})
+	}
+	s.tags[id] = tags
+}
+
+// serveOBS handles the requests of the buckets and objects, the bucket is specified
+// by the domain name or the first part of the path.
+func (s *mockServer) serveOBS(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), mockAccessKey) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if host := mockHostname(r); strings.HasSuffix(host, "."+mockOBSHost) {
+		bucket := strings.TrimSuffix(host, "."+mockOBSHost)
+		path = bucket + "/" + path
+	}
+	parts := strings.SplitN(path, "/", 2)
+	bucket := parts[0]
+	objects, exists := s.buckets[bucket]
+
+	if bucket == "" {
+		type bucketXML struct {
+			Name string `xml:"Name"`
+		}
+		result := struct {
+			XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+			Buckets []bucketXML `xml:"Buckets>Bucket"`
+		}{}
+		for name := range s.buckets {
+			result.Buckets = append(result.Buckets, bucketXML{Name: name})
+		}
+		s.writeXML(w, http.StatusOK, result)
+		return
+	}
+
+	if len(parts) == 1 || parts[1] == "" {
+		switch {
+		case r.Method == http.MethodPut:
+			if !exists {
+				s.buckets[bucket] = make(map[string][]byte)
+			}
+			w.WriteHeader(http.StatusOK)
+		case !exists:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete && len(objects) > 0:
+			w.WriteHeader(http.StatusConflict)
+		case r.Method == http.MethodDelete:
+			delete(s.buckets, bucket)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet:
+			type objectXML struct {
+				Key  string `xml:"Key"`
+				Size int    `xml:"Size"`
+			}
+			result := struct {
+				XMLName  xml.Name    `xml:"ListBucketResult"`
+				Name     string      `xml:"Name"`
+				Contents []objectXML `xml:"Contents"`
+			}{Name: bucket}
+			keys := make([]string, 0, len(objects))
+			for key := range objects {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				result.Contents = append(result.Contents, objectXML{Key: key, Size: len(objects[key])})
+			}
+			s.writeXML(w, http.StatusOK, result)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	key := parts[1]
+	data, found := objects[key]
+	switch {
+	case r.Method == http.MethodPut:
+		content, _ := io.ReadAll(r.Body)
+		objects[key] = content
+		w.Header().Set("ETag", fmt.Sprintf("\"%x\"", len(content)))
+		w.WriteHeader(http.StatusOK)
+	case !found:
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("ETag", fmt.Sprintf("\"%x\"", len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// mockHostname returns the host of the request without the port
+func mockHostname(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
+
+func mockCreatedCode(collection string) int {
+	switch collection {
+	case "volumes", "cloudvolumes", "servers":
+		return http.StatusAccepted
+	case "vpcs", "subnets", "publicips":
+		return http.StatusOK
+	}
+	return http.StatusCreated
+}
+
+func (s *mockServer) writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (s *mockServer) writeXML(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_ = xml.NewEncoder(w).Encode(body)
+}
+
+func (s *mockServer) writeError(w http.ResponseWriter, code int, format string, a ...interface{}) {
+	s.writeJSON(w, code, map[string]interface{}{
+		"error_code": fmt.Sprintf("MOCK.%d", code),
+		"error_msg":  fmt.Sprintf(format, a...),
+	})
+}
+
+func TestMockVpcV1(t *testing.T) {
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_vpc_v1",
+		steps: []mockResourceStep{
+			{
+				config: map[string]interface{}{
+					"name": "vpc_1",
+					"cidr": "192.168.0.0/16",
+					"tags": map[string]interface{}{"owner": "terraform"},
+				},
+				attrs: map[string]string{"status": "OK", "tags.owner": "terraform"},
+			},
+			{
+				config: map[string]interface{}{
+					"name":        "vpc_2",
+					"cidr":        "192.168.0.0/16",
+					"description": "updated by terraform",
+					"tags":        map[string]interface{}{"owner": "terraform"},
+				},
+				attrs: map[string]string{"name": "vpc_2", "description": "updated by terraform"},
+			},
+		},
+		importAttrs: []string{"name", "cidr"},
+		collections: []string{"vpcs"},
+	})
+}
+
+func TestMockServer_authentication(t *testing.T) {
+	server := newMockServer(t)
+
+	// the clients are created by the token issued with the password
+	config := server.configure(t, map[string]interface{}{
+		"access_key": "",
+		"secret_key": "",
+		"user_name":  mockUserName,
+		"password":   mockPassword,
+	})
+	th.AssertEquals(t, mockProjectID, config.HwClient.ProjectID)
+	th.AssertEquals(t, mockDomainID, config.DomainID)
+
+	client, err := config.NetworkingV1Client(mockRegion)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, server.URL+"/vpc/v1/", client.ResourceBaseURL())
+
+	p := Provider()
+	raw := server.providerConfig()
+	raw["access_key"], raw["secret_key"] = "", ""
+	raw["user_name"], raw["password"] = mockUserName, "wrong-password"
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(raw))
+	th.AssertEquals(t, true, diags.HasError())
+}
+
+func TestMockServer_obs(t *testing.T) {
+	server := newMockServer(t)
+	config := server.configure(t, nil)
+	client, err := objectStorageClient(config, mockRegion)
+	th.AssertNoErr(t, err)
+
+	_, err = client.CreateBucket(&obs.CreateBucketInput{Bucket: "bucket-1"})
+	th.AssertNoErr(t, err)
+
+	input := &obs.PutObjectInput{}
+	input.Bucket, input.Key = "bucket-1", "dir/object.txt"
+	input.Body = strings.NewReader("hello")
+	_, err = client.PutObject(input)
+	th.AssertNoErr(t, err)
+
+	output, err := client.GetObject(&obs.GetObjectInput{
+		GetObjectMetadataInput: obs.GetObjectMetadataInput{Bucket: "bucket-1", Key: "dir/object.txt"},
+	})
+	th.AssertNoErr(t, err)
+	defer output.Body.Close()
+	data, err := io.ReadAll(output.Body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "hello", string(data))
+
+	objects, err := client.ListObjects(&obs.ListObjectsInput{Bucket: "bucket-1"})
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 1, len(objects.Contents))
+
+	// the bucket can not be deleted until it is empty
+	_, err = client.DeleteBucket("bucket-1")
+	th.AssertEquals(t, true, err != nil)
+}
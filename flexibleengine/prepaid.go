@@ -0,0 +1,249 @@
+package flexibleengine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/common"
+)
+
+const (
+	chargingModePrePaid  = "prePaid"
+	chargingModePostPaid = "postPaid"
+)
+
+// prePaidPeriodTypes maps period_unit to the period types of the BSS orders
+var prePaidPeriodTypes = map[string]int{
+	"month": 2,
+	"year":  3,
+}
+
+// schemaChargingMode returns the schema of charging_mode, the pay-per-use resource
+// can be changed to prepaid in place.
+func schemaChargingMode() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ValidateFunc: validation.StringInSlice([]string{
+			chargingModePrePaid, chargingModePostPaid,
+		}, false),
+	}
+}
+
+func schemaPeriodUnit() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		RequiredWith: []string{"period"},
+		ValidateFunc: validation.StringInSlice([]string{
+			"month", "year",
+		}, false),
+	}
+}
+
+func schemaPeriod() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		RequiredWith: []string{"period_unit"},
+		ValidateFunc: validation.IntBetween(1, 9),
+	}
+}
+
+func schemaAutoRenew() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		ValidateFunc: validation.StringInSlice([]string{
+			"true", "false",
+		}, false),
+	}
+}
+
+func isPrePaid(d *schema.ResourceData) bool {
+	return d.Get("charging_mode").(string) == chargingModePrePaid
+}
+
+// customizeChargingModeDiff checks the period of the prepaid resource, and forbids changing
+// the prepaid resource back to pay-per-use or changing its period, which is only used by the order.
+func customizeChargingModeDiff(d *schema.ResourceDiff) error {
+	oldMode, newMode := d.GetChange("charging_mode")
+	if newMode.(string) != chargingModePrePaid {
+		if d.Id() != "" && oldMode.(string) == chargingModePrePaid && newMode.(string) == chargingModePostPaid {
+			return fmt.Errorf("the prePaid resource can not be changed to postPaid")
+		}
+		return nil
+	}
+
+	if (d.Id() == "" || d.HasChange("charging_mode")) && d.Get("period_unit").(string) == "" {
+		return fmt.Errorf("both of `period` and `period_unit` must be specified in prePaid charging mode")
+	}
+
+	// the period of the subscribed resource is not known until it is read
+	if oldUnit, _ := d.GetChange("period_unit"); d.Id() != "" && oldMode.(string) == chargingModePrePaid &&
+		oldUnit.(string) != "" && d.HasChanges("period_unit", "period") {
+		return fmt.Errorf("`period_unit` and `period` of the prePaid resource can not be changed, " +
+			"renew the subscription instead")
+	}
+	return nil
+}
+
+// changeToPrePaid changes the pay-per-use resources to prepaid with period_unit and period,
+// and waits for the orders to complete.
+func changeToPrePaid(d *schema.ResourceData, config *Config, resourceIDs []string, timeout time.Duration) error {
+	bssClient, err := config.BssV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine BSS client: %s", err)
+	}
+
+	changeOpts := map[string]interface{}{
+		"resource_ids": resourceIDs,
+		"period_type":  prePaidPeriodTypes[d.Get("period_unit").(string)],
+		"period_num":   d.Get("period").(int),
+	}
+	log.Printf("[DEBUG] Changing %v to prePaid: %#v", resourceIDs, changeOpts)
+
+	r := golangsdk.Result{}
+	_, r.Err = bssClient.Post(bssClient.ServiceURL("orders", "subscriptions", "resources", "to-period"),
+		changeOpts, &r.Body, &golangsdk.RequestOpts{OkCodes: []int{200}})
+	if r.Err != nil {
+		return fmt.Errorf("Error changing %v to prePaid: %s", resourceIDs, r.Err)
+	}
+
+	orderIDs, err := navigateValue(r.Body, []string{"order_ids"}, nil)
+	if err != nil {
+		return fmt.Errorf("Error changing %v to prePaid: the order ID is not found", resourceIDs)
+	}
+	for _, orderID := range orderIDs.([]interface{}) {
+		if err := common.WaitOrderComplete(context.Background(), bssClient, orderID.(string), timeout); err != nil {
+			return err
+		}
+	}
+
+	if d.Get("auto_renew").(string) == "true" {
+		return updatePrePaidAutoRenew(d, config, resourceIDs)
+	}
+	return nil
+}
+
+// updatePrePaidAutoRenew enables or disables the auto renewal of the prepaid resources
+func updatePrePaidAutoRenew(d *schema.ResourceData, config *Config, resourceIDs []string) error {
+	bssClient, err := config.BssV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine BSS client: %s", err)
+	}
+
+	enabled := d.Get("auto_renew").(string)
+	if enabled == "" {
+		enabled = "false"
+	}
+	for _, id := range resourceIDs {
+		if err := common.UpdateAutoRenew(bssClient, enabled, id); err != nil {
+			return fmt.Errorf("Error updating the auto renewal of %s: %s", id, err)
+		}
+	}
+	return nil
+}
+
+// updateChargingMode changes the pay-per-use resource to prepaid, or updates the auto renewal
+// of the prepaid resource.
+func updateChargingMode(d *schema.ResourceData, config *Config, resourceIDs []string) error {
+	if d.HasChange("charging_mode") && isPrePaid(d) {
+		return changeToPrePaid(d, config, resourceIDs, d.Timeout(schema.TimeoutUpdate))
+	}
+	if d.HasChange("auto_renew") && isPrePaid(d) {
+		return updatePrePaidAutoRenew(d, config, resourceIDs)
+	}
+	return nil
+}
+
+// setPrePaidInfo sets auto_renew of the prepaid resource from its subscription. The period of
+// the renewed resource is longer than the one subscribed, so period_unit and period are only
+// set when they are not known, e.g. the resource is imported.
+func setPrePaidInfo(d *schema.ResourceData, config *Config, resourceID string) error {
+	bssClient, err := config.BssV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine BSS client: %s", err)
+	}
+
+	queryOpts := map[string]interface{}{
+		"resource_ids":       []string{resourceID},
+		"only_main_resource": 1,
+	}
+	var rst struct {
+		Data []struct {
+			ResourceID    string `json:"resource_id"`
+			EffectiveTime string `json:"effective_time"`
+			ExpireTime    string `json:"expire_time"`
+			ExpirePolicy  int    `json:"expire_policy"`
+		} `json:"data"`
+	}
+	_, err = bssClient.Post(bssClient.ServiceURL("orders", "suscriptions", "resources", "query"),
+		queryOpts, &rst, &golangsdk.RequestOpts{OkCodes: []int{200}})
+	if err != nil {
+		return fmt.Errorf("Error querying the subscription of %s: %s", resourceID, err)
+	}
+
+	for _, v := range rst.Data {
+		if v.ResourceID != resourceID {
+			continue
+		}
+
+		// the resource is renewed automatically when it expires with policy 3
+		if v.ExpirePolicy == 3 {
+			d.Set("auto_renew", "true")
+		} else if d.Get("auto_renew").(string) != "" {
+			d.Set("auto_renew", "false")
+		}
+
+		if d.Get("period_unit").(string) == "" {
+			unit, period, err := parsePrePaidPeriod(v.EffectiveTime, v.ExpireTime)
+			if err != nil {
+				log.Printf("[WARN] Error parsing the period of %s: %s", resourceID, err)
+				return nil
+			}
+			d.Set("period_unit", unit)
+			d.Set("period", period)
+		}
+		return nil
+	}
+	log.Printf("[WARN] The subscription of %s is not found", resourceID)
+	return nil
+}
+
+// parsePrePaidPeriod returns period_unit and period between the effective and the expire time
+func parsePrePaidPeriod(effectiveTime, expireTime string) (string, int, error) {
+	effective, err := time.Parse(time.RFC3339, effectiveTime)
+	if err != nil {
+		return "", 0, err
+	}
+	expire, err := time.Parse(time.RFC3339, expireTime)
+	if err != nil {
+		return "", 0, err
+	}
+
+	months := (expire.Year()-effective.Year())*12 + int(expire.Month()-effective.Month())
+	if months <= 0 {
+		return "", 0, fmt.Errorf("the expire time %s is not later than the effective time %s",
+			expireTime, effectiveTime)
+	}
+	if months%12 == 0 {
+		return "year", months / 12, nil
+	}
+	return "month", months, nil
+}
+
+// unsubscribePrePaid unsubscribes the prepaid resources, which are deleted by the orders
+func unsubscribePrePaid(d *schema.ResourceData, config *Config, resourceIDs []string) error {
+	log.Printf("[DEBUG] Unsubscribing prePaid resources %v", resourceIDs)
+	if err := common.UnsubscribePrePaidResource(d, config, resourceIDs); err != nil {
+		return fmt.Errorf("Error unsubscribing prePaid resources %v: %s", resourceIDs, err)
+	}
+	return nil
+}
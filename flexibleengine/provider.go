@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud"
 	"github.com/huaweicloud/terraform-provider-huaweicloud/huaweicloud/config"
@@ -73,7 +76,7 @@ func Provider() *schema.Provider {
 		Schema: map[string]*schema.Schema{
 			"region": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				Description: descriptions["region"],
 				DefaultFunc: schema.EnvDefaultFunc("OS_REGION_NAME", nil),
 			},
@@ -175,6 +178,49 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("OS_SECURITY_TOKEN", nil),
 			},
 
+			"credential_process": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   descriptions["credential_process"],
+				DefaultFunc:   schema.EnvDefaultFunc("OS_CREDENTIAL_PROCESS", ""),
+				ConflictsWith: []string{"credential_file"},
+			},
+
+			"credential_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["credential_file"],
+				DefaultFunc: schema.EnvDefaultFunc("OS_CREDENTIAL_FILE", ""),
+			},
+
+			"assume_role": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: descriptions["assume_role"],
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"agency_name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: descriptions["assume_role_agency_name"],
+						},
+						"domain_name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: descriptions["assume_role_domain_name"],
+						},
+						"duration": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      defaultAssumeRoleDuration,
+							Description:  descriptions["assume_role_duration"],
+							ValidateFunc: validation.IntBetween(900, 86400),
+						},
+					},
+				},
+			},
+
 			"auth_url": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -189,6 +235,20 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("OS_CLOUD", defaultCloud),
 			},
 
+			"shared_config_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["shared_config_file"],
+				DefaultFunc: schema.EnvDefaultFunc("OS_SHARED_CONFIG_FILE", ""),
+			},
+
+			"profile": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["profile"],
+				DefaultFunc: schema.EnvDefaultFunc("OS_PROFILE", ""),
+			},
+
 			"endpoints": {
 				Type:        schema.TypeMap,
 				Optional:    true,
@@ -196,6 +256,22 @@ func Provider() *schema.Provider {
 				Elem:        &schema.Schema{Type: schema.TypeString},
 			},
 
+			"endpoint_template": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["endpoint_template"],
+				DefaultFunc: schema.EnvDefaultFunc("OS_ENDPOINT_TEMPLATE", ""),
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`\{service\}`),
+					"the endpoint template must contain the {service} placeholder"),
+			},
+
+			"skip_catalog": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: descriptions["skip_catalog"],
+				DefaultFunc: schema.EnvDefaultFunc("OS_SKIP_CATALOG", false),
+			},
+
 			"insecure": {
 				Type:        schema.TypeBool,
 				Optional:    true,
@@ -210,6 +286,89 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("OS_MAX_RETRIES", 5),
 			},
 
+			"retry_policy": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: descriptions["retry_policy"],
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"base_delay": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
+						"max_delay": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      60,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
+						"jitter": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"max_duration": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      600,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
+					},
+				},
+			},
+
+			"rate_limit": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Description:  descriptions["rate_limit"],
+				ValidateFunc: validation.IntAtLeast(0),
+				DefaultFunc:  schema.EnvDefaultFunc("OS_RATE_LIMIT", 0),
+			},
+
+			"rate_limits": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: descriptions["rate_limits"],
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+			},
+
+			"http_log": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: descriptions["http_log"],
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"services": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"include_bodies": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+						"format": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  httpLogFormatText,
+							ValidateFunc: validation.StringInSlice([]string{
+								httpLogFormatText, httpLogFormatJSON,
+							}, false),
+						},
+						"redact_keys": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+
 			"cacert_file": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -235,8 +394,10 @@ func Provider() *schema.Provider {
 		DataSourcesMap: map[string]*schema.Resource{
 			"flexibleengine_availability_zones":        dataSourceAvailabilityZones(),
 			"flexibleengine_blockstorage_volume_v2":    dataSourceBlockStorageVolumeV2(),
+			"flexibleengine_blockstorage_snapshots":    dataSourceBlockStorageSnapshots(),
 			"flexibleengine_compute_instance_v2":       dataSourceComputeInstance(),
 			"flexibleengine_compute_instances":         dataSourceComputeInstances(),
+			"flexibleengine_compute_instance_console":  dataSourceComputeInstanceConsole(),
 			"flexibleengine_compute_flavors_v2":        dataSourceEcsFlavors(),
 			"flexibleengine_networking_secgroup_v2":    dataSourceNetworkingSecGroupV2(),
 			"flexibleengine_s3_bucket_object":          dataSourceS3BucketObject(),
@@ -267,6 +428,7 @@ func Provider() *schema.Provider {
 			"flexibleengine_cce_node_v3":               dataSourceCceNodesV3(),
 			"flexibleengine_cce_node_ids_v3":           dataSourceCceNodeIdsV3(),
 			"flexibleengine_cce_cluster_v3":            dataSourceCCEClusterV3(),
+			"flexibleengine_cce_cluster_kubeconfig":    dataSourceCCEClusterKubeConfig(),
 			"flexibleengine_cce_addon_template":        dataSourceCCEAddonTemplate(),
 			"flexibleengine_dns_zone_v2":               dataSourceDNSZoneV2(),
 			"flexibleengine_dds_flavors_v3":            dataSourceDDSFlavorsV3(),
@@ -380,7 +542,10 @@ func Provider() *schema.Provider {
 
 		ResourcesMap: map[string]*schema.Resource{
 			"flexibleengine_blockstorage_volume_v2":             resourceBlockStorageVolumeV2(),
+			"flexibleengine_blockstorage_snapshot":              resourceBlockStorageSnapshot(),
+			"flexibleengine_blockstorage_snapshot_rollback":     resourceBlockStorageSnapshotRollback(),
 			"flexibleengine_compute_instance_v2":                resourceComputeInstanceV2(),
+			"flexibleengine_compute_instance_snapshot":          resourceComputeInstanceSnapshot(),
 			"flexibleengine_compute_interface_attach_v2":        resourceComputeInterfaceAttachV2(),
 			"flexibleengine_compute_keypair_v2":                 resourceComputeKeypairV2(),
 			"flexibleengine_compute_servergroup_v2":             resourceComputeServerGroupV2(),
@@ -467,6 +632,7 @@ func Provider() *schema.Provider {
 			"flexibleengine_cce_node_v3":                        resourceCCENodeV3(),
 			"flexibleengine_cce_node_pool_v3":                   resourceCCENodePool(),
 			"flexibleengine_cce_addon_v3":                       resourceCCEAddon(),
+			"flexibleengine_cce_manifest":                       resourceCCEManifest(),
 			"flexibleengine_dds_instance_v3":                    resourceDdsInstanceV3(),
 			"flexibleengine_sdrs_drill_v1":                      resourceSdrsDrillV1(),
 			"flexibleengine_sdrs_protectiongroup_v1":            resourceSdrsProtectiongroupV1(),
@@ -657,6 +823,14 @@ func Provider() *schema.Provider {
 		ConfigureContextFunc: configureProvider,
 	}
 
+	// every regional resource can be managed in another project with project_id
+	for _, r := range provider.ResourcesMap {
+		projectScopedResource(r)
+	}
+	for _, r := range provider.DataSourcesMap {
+		regionScopedDataSource(r)
+	}
+
 	return provider
 }
 
@@ -688,14 +862,43 @@ func init() {
 
 		"security_token": "The security token to authenticate with a temporary security credential.",
 
+		"credential_process": "The command which prints the temporary credentials in JSON format, " +
+			"it will be run again to refresh the credentials before they expire.",
+
+		"credential_file": "The file which contains the temporary credentials in JSON format, " +
+			"it will be read again to refresh the credentials before they expire.",
+
+		"assume_role": "The agency which is assumed to manage the resources of the delegated domain.",
+
+		"assume_role_agency_name": "The name of the agency to assume.",
+
+		"assume_role_domain_name": "The name of the domain which created the agency.",
+
+		"assume_role_duration": "The validity period in seconds of the temporary credentials of the agency.",
+
 		"domain_id": "The ID of the Domain to scope to (Identity v3).",
 
 		"domain_name": "The name of the Domain to scope to (Identity v3).",
 
+		"endpoint_template": "The template of the service endpoints which are not customized, " +
+			"e.g. https://{service}.{region}.example.internal/",
+
+		"skip_catalog": "Whether to build all service clients without the service catalog of IAM.",
+
 		"insecure": "Trust self-signed certificates.",
 
 		"max_retries": "How many times HTTP connection should be retried until giving up.",
 
+		"retry_policy": "The backoff policy of the throttled, failed or reset HTTP requests.",
+
+		"rate_limit": "The maximum number of HTTP requests per second sent to each service, 0 means unlimited.",
+
+		"rate_limits": "The maximum number of HTTP requests per second sent to the specified services, " +
+			"the key is the service catalog name, e.g. ecs, vpc.",
+
+		"http_log": "The structured HTTP debug logs of the selected services, " +
+			"the sensitive values in the bodies are redacted.",
+
 		"cacert_file": "A Custom CA certificate.",
 
 		"cert": "A client certificate to authenticate with.",
@@ -703,29 +906,31 @@ func init() {
 		"key": "A client private key to authenticate with.",
 
 		"cloud": "The endpoint of cloud provider, defaults to prod-cloud-ocb.orange-business.com",
+
+		"shared_config_file": "The path of the shared config file which contains the named profiles, " +
+			"defaults to ~/.flexibleengine/config when profile is specified.",
+
+		"profile": "The profile name in the shared config file.",
 	}
 }
 
-func configureProvider(_ context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+func configureProvider(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	return configureProviderWithRecorder(ctx, d, nil)
+}
+
+// configureProviderWithRecorder configures the provider whose requests are recorded to or replayed
+// from the cassette of recorder, the requests are sent as they are if recorder is nil.
+func configureProviderWithRecorder(_ context.Context, d *schema.ResourceData,
+	recorder *recorderOptions) (interface{}, diag.Diagnostics) {
 	config := Config{}
+	ext := getConfigExtension(&config)
+	ext.Recorder = recorder
 
-	region := d.Get("region").(string)
 	cloud := d.Get("cloud").(string)
-	config.Region = region
-
+	config.Region = d.Get("region").(string)
 	config.TenantID = d.Get("tenant_id").(string)
 	config.TenantName = d.Get("tenant_name").(string)
-	// set tenant_name to region when neither `tenant_name` nor `tenant_id` was specified
-	if config.TenantID == "" && config.TenantName == "" {
-		config.TenantName = region
-	}
-
-	if v, ok := d.GetOk("auth_url"); ok {
-		config.IdentityEndpoint = v.(string)
-	} else {
-		config.IdentityEndpoint = fmt.Sprintf("https://iam.%s.%s/v3", mainRegion, cloud)
-	}
-
+	config.IdentityEndpoint = d.Get("auth_url").(string)
 	config.DomainID = d.Get("domain_id").(string)
 	config.DomainName = d.Get("domain_name").(string)
 	config.UserID = d.Get("user_id").(string)
@@ -736,6 +941,44 @@ func configureProvider(_ context.Context, d *schema.ResourceData) (interface{},
 	config.SecurityToken = d.Get("security_token").(string)
 	config.Token = d.Get("token").(string)
 
+	ext.CredentialProcess = d.Get("credential_process").(string)
+	ext.CredentialFile = d.Get("credential_file").(string)
+
+	if v, ok := d.GetOk("assume_role"); ok {
+		assumeRole := v.([]interface{})[0].(map[string]interface{})
+		config.AssumeRoleAgency = assumeRole["agency_name"].(string)
+		config.AssumeRoleDomain = assumeRole["domain_name"].(string)
+		ext.AssumeRoleDuration = assumeRole["duration"].(int)
+	}
+
+	// the arguments and environment variables take precedence over the shared profile
+	config.SharedConfigFile = d.Get("shared_config_file").(string)
+	config.Profile = d.Get("profile").(string)
+	if config.SharedConfigFile != "" || config.Profile != "" {
+		profile, err := loadSharedProfile(config.SharedConfigFile, config.Profile)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		applySharedProfile(&config, profile)
+	}
+
+	region := config.Region
+	if region == "" {
+		return nil, diag.Errorf("region must be specified in the provider block, " +
+			"the OS_REGION_NAME environment variable or the shared profile")
+	}
+
+	// set tenant_name to region when neither `tenant_name` nor `tenant_id` was specified
+	if config.TenantID == "" && config.TenantName == "" {
+		config.TenantName = region
+	}
+
+	// the identity endpoint is generated from endpoint_template if specified
+	endpointTemplate := d.Get("endpoint_template").(string)
+	if config.IdentityEndpoint == "" && endpointTemplate == "" {
+		config.IdentityEndpoint = fmt.Sprintf("https://iam.%s.%s/v3", mainRegion, cloud)
+	}
+
 	config.MaxRetries = d.Get("max_retries").(int)
 	config.Insecure = d.Get("insecure").(bool)
 	config.CACertFile = d.Get("cacert_file").(string)
@@ -753,6 +996,22 @@ func configureProvider(_ context.Context, d *schema.ResourceData) (interface{},
 		return nil, diag.FromErr(err)
 	}
 
+	// generate the endpoints which are not customized from the template,
+	// so the hard-coded default endpoints below are not used any more
+	if endpointTemplate != "" {
+		ext.EndpointTemplate = endpointTemplate
+		ext.CustomEndpoints = make(map[string]string, len(endpoints))
+		for k, v := range endpoints {
+			ext.CustomEndpoints[k] = v
+		}
+		if err := expandEndpointTemplate(endpoints, endpointTemplate, region); err != nil {
+			return nil, diag.FromErr(err)
+		}
+		if config.IdentityEndpoint == "" {
+			config.IdentityEndpoint = endpoints["identity"] + "v3"
+		}
+	}
+
 	// set default endpoints
 	if _, ok := endpoints["obs"]; !ok {
 		endpoints["obs"] = fmt.Sprintf("https://oss.%s.%s/", region, config.Cloud)
@@ -777,12 +1036,78 @@ func configureProvider(_ context.Context, d *schema.ResourceData) (interface{},
 	}
 
 	config.Endpoints = endpoints
+	ext.SkipCatalog = d.Get("skip_catalog").(bool)
+	ext.RetryPolicy = expandProviderRetryPolicy(d, config.MaxRetries)
+	ext.RateLimiter = newServiceRateLimiter(d.Get("rate_limit").(int), expandProviderRateLimits(d), endpoints)
+	ext.HTTPLog = expandProviderHTTPLog(d, endpoints)
+
 	if err := LoadAndValidate(&config); err != nil {
 		return nil, diag.FromErr(err)
 	}
 	return &config, nil
 }
 
+func expandProviderRetryPolicy(d *schema.ResourceData, maxRetries int) *backoffPolicy {
+	policy := defaultBackoffPolicy(maxRetries)
+
+	rawList := d.Get("retry_policy").([]interface{})
+	if len(rawList) == 0 || rawList[0] == nil {
+		return policy
+	}
+
+	raw := rawList[0].(map[string]interface{})
+	policy.BaseDelay = time.Duration(raw["base_delay"].(int)) * time.Second
+	policy.MaxDelay = time.Duration(raw["max_delay"].(int)) * time.Second
+	policy.Jitter = raw["jitter"].(bool)
+	policy.MaxDuration = time.Duration(raw["max_duration"].(int)) * time.Second
+
+	log.Printf("[DEBUG] retry policy: %+v", *policy)
+	return policy
+}
+
+func expandProviderRateLimits(d *schema.ResourceData) map[string]int {
+	rawLimits := d.Get("rate_limits").(map[string]interface{})
+	limits := make(map[string]int, len(rawLimits))
+	for srv, val := range rawLimits {
+		limits[srv] = val.(int)
+
+		// the derived catalogs share the limit of the main service
+		for _, k := range config.GetServiceDerivedCatalogKeys(srv) {
+			if _, ok := rawLimits[k]; !ok {
+				limits[k] = val.(int)
+			}
+		}
+	}
+	return limits
+}
+
+func expandProviderHTTPLog(d *schema.ResourceData, endpoints map[string]string) *httpLogOptions {
+	rawList := d.Get("http_log").([]interface{})
+	if len(rawList) == 0 {
+		return nil
+	}
+
+	// an empty block logs all services without the bodies
+	raw, _ := rawList[0].(map[string]interface{})
+	if raw == nil {
+		return newHTTPLogOptions(nil, false, httpLogFormatText, nil, endpoints)
+	}
+
+	var services []string
+	for _, v := range raw["services"].([]interface{}) {
+		srv := v.(string)
+		// the derived catalogs are logged with the main service
+		services = append(services, srv)
+		services = append(services, config.GetServiceDerivedCatalogKeys(srv)...)
+	}
+
+	opts := newHTTPLogOptions(services, raw["include_bodies"].(bool), raw["format"].(string),
+		utils.ExpandToStringList(raw["redact_keys"].([]interface{})), endpoints)
+	log.Printf("[DEBUG] HTTP log options: services %v, include bodies %t, format %s",
+		services, opts.IncludeBodies, opts.Format)
+	return opts
+}
+
 func flattenProviderEndpoints(d *schema.ResourceData) (map[string]string, error) {
 	endpoints := d.Get("endpoints").(map[string]interface{})
 	epMap := make(map[string]string)
@@ -0,0 +1,182 @@
+package flexibleengine
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple token bucket which allows `rate` requests per second,
+// the burst size is the same as the rate.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	tokens   float64
+	lastTime time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{
+		rate:     float64(rate),
+		tokens:   float64(rate),
+		lastTime: time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller should wait
+// before the token is available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.lastTime).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.lastTime = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or the context is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	wait := b.reserve()
+	if wait == 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serviceRateLimiter limits the requests per second of each service, the service is
+// identified by the catalog name, e.g. ecs, vpc, evs.
+type serviceRateLimiter struct {
+	// DefaultRate is the rate of the services without an override, zero means unlimited
+	DefaultRate int
+	// Rates is the per-service override of the rate
+	Rates map[string]int
+	// hosts is the map of the custom endpoint hosts and their service names
+	hosts map[string]string
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newServiceRateLimiter(defaultRate int, rates map[string]int, endpoints map[string]string) *serviceRateLimiter {
+	hasRate := func(srv string) bool {
+		_, ok := rates[srv]
+		return ok
+	}
+	return &serviceRateLimiter{
+		DefaultRate: defaultRate,
+		Rates:       rates,
+		hosts:       endpointServiceHosts(endpoints, hasRate),
+		buckets:     make(map[string]*tokenBucket),
+	}
+}
+
+// endpointServiceHosts returns the map of the custom endpoint hosts and their service names.
+// When several keys share an endpoint, the keys matched by preferred take precedence, and then
+// the shortest key which is usually the main catalog key rather than a derived one.
+func endpointServiceHosts(endpoints map[string]string, preferred func(string) bool) map[string]string {
+	hosts := make(map[string]string, len(endpoints))
+	for srv, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		current, ok := hosts[u.Host]
+		switch {
+		case !ok, preferred(srv) && !preferred(current):
+			hosts[u.Host] = srv
+		case preferred(srv) == preferred(current):
+			if len(srv) < len(current) || (len(srv) == len(current) && srv < current) {
+				hosts[u.Host] = srv
+			}
+		}
+	}
+	return hosts
+}
+
+// serviceNameOfURL returns the service of the request URL which likes https://{Name}.{Region}.{Cloud}/,
+// the requests to a custom endpoint or its subdomains (e.g. OBS buckets) belong to the endpoint key.
+func serviceNameOfURL(hosts map[string]string, u *url.URL) string {
+	if srv, ok := hosts[u.Host]; ok {
+		return srv
+	}
+	if parts := strings.SplitN(u.Host, ".", 2); len(parts) == 2 {
+		if srv, ok := hosts[parts[1]]; ok {
+			return srv
+		}
+	}
+	return strings.SplitN(u.Hostname(), ".", 2)[0]
+}
+
+// enabled reports whether any limit is configured.
+func (l *serviceRateLimiter) enabled() bool {
+	return l != nil && (l.DefaultRate > 0 || len(l.Rates) > 0)
+}
+
+// serviceName returns the service of the request URL which likes https://{Name}.{Region}.{Cloud}/
+func (l *serviceRateLimiter) serviceName(u *url.URL) string {
+	return serviceNameOfURL(l.hosts, u)
+}
+
+// bucket returns the token bucket of the service, nil means the service is unlimited.
+func (l *serviceRateLimiter) bucket(srv string) *tokenBucket {
+	rate := l.DefaultRate
+	if v, ok := l.Rates[srv]; ok {
+		rate = v
+	}
+	if rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[srv]
+	if !ok {
+		b = newTokenBucket(rate)
+		l.buckets[srv] = b
+	}
+	return b
+}
+
+// rateLimitRoundTripper satisfies the http.RoundTripper interface and delays the requests
+// which exceed the rate limit of the service.
+type rateLimitRoundTripper struct {
+	Rt      http.RoundTripper
+	Limiter *serviceRateLimiter
+}
+
+// RoundTrip waits for the rate limiter of the service and then performs the HTTP request.
+func (lrt *rateLimitRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	srv := lrt.Limiter.serviceName(request.URL)
+	if b := lrt.Limiter.bucket(srv); b != nil {
+		start := time.Now()
+		if err := b.Wait(request.Context()); err != nil {
+			return nil, err
+		}
+		if waited := time.Since(start); waited > time.Second {
+			log.Printf("[DEBUG] the request to %s service was delayed %s by the rate limiter", srv, waited)
+		}
+	}
+
+	return lrt.Rt.RoundTrip(request)
+}
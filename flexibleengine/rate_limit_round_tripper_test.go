@@ -0,0 +1,39 @@
+package flexibleengine
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestServiceRateLimiter_serviceName(t *testing.T) {
+	endpoints := map[string]string{
+		"obs": "https://oss.eu-west-0.prod-cloud-ocb.orange-business.com/",
+	}
+	limiter := newServiceRateLimiter(0, map[string]int{"ecs": 10}, endpoints)
+
+	u, _ := url.Parse("https://ecs.eu-west-0.prod-cloud-ocb.orange-business.com/v1/xxx/cloudservers")
+	th.AssertEquals(t, "ecs", limiter.serviceName(u))
+
+	// the service name of custom endpoints is the key in the endpoints map
+	u, _ = url.Parse("https://oss.eu-west-0.prod-cloud-ocb.orange-business.com/bucket")
+	th.AssertEquals(t, "obs", limiter.serviceName(u))
+
+	th.AssertEquals(t, true, limiter.bucket("ecs") != nil)
+	th.AssertEquals(t, true, limiter.bucket("vpc") == nil)
+}
+
+func TestTokenBucket_reserve(t *testing.T) {
+	bucket := newTokenBucket(2)
+
+	// the burst size is the same as the rate
+	th.AssertEquals(t, time.Duration(0), bucket.reserve())
+	th.AssertEquals(t, time.Duration(0), bucket.reserve())
+
+	// the third request has to wait about half a second
+	if wait := bucket.reserve(); wait < 400*time.Millisecond || wait > 500*time.Millisecond {
+		t.Fatalf("unexpected wait time of the third request: %s", wait)
+	}
+}
@@ -0,0 +1,402 @@
+package flexibleengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	recorderModeRecord = "record"
+	recorderModeReplay = "replay"
+
+	// redactedValue replaces the secrets in the cassettes
+	redactedValue = "REDACTED"
+)
+
+// sensitiveHeaders are the headers which contain signatures, tokens or keys
+var sensitiveHeaders = []string{
+	"Authorization", "X-Auth-Token", "X-Subject-Token", "X-Security-Token",
+}
+
+// sensitiveBodyKeys are the JSON keys whose values are replaced in the request and response bodies
+var sensitiveBodyKeys = []string{
+	"password", "access", "secret", "securitytoken", "access_key", "secret_key", "security_token",
+	"adminPass", "admin_pass",
+}
+
+// interaction is a recorded pair of request and response
+type interaction struct {
+	Method          string              `yaml:"method"`
+	URL             string              `yaml:"url"`
+	RequestHeaders  map[string][]string `yaml:"request_headers,omitempty"`
+	RequestBody     string              `yaml:"request_body,omitempty"`
+	StatusCode      int                 `yaml:"status_code"`
+	ResponseHeaders map[string][]string `yaml:"response_headers,omitempty"`
+	ResponseBody    string              `yaml:"response_body,omitempty"`
+
+	replayed bool
+}
+
+// cassette is the file which stores the interactions of an acceptance test
+type cassette struct {
+	mu           sync.Mutex
+	path         string
+	Interactions []*interaction `yaml:"interactions"`
+}
+
+// cassettes are shared by all clients of the process, so the interactions of
+// several test steps and provider instances are recorded to and replayed from the same file.
+var (
+	cassettesLock sync.Mutex
+	cassettes     = make(map[string]*cassette)
+)
+
+// loadCassette returns the cassette of path, a new one is started when recording
+// and the file is read when replaying.
+func loadCassette(path, mode string) (*cassette, error) {
+	cassettesLock.Lock()
+	defer cassettesLock.Unlock()
+
+	if c, ok := cassettes[path]; ok {
+		return c, nil
+	}
+
+	c := &cassette{path: path}
+	if mode == recorderModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %s", err)
+		}
+		if err := yaml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("error parsing cassette %s: %s", path, err)
+		}
+	}
+
+	cassettes[path] = c
+	return c, nil
+}
+
+func (c *cassette) add(item *interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, item)
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0600)
+}
+
+// find returns the first interaction which was not replayed and has the same method and URL,
+// the one with the same body is preferred. The last matched interaction is replayed again when
+// all of them have been replayed, so that the polling of the status always ends.
+func (c *cassette) find(method, url, body string) *interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var first, last *interaction
+	for _, item := range c.Interactions {
+		if item.Method != method || item.URL != url {
+			continue
+		}
+		last = item
+		if item.replayed {
+			continue
+		}
+		if item.RequestBody == body {
+			item.replayed = true
+			return item
+		}
+		if first == nil {
+			first = item
+		}
+	}
+
+	if first != nil {
+		first.replayed = true
+		return first
+	}
+	return last
+}
+
+// recorderOptions specifies how the requests of a provider instance are recorded or replayed
+type recorderOptions struct {
+	// Mode is recorderModeRecord or recorderModeReplay
+	Mode string
+	// Cassette is the path of the file which stores the interactions
+	Cassette string
+}
+
+// recorderRoundTripper satisfies the http.RoundTripper interface and records the
+// requests and responses to a cassette, or serves the responses from the cassette
+// without any network access. It is used to run the acceptance tests offline.
+type recorderRoundTripper struct {
+	Rt       http.RoundTripper
+	Mode     string
+	Cassette *cassette
+}
+
+// newRecorderRoundTripper wraps rt with the cassette of opts, rt is returned as it is if opts is nil.
+func newRecorderRoundTripper(rt http.RoundTripper, opts *recorderOptions) (http.RoundTripper, error) {
+	if opts == nil || opts.Mode == "" {
+		return rt, nil
+	}
+	mode := opts.Mode
+	if mode != recorderModeRecord && mode != recorderModeReplay {
+		return nil, fmt.Errorf("invalid OS_RECORDER_MODE %s, must be %s or %s", mode, recorderModeRecord, recorderModeReplay)
+	}
+	if opts.Cassette == "" {
+		return nil, fmt.Errorf("the cassette must be specified in %s mode", mode)
+	}
+
+	c, err := loadCassette(opts.Cassette, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] the requests are in %s mode with cassette %s", mode, opts.Cassette)
+	return &recorderRoundTripper{
+		Rt:       rt,
+		Mode:     mode,
+		Cassette: c,
+	}, nil
+}
+
+// RoundTrip records or replays the request.
+func (rrt *recorderRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+	reqBody := sanitizeBody(body)
+
+	if rrt.Mode == recorderModeReplay {
+		item := rrt.Cassette.find(request.Method, request.URL.String(), reqBody)
+		if item == nil {
+			return nil, fmt.Errorf("no interaction of %s %s was recorded in cassette %s",
+				request.Method, request.URL, rrt.Cassette.path)
+		}
+		return item.response(request), nil
+	}
+
+	response, err := rrt.Rt.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	item := &interaction{
+		Method:          request.Method,
+		URL:             request.URL.String(),
+		RequestHeaders:  sanitizeHeaders(request.Header),
+		RequestBody:     reqBody,
+		StatusCode:      response.StatusCode,
+		ResponseHeaders: sanitizeHeaders(response.Header),
+		ResponseBody:    sanitizeBody(respBody),
+	}
+	if err := rrt.Cassette.add(item); err != nil {
+		log.Printf("[WARN] error saving cassette %s: %s", rrt.Cassette.path, err)
+	}
+	return response, nil
+}
+
+func (item *interaction) response(request *http.Request) *http.Response {
+	header := make(http.Header)
+	for k, v := range item.ResponseHeaders {
+		header[k] = v
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", item.StatusCode, http.StatusText(item.StatusCode)),
+		StatusCode:    item.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(item.ResponseBody)),
+		ContentLength: int64(len(item.ResponseBody)),
+		Request:       request,
+	}
+}
+
+// readRequestBody reads the body and makes it readable again
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func sanitizeHeaders(header http.Header) map[string][]string {
+	result := make(map[string][]string, len(header))
+	for k, v := range header {
+		result[k] = v
+	}
+	for _, key := range sensitiveHeaders {
+		if _, ok := result[http.CanonicalHeaderKey(key)]; ok {
+			result[http.CanonicalHeaderKey(key)] = []string{redactedValue}
+		}
+	}
+	return result
+}
+
+// sanitizeBody replaces the values of sensitiveBodyKeys in a JSON body,
+// the other bodies are returned as they are.
+func sanitizeBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return string(body)
+	}
+
+	sanitized, err := json.Marshal(redactJSON(data, sensitiveBodyKeys))
+	if err != nil {
+		return string(body)
+	}
+	return string(sanitized)
+}
+
+// redactJSON replaces the values of keys in the decoded JSON data recursively
+func redactJSON(data interface{}, keys []string) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveKey(key, keys) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactJSON(value, keys)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactJSON(value, keys)
+		}
+	}
+	return data
+}
+
+func isSensitiveKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccRecorder records the requests of an acceptance test to its own cassette or replays them
+// without network access, the mode is specified by OS_RECORDER_MODE. The cassette is passed to
+// the provider instances of the test, so the tests with different cassettes can run in parallel.
+type AccRecorder struct {
+	// Mode is empty if the requests are neither recorded nor replayed
+	Mode string
+	// Cassette is stored in testdata/cassettes of the test package and named after the test
+	Cassette string
+
+	name     string
+	provider *schema.Provider
+	mu       sync.Mutex
+	random   *rand.Rand
+}
+
+// NewAccRecorder returns the recorder of the test which is named testName.
+func NewAccRecorder(testName string) *AccRecorder {
+	name := strings.ReplaceAll(testName, "/", "_")
+	return &AccRecorder{
+		Mode:     os.Getenv("OS_RECORDER_MODE"),
+		Cassette: filepath.Join("testdata", "cassettes", name+".yaml"),
+		name:     name,
+	}
+}
+
+// Provider returns the provider instance of the test, which is configured with the cassette.
+func (r *AccRecorder) Provider() *schema.Provider {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.provider != nil {
+		return r.provider
+	}
+
+	r.provider = Provider()
+	if r.Mode != "" {
+		opts := &recorderOptions{Mode: r.Mode, Cassette: r.Cassette}
+		r.provider.ConfigureContextFunc = func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+			return configureProviderWithRecorder(ctx, d, opts)
+		}
+	}
+	return r.provider
+}
+
+// ProviderFactories returns the factories of TestCase.ProviderFactories
+func (r *AccRecorder) ProviderFactories() map[string]func() (*schema.Provider, error) {
+	return map[string]func() (*schema.Provider, error){
+		"flexibleengine": func() (*schema.Provider, error) {
+			return r.Provider(), nil
+		},
+	}
+}
+
+// RandomName returns a name with a random suffix of 5 characters. The suffixes are generated from
+// the test name when recording and replaying, so the requests of both modes are the same.
+func (r *AccRecorder) RandomName(prefix string) string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.random == nil {
+		seed := time.Now().UnixNano()
+		if r.Mode != "" {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(r.name))
+			seed = int64(h.Sum64())
+		}
+		r.random = rand.New(rand.NewSource(seed)) //nolint:gosec
+	}
+
+	suffix := make([]byte, 5)
+	for i := range suffix {
+		suffix[i] = charset[r.random.Intn(len(charset))]
+	}
+	return fmt.Sprintf("%s-%s", prefix, suffix)
+}
@@ -0,0 +1,145 @@
+package flexibleengine
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestRecorderRoundTripper(t *testing.T) {
+	var status string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Subject-Token", "secret-token")
+		if r.Method == http.MethodPost {
+			status = "BUILD"
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"server":{"id":"server-id","adminPass":"secret-password"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"server":{"id":"server-id","status":"`+status+`"}}`)
+		status = "ACTIVE"
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassettes", "TestRecorder.yaml")
+	recorder, err := newRecorderRoundTripper(http.DefaultTransport, &recorderOptions{Mode: recorderModeRecord, Cassette: path})
+	th.AssertNoErr(t, err)
+
+	client := http.Client{Transport: recorder}
+	request := func(method, body string) (int, string) {
+		req, _ := http.NewRequest(method, server.URL+"/v1/servers/server-id", strings.NewReader(body))
+		req.Header.Set("Authorization", "SDK-HMAC-SHA256 Access=ak, Signature=xxx")
+		resp, err := client.Do(req)
+		th.AssertNoErr(t, err)
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, _ := request(http.MethodPost, `{"server":{"name":"test","adminPass":"secret-password"}}`)
+	th.AssertEquals(t, http.StatusCreated, code)
+	_, body := request(http.MethodGet, "")
+	th.AssertEquals(t, true, strings.Contains(body, "BUILD"))
+	_, body = request(http.MethodGet, "")
+	th.AssertEquals(t, true, strings.Contains(body, "ACTIVE"))
+	server.Close()
+
+	// the secrets are scrubbed from the cassette
+	data, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	for _, secret := range []string{"secret-token", "secret-password", "Signature=xxx"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("%s was recorded in the cassette:\n%s", secret, data)
+		}
+	}
+
+	// the responses are replayed in the recorded order without network
+	delete(cassettes, path)
+	recorder, err = newRecorderRoundTripper(http.DefaultTransport, &recorderOptions{Mode: recorderModeReplay, Cassette: path})
+	th.AssertNoErr(t, err)
+	client = http.Client{Transport: recorder}
+
+	code, _ = request(http.MethodPost, `{"server":{"name":"test","adminPass":"secret-password"}}`)
+	th.AssertEquals(t, http.StatusCreated, code)
+	_, body = request(http.MethodGet, "")
+	th.AssertEquals(t, true, strings.Contains(body, "BUILD"))
+	// the last response is replayed again when polling the status
+	for i := 0; i < 2; i++ {
+		_, body = request(http.MethodGet, "")
+		th.AssertEquals(t, true, strings.Contains(body, "ACTIVE"))
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/v1/servers/server-id", nil)
+	_, err = client.Do(req)
+	th.AssertEquals(t, true, err != nil)
+}
+
+func TestSanitizeBody(t *testing.T) {
+	body := `{"auth":{"identity":{"password":{"user":{"name":"admin","password":"secret"}}}},"count":12345678901}`
+	expected := `{"auth":{"identity":{"password":"REDACTED"}},"count":12345678901}`
+	th.AssertEquals(t, expected, sanitizeBody([]byte(body)))
+
+	// the bodies which are not JSON are recorded as they are
+	th.AssertEquals(t, "<xml/>", sanitizeBody([]byte("<xml/>")))
+}
+
+// TestRecorderReplay replays the requests of a VPC in testdata/cassettes without network access, the cassette
+// is recorded against the mock server so it covers the matching of the recorder rather than the real API.
+func TestRecorderReplay(t *testing.T) {
+	t.Parallel()
+	recorder := NewAccRecorder(t.Name())
+	recorder.Mode = recorderModeReplay
+
+	p := recorder.Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"region":      "eu-west-0",
+		"domain_name": "mock-domain",
+		"access_key":  "fake",
+		"secret_key":  "fake",
+		"max_retries": 0,
+	}))
+	if diags.HasError() {
+		t.Fatalf("error configuring the provider: %v", diags)
+	}
+
+	driver := newMockResourceDriver(t, "flexibleengine_vpc_v1", p.Meta())
+	config := map[string]interface{}{
+		"name": recorder.RandomName("vpc-replay"),
+		"cidr": "192.168.0.0/16",
+		"tags": map[string]interface{}{"owner": "terraform"},
+	}
+	driver.apply(config)
+	th.AssertEquals(t, "OK", driver.state.Attributes["status"])
+
+	config["description"] = "updated by terraform"
+	driver.apply(config)
+	state := driver.refresh()
+	th.AssertEquals(t, config["name"], state.Attributes["name"])
+	th.AssertEquals(t, "updated by terraform", state.Attributes["description"])
+
+	imported := driver.importState(state.ID)
+	th.AssertEquals(t, "192.168.0.0/16", imported.Attributes["cidr"])
+	driver.destroy()
+}
+
+func TestRecorderRandomName(t *testing.T) {
+	// the names are the same when recording and replaying the same test
+	recorded := NewAccRecorder("TestAccVpcV1_basic")
+	recorded.Mode = recorderModeRecord
+	replayed := NewAccRecorder("TestAccVpcV1_basic")
+	replayed.Mode = recorderModeReplay
+	name := recorded.RandomName("vpc")
+	th.AssertEquals(t, name, replayed.RandomName("vpc"))
+
+	other := NewAccRecorder("TestAccVpcV1_secondaryCIDR")
+	other.Mode = recorderModeReplay
+	th.AssertEquals(t, true, name != other.RandomName("vpc"))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/chnsz/golangsdk/openstack/autoscaling/v1/configurations"
 	"github.com/chnsz/golangsdk/openstack/autoscaling/v1/groups"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 func resourceASConfiguration() *schema.Resource {
@@ -20,6 +21,10 @@ func resourceASConfiguration() *schema.Resource {
 		Update: nil,
 		Delete: resourceASConfigurationDelete,
 
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
 		Schema: map[string]*schema.Schema{
 			"region": {
 				Type:     schema.TypeString,
@@ -162,6 +167,20 @@ func resourceASConfiguration() *schema.Resource {
 							Type:     schema.TypeMap,
 							Optional: true,
 						},
+						"spot_price": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"spot_duration": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntBetween(1, 6),
+						},
+						"interruption_policy": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{"immediate"}, false),
+						},
 					},
 				},
 			},
@@ -266,10 +285,42 @@ func getInstanceConfig(configDataMap map[string]interface{}) (configurations.Ins
 		instanceConfigOpts.PubicIP = &publicIps
 		log.Printf("[DEBUG] get publicIps: %#v", publicIps)
 	}
+	if configDataMap["spot_price"].(string) != "" {
+		instanceConfigOpts.MarketType = "spot"
+	} else if configDataMap["spot_duration"].(int) > 0 || configDataMap["interruption_policy"].(string) != "" {
+		return instanceConfigOpts, fmt.Errorf("spot_price must be specified with spot_duration and interruption_policy")
+	}
 	log.Printf("[DEBUG] get instanceConfig: %#v", instanceConfigOpts)
 	return instanceConfigOpts, nil
 }
 
+// spotConfigurationCreateOpts adds the spot options of the instances to the request body,
+// they are missing in configurations.InstanceConfigOpts.
+type spotConfigurationCreateOpts struct {
+	configurations.CreateOpts
+	SpotPrice          string
+	SpotDurationHours  int
+	InterruptionPolicy string
+}
+
+func (opts spotConfigurationCreateOpts) ToConfigurationCreateMap() (map[string]interface{}, error) {
+	b, err := opts.CreateOpts.ToConfigurationCreateMap()
+	if err != nil {
+		return nil, err
+	}
+
+	instanceConfig := b["instance_config"].(map[string]interface{})
+	instanceConfig["spot_price"] = opts.SpotPrice
+	if opts.SpotDurationHours > 0 {
+		instanceConfig["spot_duration_hours"] = opts.SpotDurationHours
+		instanceConfig["spot_duration_count"] = 1
+	}
+	if opts.InterruptionPolicy != "" {
+		instanceConfig["interruption_policy"] = opts.InterruptionPolicy
+	}
+	return b, nil
+}
+
 func resourceASConfigurationCreate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 	asClient, err := config.AutoscalingV1Client(GetRegion(d, config))
@@ -283,10 +334,18 @@ func resourceASConfigurationCreate(d *schema.ResourceData, meta interface{}) err
 	if err1 != nil {
 		return fmt.Errorf("Error when getting instance_config info: %s", err1)
 	}
-	createOpts := configurations.CreateOpts{
+	var createOpts configurations.CreateOptsBuilder = configurations.CreateOpts{
 		Name:           d.Get("scaling_configuration_name").(string),
 		InstanceConfig: instanceConfig,
 	}
+	if spotPrice := configDataMap["spot_price"].(string); spotPrice != "" {
+		createOpts = spotConfigurationCreateOpts{
+			CreateOpts:         createOpts.(configurations.CreateOpts),
+			SpotPrice:          spotPrice,
+			SpotDurationHours:  configDataMap["spot_duration"].(int),
+			InterruptionPolicy: configDataMap["interruption_policy"].(string),
+		}
+	}
 
 	log.Printf("[DEBUG] Create AS configuration Options: %#v", createOpts)
 	asConfigId, err := configurations.Create(asClient, createOpts).Extract()
@@ -313,9 +372,72 @@ func resourceASConfigurationRead(d *schema.ResourceData, meta interface{}) error
 
 	log.Printf("[DEBUG] Retrieved ASConfiguration %q: %+v", d.Id(), asConfig)
 
+	d.Set("region", GetRegion(d, config))
+	d.Set("scaling_configuration_name", asConfig.Name)
+	if err := d.Set("instance_config", flattenInstanceConfig(d, asConfig.InstanceConfig)); err != nil {
+		return fmt.Errorf("Error setting instance_config: %s", err)
+	}
+
 	return nil
 }
 
+// flattenInstanceConfig converts the instance_config of the AS configuration, user_data and
+// the spot options are not returned by the API and are kept from the state.
+func flattenInstanceConfig(d *schema.ResourceData, instanceConfig configurations.InstanceConfig) []map[string]interface{} {
+	disks := make([]map[string]interface{}, len(instanceConfig.Disk))
+	for i, disk := range instanceConfig.Disk {
+		disks[i] = map[string]interface{}{
+			"size":        disk.Size,
+			"volume_type": disk.VolumeType,
+			"disk_type":   disk.DiskType,
+		}
+	}
+
+	personalities := make([]map[string]interface{}, len(instanceConfig.Personality))
+	for i, personality := range instanceConfig.Personality {
+		personalities[i] = map[string]interface{}{
+			"path":    personality.Path,
+			"content": personality.Content,
+		}
+	}
+
+	var publicIps []map[string]interface{}
+	if eip := instanceConfig.PublicIp.Eip; eip.Type != "" {
+		publicIps = []map[string]interface{}{
+			{
+				"eip": []map[string]interface{}{
+					{
+						"ip_type": eip.Type,
+						"bandwidth": []map[string]interface{}{
+							{
+								"size":          eip.Bandwidth.Size,
+								"share_type":    eip.Bandwidth.ShareType,
+								"charging_mode": eip.Bandwidth.ChargingMode,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	result := map[string]interface{}{
+		"instance_id":         instanceConfig.InstanceID,
+		"flavor":              instanceConfig.FlavorRef,
+		"image":               instanceConfig.ImageRef,
+		"key_name":            instanceConfig.SSHKey,
+		"user_data":           d.Get("instance_config.0.user_data"),
+		"disk":                disks,
+		"personality":         personalities,
+		"public_ip":           publicIps,
+		"metadata":            instanceConfig.Metadata,
+		"spot_price":          d.Get("instance_config.0.spot_price"),
+		"spot_duration":       d.Get("instance_config.0.spot_duration"),
+		"interruption_policy": d.Get("instance_config.0.interruption_policy"),
+	}
+	return []map[string]interface{}{result}
+}
+
 func resourceASConfigurationDelete(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 	asClient, err := config.AutoscalingV1Client(GetRegion(d, config))
@@ -12,6 +12,7 @@ import (
 
 func TestAccASV1Configuration_basic(t *testing.T) {
 	var asConfig configurations.Configuration
+	resourceName := "flexibleengine_as_configuration_v1.hth_as_config"
 
 	resource.Test(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheck(t) },
@@ -21,7 +22,33 @@ func TestAccASV1Configuration_basic(t *testing.T) {
 			{
 				Config: testASV1Configuration_basic,
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckASV1ConfigurationExists("flexibleengine_as_configuration_v1.hth_as_config", &asConfig),
+					testAccCheckASV1ConfigurationExists(resourceName, &asConfig),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccASV1Configuration_spot(t *testing.T) {
+	var asConfig configurations.Configuration
+	resourceName := "flexibleengine_as_configuration_v1.hth_as_config"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckASV1ConfigurationDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testASV1Configuration_spot,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckASV1ConfigurationExists(resourceName, &asConfig),
+					resource.TestCheckResourceAttr(resourceName, "instance_config.0.spot_price", "0.05"),
+					resource.TestCheckResourceAttr(resourceName, "instance_config.0.interruption_policy", "immediate"),
 				),
 			},
 		},
@@ -103,3 +130,32 @@ resource "flexibleengine_as_configuration_v1" "hth_as_config"{
   }
 }
 `
+
+var testASV1Configuration_spot = `
+data "flexibleengine_images_image_v2" "ubuntu" {
+  name = "OBS Ubuntu 18.04"
+}
+
+resource "flexibleengine_compute_keypair_v2" "hth_key" {
+  name = "hth_key"
+  public_key = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDAjpC1hwiOCCmKEWxJ4qzTTsJbKzndLo1BCz5PcwtUnflmU+gHJtWMZKpuEGVi29h0A/+ydKek1O18k10Ff+4tyFjiHDQAT9+OfgWf7+b1yK+qDip3X1C0UPMbwHlTfSGWLGZquwhvEFx9k3h/M+VtMvwR1lJ9LUyTAImnNjWG7TAIPmui30HvM2UiFEmqkr4ijq45MyX2+fLIePLRIFuu1p4whjHAQYufqyno3BS48icQb4p6iVEZPo4AE2o9oIyQvj2mx4dk5Y8CgSETOZTYDOR3rU2fZTRDRgPJDH9FWvQjF5tA0p3d9CoWWd2s6GKKbfoUIi8R/Db1BSPJwkqB jrp-hp-pc"
+}
+
+resource "flexibleengine_as_configuration_v1" "hth_as_config"{
+  scaling_configuration_name = "hth_as_config"
+  instance_config {
+    image    = data.flexibleengine_images_image_v2.ubuntu.id
+    key_name = flexibleengine_compute_keypair_v2.hth_key.id
+
+    spot_price          = "0.05"
+    spot_duration       = 1
+    interruption_policy = "immediate"
+
+    disk {
+      size = 40
+      volume_type = "SATA"
+      disk_type = "SYS"
+    }
+  }
+}
+`
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/autoscaling/v1/configurations"
 	"github.com/chnsz/golangsdk/openstack/autoscaling/v1/groups"
 	"github.com/chnsz/golangsdk/openstack/autoscaling/v1/instances"
 	"github.com/chnsz/golangsdk/openstack/common/tags"
@@ -21,6 +22,9 @@ func resourceASGroup() *schema.Resource {
 		Read:   resourceASGroupRead,
 		Update: resourceASGroupUpdate,
 		Delete: resourceASGroupDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
@@ -105,6 +109,7 @@ func resourceASGroup() *schema.Resource {
 			"available_zones": {
 				Type:     schema.TypeList,
 				Optional: true,
+				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 				ForceNew: false,
 			},
@@ -191,6 +196,12 @@ func resourceASGroup() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeString},
 				Description: "The instances id list in the as group.",
 			},
+			"spot_instances": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The spot instances id list in the as group.",
+			},
 			"current_instance_number": {
 				Type:     schema.TypeInt,
 				Computed: true,
@@ -333,6 +344,34 @@ func getInstancesIDs(allIns []instances.Instance) []string {
 	return allIDs
 }
 
+// getSpotInstancesIDs returns the IDs of the instances which are created by the spot configurations
+func getSpotInstancesIDs(asClient *golangsdk.ServiceClient, allIns []instances.Instance) []string {
+	spotConfigs := make(map[string]bool)
+	var spotIDs []string
+	for _, ins := range allIns {
+		if ins.ID == "" || ins.ConfigurationID == "" {
+			continue
+		}
+
+		spot, ok := spotConfigs[ins.ConfigurationID]
+		if !ok {
+			asConfig, err := configurations.Get(asClient, ins.ConfigurationID).Extract()
+			if err != nil {
+				// the configuration may have been deleted after the instance is created
+				log.Printf("[WARN] Error retrieving AS configuration %s: %s", ins.ConfigurationID, err)
+			} else {
+				spot = asConfig.InstanceConfig.MarketType == "spot"
+			}
+			spotConfigs[ins.ConfigurationID] = spot
+		}
+		if spot {
+			spotIDs = append(spotIDs, ins.ID)
+		}
+	}
+	log.Printf("[DEBUG] Get spot instances in ASGroups: %#v", spotIDs)
+	return spotIDs
+}
+
 func getInstancesLifeStates(allIns []instances.Instance) []string {
 	var allLifeStates []string
 	for _, ins := range allIns {
@@ -554,6 +593,20 @@ func resourceASGroupRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("instance_terminate_policy", asg.InstanceTerminatePolicy)
 	d.Set("scaling_configuration_id", asg.ConfigurationID)
 	d.Set("delete_publicip", asg.DeletePublicip)
+	d.Set("vpc_id", asg.VpcID)
+	d.Set("available_zones", asg.AvailableZones)
+
+	networks := make([]map[string]interface{}, len(asg.Networks))
+	for i, network := range asg.Networks {
+		networks[i] = map[string]interface{}{"id": network.ID}
+	}
+	d.Set("networks", networks)
+	secGroups := make([]map[string]interface{}, len(asg.SecurityGroups))
+	for i, group := range asg.SecurityGroups {
+		secGroups[i] = map[string]interface{}{"id": group.ID}
+	}
+	d.Set("security_groups", secGroups)
+
 	if len(asg.Notifications) >= 1 {
 		d.Set("notifications", asg.Notifications)
 	}
@@ -574,6 +627,7 @@ func resourceASGroupRead(d *schema.ResourceData, meta interface{}) error {
 	}
 	allIDs := getInstancesIDs(allIns)
 	d.Set("instances", allIDs)
+	d.Set("spot_instances", getSpotInstancesIDs(asClient, allIns))
 	d.Set("region", GetRegion(d, config))
 
 	resourceTags, err := tags.Get(asClient, "scaling_group_tag", d.Id()).Extract()
@@ -0,0 +1,216 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/snapshots"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceBlockStorageSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceBlockStorageSnapshotCreate,
+		Read:   resourceBlockStorageSnapshotRead,
+		Update: resourceBlockStorageSnapshotUpdate,
+		Delete: resourceBlockStorageSnapshotDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"volume_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"metadata": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"force": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  false,
+			},
+			"size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceBlockStorageSnapshotCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	blockStorageClient, err := config.BlockStorageV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	createOpts := snapshots.CreateOpts{
+		VolumeID:    d.Get("volume_id").(string),
+		Force:       d.Get("force").(bool),
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Metadata:    resourceContainerMetadataV2(d),
+	}
+	log.Printf("[DEBUG] Create volume snapshot options: %#v", createOpts)
+
+	snapshot, err := snapshots.Create(blockStorageClient, createOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("Error creating the snapshot of volume %s: %s", createOpts.VolumeID, err)
+	}
+	d.SetId(snapshot.ID)
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"creating"},
+		Target:     []string{"available"},
+		Refresh:    blockStorageSnapshotStateRefreshFunc(blockStorageClient, snapshot.ID),
+		Timeout:    d.Timeout(schema.TimeoutCreate),
+		Delay:      5 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error waiting for volume snapshot %s to become available: %s", snapshot.ID, err)
+	}
+
+	return resourceBlockStorageSnapshotRead(d, meta)
+}
+
+func resourceBlockStorageSnapshotRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	blockStorageClient, err := config.BlockStorageV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	snapshot, err := snapshots.Get(blockStorageClient, d.Id()).Extract()
+	if err != nil {
+		return CheckDeleted(d, err, "volume snapshot")
+	}
+	log.Printf("[DEBUG] Retrieved volume snapshot %s: %#v", d.Id(), snapshot)
+
+	d.Set("region", region)
+	d.Set("volume_id", snapshot.VolumeID)
+	d.Set("name", snapshot.Name)
+	d.Set("description", snapshot.Description)
+	d.Set("metadata", snapshot.Metadata)
+	d.Set("size", snapshot.Size)
+	d.Set("status", snapshot.Status)
+
+	return nil
+}
+
+func resourceBlockStorageSnapshotUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	blockStorageClient, err := config.BlockStorageV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	if d.HasChanges("name", "description") {
+		// the snapshots package can only update the metadata
+		updateOpts := map[string]interface{}{
+			"snapshot": map[string]interface{}{
+				"name":        d.Get("name").(string),
+				"description": d.Get("description").(string),
+			},
+		}
+		log.Printf("[DEBUG] Updating volume snapshot %s: %#v", d.Id(), updateOpts)
+
+		r := golangsdk.Result{}
+		_, r.Err = blockStorageClient.Put(blockStorageClient.ServiceURL("snapshots", d.Id()), updateOpts, nil,
+			&golangsdk.RequestOpts{OkCodes: []int{200}})
+		if r.Err != nil {
+			return fmt.Errorf("Error updating volume snapshot %s: %s", d.Id(), r.Err)
+		}
+	}
+
+	if d.HasChange("metadata") {
+		updateOpts := snapshots.UpdateMetadataOpts{
+			Metadata: d.Get("metadata").(map[string]interface{}),
+		}
+		_, err := snapshots.UpdateMetadata(blockStorageClient, d.Id(), updateOpts).ExtractMetadata()
+		if err != nil {
+			return fmt.Errorf("Error updating the metadata of volume snapshot %s: %s", d.Id(), err)
+		}
+	}
+
+	return resourceBlockStorageSnapshotRead(d, meta)
+}
+
+func resourceBlockStorageSnapshotDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	blockStorageClient, err := config.BlockStorageV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	if err := snapshots.Delete(blockStorageClient, d.Id()).ExtractErr(); err != nil {
+		return CheckDeleted(d, err, "volume snapshot")
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"deleting", "available"},
+		Target:     []string{"deleted"},
+		Refresh:    blockStorageSnapshotStateRefreshFunc(blockStorageClient, d.Id()),
+		Timeout:    d.Timeout(schema.TimeoutDelete),
+		Delay:      5 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error waiting for volume snapshot %s to delete: %s", d.Id(), err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func blockStorageSnapshotStateRefreshFunc(client *golangsdk.ServiceClient, snapshotID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		snapshot, err := snapshots.Get(client, snapshotID).Extract()
+		if err != nil {
+			if _, ok := err.(golangsdk.ErrDefault404); ok {
+				return snapshot, "deleted", nil
+			}
+			return nil, "", err
+		}
+
+		if snapshot.Status == "error" || snapshot.Status == "error_deleting" {
+			return snapshot, snapshot.Status, fmt.Errorf("the volume snapshot is in %s status", snapshot.Status)
+		}
+		return snapshot, snapshot.Status, nil
+	}
+}
@@ -0,0 +1,120 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/snapshots"
+	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/volumes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceBlockStorageSnapshotRollback rolls back the data of a volume from its snapshot in place.
+// It is a one-time action: destroying it does not change the volume.
+func resourceBlockStorageSnapshotRollback() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceBlockStorageSnapshotRollbackCreate,
+		Read:   resourceBlockStorageSnapshotRollbackRead,
+		Delete: resourceBlockStorageSnapshotRollbackDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"snapshot_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"volume_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func resourceBlockStorageSnapshotRollbackCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	blockStorageClient, err := config.BlockStorageV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	snapshotID := d.Get("snapshot_id").(string)
+	volumeID := d.Get("volume_id").(string)
+	rollbackOpts := map[string]interface{}{
+		"rollback": map[string]interface{}{
+			"volume_id": volumeID,
+		},
+	}
+	if name := d.Get("name").(string); name != "" {
+		rollbackOpts["rollback"].(map[string]interface{})["name"] = name
+	}
+	log.Printf("[DEBUG] Rolling back volume %s from snapshot %s: %#v", volumeID, snapshotID, rollbackOpts)
+
+	r := golangsdk.Result{}
+	_, r.Err = blockStorageClient.Post(blockStorageClient.ServiceURL("os-vendor-snapshots", snapshotID, "rollback"),
+		rollbackOpts, &r.Body, &golangsdk.RequestOpts{OkCodes: []int{202}})
+	if r.Err != nil {
+		return fmt.Errorf("Error rolling back volume %s from snapshot %s: %s", volumeID, snapshotID, r.Err)
+	}
+	d.SetId(snapshotID)
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"restoring", "rollbacking"},
+		Target:     []string{"available"},
+		Refresh:    VolumeV2StateRefreshFunc(blockStorageClient, volumeID),
+		Timeout:    d.Timeout(schema.TimeoutCreate),
+		Delay:      5 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error waiting for volume %s to be rolled back: %s", volumeID, err)
+	}
+
+	return resourceBlockStorageSnapshotRollbackRead(d, meta)
+}
+
+func resourceBlockStorageSnapshotRollbackRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	blockStorageClient, err := config.BlockStorageV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	// the rollback is gone together with the snapshot or the volume
+	if _, err := snapshots.Get(blockStorageClient, d.Get("snapshot_id").(string)).Extract(); err != nil {
+		return CheckDeleted(d, err, "volume snapshot")
+	}
+	if _, err := volumes.Get(blockStorageClient, d.Get("volume_id").(string)).Extract(); err != nil {
+		return CheckDeleted(d, err, "volume")
+	}
+
+	d.Set("region", region)
+	return nil
+}
+
+func resourceBlockStorageSnapshotRollbackDelete(d *schema.ResourceData, meta interface{}) error {
+	log.Printf("[DEBUG] Removing the rollback of volume %s from the state, the volume is not changed",
+		d.Get("volume_id").(string))
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,240 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/snapshots"
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestAccBlockStorageSnapshot_basic(t *testing.T) {
+	var snapshot snapshots.Snapshot
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_blockstorage_snapshot.snapshot_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBlockStorageSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBlockStorageSnapshot_basic(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageSnapshotExists(resourceName, &snapshot),
+					resource.TestCheckResourceAttr(resourceName, "name", rName),
+					resource.TestCheckResourceAttr(resourceName, "description", "created by terraform"),
+					resource.TestCheckResourceAttr(resourceName, "metadata.foo", "bar"),
+					resource.TestCheckResourceAttr(resourceName, "size", "10"),
+					resource.TestCheckResourceAttr(resourceName, "status", "available"),
+					resource.TestCheckResourceAttrPair(resourceName, "volume_id",
+						"flexibleengine_blockstorage_volume_v2.volume_1", "id"),
+				),
+			},
+			{
+				Config: testAccBlockStorageSnapshot_update(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageSnapshotExists(resourceName, &snapshot),
+					resource.TestCheckResourceAttr(resourceName, "name", rName+"-update"),
+					resource.TestCheckResourceAttr(resourceName, "description", "updated by terraform"),
+					resource.TestCheckResourceAttr(resourceName, "metadata.owner", "terraform"),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"force",
+				},
+			},
+		},
+	})
+}
+
+func TestAccBlockStorageSnapshotRollback_basic(t *testing.T) {
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_blockstorage_snapshot_rollback.rollback"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBlockStorageSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBlockStorageSnapshotRollback_basic(rName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair(resourceName, "snapshot_id",
+						"flexibleengine_blockstorage_snapshot.snapshot_1", "id"),
+					resource.TestCheckResourceAttrPair(resourceName, "volume_id",
+						"flexibleengine_blockstorage_volume_v2.volume_1", "id"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckBlockStorageSnapshotDestroy(s *terraform.State) error {
+	config := testAccProvider.Meta().(*Config)
+	blockStorageClient, err := config.BlockStorageV2Client(OS_REGION_NAME)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "flexibleengine_blockstorage_snapshot" {
+			continue
+		}
+
+		_, err := snapshots.Get(blockStorageClient, rs.Primary.ID).Extract()
+		if err == nil {
+			return fmt.Errorf("Volume snapshot still exists")
+		}
+	}
+
+	return nil
+}
+
+func testAccCheckBlockStorageSnapshotExists(n string, snapshot *snapshots.Snapshot) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No ID is set")
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		blockStorageClient, err := config.BlockStorageV2Client(OS_REGION_NAME)
+		if err != nil {
+			return fmt.Errorf("Error creating FlexibleEngine block storage client: %s", err)
+		}
+
+		found, err := snapshots.Get(blockStorageClient, rs.Primary.ID).Extract()
+		if err != nil {
+			return err
+		}
+
+		if found.ID != rs.Primary.ID {
+			return fmt.Errorf("Volume snapshot not found")
+		}
+
+		*snapshot = *found
+
+		return nil
+	}
+}
+
+func testAccBlockStorageSnapshot_base(rName string) string {
+	return fmt.Sprintf(`
+resource "flexibleengine_blockstorage_volume_v2" "volume_1" {
+  name = "%s"
+  size = 10
+}
+`, rName)
+}
+
+func testAccBlockStorageSnapshot_basic(rName string) string {
+	return fmt.Sprintf(`
+%s
+
+resource "flexibleengine_blockstorage_snapshot" "snapshot_1" {
+  volume_id   = flexibleengine_blockstorage_volume_v2.volume_1.id
+  name        = "%s"
+  description = "created by terraform"
+
+  metadata = {
+    foo = "bar"
+  }
+}
+`, testAccBlockStorageSnapshot_base(rName), rName)
+}
+
+func testAccBlockStorageSnapshot_update(rName string) string {
+	return fmt.Sprintf(`
+%s
+
+resource "flexibleengine_blockstorage_snapshot" "snapshot_1" {
+  volume_id   = flexibleengine_blockstorage_volume_v2.volume_1.id
+  name        = "%s-update"
+  description = "updated by terraform"
+
+  metadata = {
+    owner = "terraform"
+  }
+}
+`, testAccBlockStorageSnapshot_base(rName), rName)
+}
+
+func testAccBlockStorageSnapshotRollback_basic(rName string) string {
+	return fmt.Sprintf(`
+%s
+
+resource "flexibleengine_blockstorage_snapshot_rollback" "rollback" {
+  snapshot_id = flexibleengine_blockstorage_snapshot.snapshot_1.id
+  volume_id   = flexibleengine_blockstorage_volume_v2.volume_1.id
+}
+`, testAccBlockStorageSnapshot_basic(rName))
+}
+
+func TestMockBlockStorageSnapshot(t *testing.T) {
+	server, meta := newMockTest(t)
+	volume := newMockResourceDriver(t, "flexibleengine_blockstorage_volume_v2", meta)
+	volume.apply(map[string]interface{}{
+		"name":              "volume_1",
+		"size":              10,
+		"availability_zone": mockAZ,
+	})
+
+	driver := newMockResourceDriver(t, "flexibleengine_blockstorage_snapshot", meta)
+	raw := map[string]interface{}{
+		"volume_id":   volume.state.ID,
+		"name":        "snapshot_1",
+		"description": "created by terraform",
+		"metadata":    map[string]interface{}{"foo": "bar"},
+	}
+	driver.apply(raw)
+	th.AssertEquals(t, "available", driver.state.Attributes["status"])
+	th.AssertEquals(t, "10", driver.state.Attributes["size"])
+	th.AssertEquals(t, "bar", driver.state.Attributes["metadata.foo"])
+
+	snapshotID := driver.state.ID
+	raw["name"] = "snapshot_2"
+	raw["metadata"] = map[string]interface{}{"key": "value"}
+	driver.apply(raw)
+	th.AssertEquals(t, snapshotID, driver.state.ID)
+	th.AssertEquals(t, "snapshot_2", server.objects["snapshots"][snapshotID]["name"])
+	th.AssertDeepEquals(t, map[string]interface{}{"key": "value"}, server.objects["snapshots"][snapshotID]["metadata"])
+
+	imported := driver.importState(snapshotID)
+	th.AssertEquals(t, "snapshot_2", imported.Attributes["name"])
+	th.AssertEquals(t, volume.state.ID, imported.Attributes["volume_id"])
+
+	d := readMockDataSource(t, "flexibleengine_blockstorage_snapshots", meta, map[string]interface{}{
+		"volume_id": volume.state.ID,
+	})
+	th.AssertEquals(t, 1, d.Get("snapshots.#").(int))
+	th.AssertEquals(t, snapshotID, d.Get("snapshots.0.id").(string))
+	d = readMockDataSource(t, "flexibleengine_blockstorage_snapshots", meta, map[string]interface{}{
+		"status": "error",
+	})
+	th.AssertEquals(t, 0, d.Get("snapshots.#").(int))
+
+	rollback := newMockResourceDriver(t, "flexibleengine_blockstorage_snapshot_rollback", meta)
+	rollback.apply(map[string]interface{}{
+		"snapshot_id": snapshotID,
+		"volume_id":   volume.state.ID,
+	})
+	th.AssertEquals(t, snapshotID, server.objects["volumes"][volume.state.ID]["__snapshot_id"])
+	rollback.destroy()
+
+	driver.destroy()
+	th.AssertEquals(t, 0, len(server.objects["snapshots"]))
+	volume.destroy()
+}
@@ -2,6 +2,7 @@ package flexibleengine
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -25,8 +26,13 @@ func resourceBlockStorageVolumeV2() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		CustomizeDiff: func(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+			return customizeChargingModeDiff(d)
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
 			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
@@ -62,6 +68,11 @@ func resourceBlockStorageVolumeV2() *schema.Resource {
 				Computed: true,
 			},
 			"tags": tagsSchema(),
+
+			"charging_mode": schemaChargingMode(),
+			"period_unit":   schemaPeriodUnit(),
+			"period":        schemaPeriod(),
+			"auto_renew":    schemaAutoRenew(),
 			"snapshot_id": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -80,7 +91,6 @@ func resourceBlockStorageVolumeV2() *schema.Resource {
 			"volume_type": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 			},
 			"consistency_group_id": {
@@ -179,7 +189,7 @@ func resourceBlockStorageVolumeV2Create(d *schema.ResourceData, meta interface{}
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf(
 			"Error waiting for volume (%s) to become ready: %s",
@@ -198,6 +208,13 @@ func resourceBlockStorageVolumeV2Create(d *schema.ResourceData, meta interface{}
 		}
 	}
 
+	// the volume is created in pay-per-use mode and then changed to prepaid
+	if isPrePaid(d) {
+		if err := changeToPrePaid(d, config, []string{v.ID}, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return err
+		}
+	}
+
 	return resourceBlockStorageVolumeV2Read(d, meta)
 }
 
@@ -225,13 +242,23 @@ func resourceBlockStorageVolumeV2Read(d *schema.ResourceData, meta interface{})
 	//flexibleengine will add metadata 'billing=1' additionally, so remove the metadata 'billing' from response
 	m := make(map[string]string)
 	for key, val := range v.Metadata {
-		if key == "billing" {
+		if key == "billing" || key == "orderID" {
 			continue
 		}
 		m[key] = val
 	}
 	d.Set("metadata", m)
 
+	// the order ID is added to the metadata of the prepaid volume
+	if v.Metadata["orderID"] != "" {
+		d.Set("charging_mode", chargingModePrePaid)
+		if err := setPrePaidInfo(d, config, d.Id()); err != nil {
+			return err
+		}
+	} else {
+		d.Set("charging_mode", chargingModePostPaid)
+	}
+
 	d.Set("region", GetRegion(d, config))
 
 	attachments := make([]map[string]interface{}, len(v.Attachments))
@@ -298,13 +325,19 @@ func resourceBlockStorageVolumeV2Update(d *schema.ResourceData, meta interface{}
 			MinTimeout: 3 * time.Second,
 		}
 
-		_, err := stateConf.WaitForState()
+		_, err := waitForState(config, stateConf)
 		if err != nil {
 			return fmt.Errorf(
 				"Error waiting for flexibleengine_blockstorage_volume_v2 %s to become ready: %s", d.Id(), err)
 		}
 	}
 
+	if d.HasChange("volume_type") {
+		if err := retypeBlockStorageVolumeV2(config, d, blockStorageClient); err != nil {
+			return err
+		}
+	}
+
 	// update tags
 	if d.HasChange("tags") {
 		tagErr := UpdateResourceTags(blockStorageClient, d, "os-vendor-volumes", d.Id())
@@ -313,9 +346,76 @@ func resourceBlockStorageVolumeV2Update(d *schema.ResourceData, meta interface{}
 		}
 	}
 
+	if d.HasChanges("charging_mode", "auto_renew") {
+		if err := updateChargingMode(d, config, []string{d.Id()}); err != nil {
+			return err
+		}
+	}
+
 	return resourceBlockStorageVolumeV2Read(d, meta)
 }
 
+// retypeBlockStorageVolumeV2 changes the type of the volume, the data is migrated to the
+// new type by the cloud and the volume can stay attached to the instance.
+func retypeBlockStorageVolumeV2(config *Config, d *schema.ResourceData, client *golangsdk.ServiceClient) error {
+	newType := d.Get("volume_type").(string)
+	retypeOpts := map[string]interface{}{
+		"os-retype": map[string]interface{}{
+			"new_type":         newType,
+			"migration_policy": "on-demand",
+		},
+	}
+	log.Printf("[DEBUG] Retyping volume %s: %#v", d.Id(), retypeOpts)
+
+	r := golangsdk.Result{}
+	_, r.Err = client.Post(client.ServiceURL("volumes", d.Id(), "action"), retypeOpts, nil,
+		&golangsdk.RequestOpts{OkCodes: []int{202}})
+	if r.Err != nil {
+		return fmt.Errorf("Error changing the type of volume %s to %s: %s", d.Id(), newType, r.Err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"retyping"},
+		Target:     []string{"available", "in-use"},
+		Refresh:    volumeV2RetypeRefreshFunc(client, d.Id(), newType),
+		Timeout:    d.Timeout(schema.TimeoutUpdate),
+		Delay:      10 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error waiting for the type of volume %s to change to %s: %s", d.Id(), newType, err)
+	}
+	return nil
+}
+
+// volumeV2RetypeRefreshFunc returns "retyping" until the data of the volume is migrated to the new type.
+// The failed retype puts the volume back to its status with the old type, which is an error once the
+// retyping status has been seen.
+func volumeV2RetypeRefreshFunc(client *golangsdk.ServiceClient, volumeID, newType string) resource.StateRefreshFunc {
+	var started bool
+	return func() (interface{}, string, error) {
+		v, err := volumes.Get(client, volumeID).Extract()
+		if err != nil {
+			return nil, "", err
+		}
+
+		if v.Status == "error" {
+			return v, v.Status, fmt.Errorf("the volume is in error status")
+		}
+		if v.Status == "retyping" {
+			started = true
+		}
+		if v.VolumeType != newType {
+			if started && (v.Status == "available" || v.Status == "in-use") {
+				return v, v.Status, fmt.Errorf("the volume is %s with type %s, the retype failed", v.Status, v.VolumeType)
+			}
+			return v, "retyping", nil
+		}
+		return v, v.Status, nil
+	}
+}
+
 func resourceBlockStorageVolumeV2Delete(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 	blockStorageClient, err := config.BlockStorageV2Client(GetRegion(d, config))
@@ -350,7 +450,7 @@ func resourceBlockStorageVolumeV2Delete(d *schema.ResourceData, meta interface{}
 				MinTimeout: 3 * time.Second,
 			}
 
-			_, err = stateConf.WaitForState()
+			_, err = waitForState(config, stateConf)
 			if err != nil {
 				return fmt.Errorf(
 					"Error waiting for volume (%s) to become available: %s",
@@ -367,7 +467,11 @@ func resourceBlockStorageVolumeV2Delete(d *schema.ResourceData, meta interface{}
 	// in a "deleting" state from when the instance was terminated.
 	// If this is true, just move on. It'll eventually delete.
 	if v.Status != "deleting" {
-		if err := volumes.Delete(blockStorageClient, d.Id(), deleteOpts).ExtractErr(); err != nil {
+		if isPrePaid(d) {
+			if err := unsubscribePrePaid(d, config, []string{d.Id()}); err != nil {
+				return err
+			}
+		} else if err := volumes.Delete(blockStorageClient, d.Id(), deleteOpts).ExtractErr(); err != nil {
 			return CheckDeleted(d, err, "volume")
 		}
 	}
@@ -384,7 +488,7 @@ func resourceBlockStorageVolumeV2Delete(d *schema.ResourceData, meta interface{}
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf(
 			"Error waiting for volume (%s) to delete: %s",
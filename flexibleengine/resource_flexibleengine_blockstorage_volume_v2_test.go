@@ -1,7 +1,9 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/chnsz/golangsdk"
 	"github.com/chnsz/golangsdk/openstack/blockstorage/v2/volumes"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccBlockStorageV2Volume_basic(t *testing.T) {
@@ -78,6 +81,34 @@ func TestAccBlockStorageV2Volume_online_resize(t *testing.T) {
 	})
 }
 
+func TestAccBlockStorageV2Volume_retype(t *testing.T) {
+	var volume volumes.Volume
+	resourceName := "flexibleengine_blockstorage_volume_v2.volume_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBlockStorageV2VolumeDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBlockStorageV2Volume_retype("SATA"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageV2VolumeExists(resourceName, &volume),
+					resource.TestCheckResourceAttr(resourceName, "volume_type", "SATA"),
+				),
+			},
+			{
+				Config: testAccBlockStorageV2Volume_retype("SSD"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageV2VolumeExists(resourceName, &volume),
+					resource.TestCheckResourceAttr(resourceName, "volume_type", "SSD"),
+					resource.TestCheckResourceAttr(resourceName, "attachment.#", "1"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccBlockStorageV2Volume_image(t *testing.T) {
 	var volume volumes.Volume
 
@@ -98,6 +129,41 @@ func TestAccBlockStorageV2Volume_image(t *testing.T) {
 	})
 }
 
+func TestAccBlockStorageV2Volume_prePaid(t *testing.T) {
+	var volume volumes.Volume
+	resourceName := "flexibleengine_blockstorage_volume_v2.volume_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBlockStorageV2VolumeDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBlockStorageV2Volume_basic,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageV2VolumeExists(resourceName, &volume),
+				),
+			},
+			{
+				Config: testAccBlockStorageV2Volume_prePaid,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBlockStorageV2VolumeExists(resourceName, &volume),
+					resource.TestCheckResourceAttr(resourceName, "charging_mode", "prePaid"),
+					resource.TestCheckResourceAttr(resourceName, "period_unit", "month"),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"cascade",
+				},
+			},
+		},
+	})
+}
+
 func testAccCheckBlockStorageV2VolumeDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	blockStorageClient, err := config.BlockStorageV2Client(OS_REGION_NAME)
@@ -246,3 +312,144 @@ resource "flexibleengine_blockstorage_volume_v2" "volume_1" {
   image_id = "%s"
 }
 `, OS_IMAGE_ID)
+
+const testAccBlockStorageV2Volume_prePaid = `
+resource "flexibleengine_blockstorage_volume_v2" "volume_1" {
+  name        = "volume_1"
+  description = "first test volume"
+  size        = 10
+
+  tags = {
+    foo = "bar"
+    key = "value"
+  }
+
+  charging_mode = "prePaid"
+  period_unit   = "month"
+  period        = 1
+}
+`
+
+func testAccBlockStorageV2Volume_retype(volumeType string) string {
+	return fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "basic" {
+  name            = "instance_1"
+  security_groups = ["default"]
+  network {
+    uuid = "%s"
+  }
+}
+
+resource "flexibleengine_blockstorage_volume_v2" "volume_1" {
+  name        = "volume_1"
+  size        = 10
+  volume_type = "%s"
+}
+
+resource "flexibleengine_compute_volume_attach_v2" "va_1" {
+  instance_id = flexibleengine_compute_instance_v2.basic.id
+  volume_id   = flexibleengine_blockstorage_volume_v2.volume_1.id
+}
+`, OS_NETWORK_ID, volumeType)
+}
+
+func TestMockBlockStorageVolumeV2(t *testing.T) {
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_blockstorage_volume_v2",
+		steps: []mockResourceStep{
+			{
+				config: map[string]interface{}{
+					"name":              "volume_1",
+					"size":              10,
+					"volume_type":       "SSD",
+					"availability_zone": mockAZ,
+					"tags":              map[string]interface{}{"owner": "terraform"},
+				},
+				attrs: map[string]string{"tags.owner": "terraform"},
+			},
+		},
+		importAttrs: []string{"name", "size"},
+		collections: []string{"volumes"},
+	})
+}
+
+func TestMockBlockStorageVolumeV2_retype(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_blockstorage_volume_v2", meta)
+
+	raw := map[string]interface{}{
+		"name":              "volume_1",
+		"size":              10,
+		"volume_type":       "SATA",
+		"availability_zone": mockAZ,
+	}
+	driver.apply(raw)
+	volumeID := driver.state.ID
+
+	raw["volume_type"] = "SSD"
+	raw["size"] = 20
+	diff := driver.plan(raw)
+	th.AssertEquals(t, false, diff.RequiresNew())
+	driver.apply(raw)
+	th.AssertEquals(t, volumeID, driver.state.ID)
+	th.AssertEquals(t, "SSD", driver.state.Attributes["volume_type"])
+	th.AssertEquals(t, "SSD", server.objects["volumes"][volumeID]["volume_type"])
+	th.AssertEquals(t, "20", driver.state.Attributes["size"])
+
+	// the failed retype puts the volume back to the old type, which fails the update at once
+	server.objects["volumes"][volumeID]["retype_fails"] = true
+	raw["volume_type"] = "SAS"
+	_, diags := driver.resource.Apply(context.Background(), driver.state, driver.plan(raw), meta)
+	th.AssertEquals(t, true, diags.HasError())
+	th.AssertEquals(t, true, strings.Contains(diags[0].Summary, "the retype failed"))
+	th.AssertEquals(t, "SSD", server.objects["volumes"][volumeID]["volume_type"])
+	th.AssertEquals(t, "available", server.objects["volumes"][volumeID]["status"])
+
+	driver.destroy()
+}
+
+func TestMockBlockStorageVolumeV2_prePaid(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_blockstorage_volume_v2", meta)
+
+	config := map[string]interface{}{
+		"name":              "volume_1",
+		"size":              10,
+		"volume_type":       "SSD",
+		"availability_zone": mockAZ,
+	}
+	driver.apply(config)
+	id := driver.state.ID
+
+	// the pay-per-use volume is changed to prepaid in place
+	config["charging_mode"] = "prePaid"
+	config["period_unit"] = "month"
+	config["period"] = 3
+	config["auto_renew"] = "true"
+	th.AssertEquals(t, false, driver.plan(config).RequiresNew())
+	driver.apply(config)
+	th.AssertEquals(t, id, driver.state.ID)
+
+	volume := server.objects["volumes"][id]
+	th.AssertEquals(t, "1", volume["charging_mode"])
+	th.AssertEquals(t, float64(2), volume["period_type"])
+	th.AssertEquals(t, float64(3), volume["period_num"])
+	th.AssertEquals(t, true, volume["auto_renew"])
+
+	config["auto_renew"] = "false"
+	driver.apply(config)
+	th.AssertEquals(t, false, volume["auto_renew"])
+
+	// the billing info is read from the order of the volume
+	th.AssertEquals(t, "prePaid", driver.state.Attributes["charging_mode"])
+	th.AssertEquals(t, "false", driver.state.Attributes["auto_renew"])
+	th.AssertEquals(t, 0, len(driver.state.Attributes["metadata.orderID"]))
+	imported := driver.importState(id)
+	for _, k := range []string{"charging_mode", "period_unit", "period"} {
+		th.AssertEquals(t, driver.state.Attributes[k], imported.Attributes[k])
+	}
+
+	// the prepaid volume is unsubscribed
+	driver.destroy()
+	th.AssertEquals(t, 0, len(server.objects["volumes"]))
+}
@@ -1,6 +1,7 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -9,22 +10,26 @@ import (
 	"github.com/chnsz/golangsdk"
 	"github.com/chnsz/golangsdk/openstack/cce/v3/clusters"
 	"github.com/chnsz/golangsdk/openstack/networking/v1/eips"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 func resourceCCEClusterV3() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceCCEClusterV3Create,
-		Read:   resourceCCEClusterV3Read,
-		Update: resourceCCEClusterV3Update,
-		Delete: resourceCCEClusterV3Delete,
+		Create:        resourceCCEClusterV3Create,
+		Read:          resourceCCEClusterV3Read,
+		UpdateContext: resourceCCEClusterV3Update,
+		Delete:        resourceCCEClusterV3Delete,
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(60 * time.Minute),
 			Delete: schema.DefaultTimeout(30 * time.Minute),
 		},
 
@@ -59,7 +64,26 @@ func resourceCCEClusterV3() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
+			},
+			"upgrade_strategy": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"step": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      20,
+							ValidateFunc: validation.IntBetween(1, 40),
+						},
+						"node_pool_order": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeInt},
+						},
+					},
+				},
 			},
 			"cluster_type": {
 				Type:     schema.TypeString,
@@ -358,12 +382,12 @@ func resourceCCEClusterV3Create(d *schema.ResourceData, meta interface{}) error
 		PollInterval: 10 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	d.SetId(create.Metadata.Id)
 
 	// create a hibernating cluster
 	if d.Get("hibernate").(bool) {
-		err = resourceClusterHibernate(d, cceClient)
+		err = resourceClusterHibernate(config, d, cceClient)
 		if err != nil {
 			return err
 		}
@@ -462,11 +486,11 @@ func resourceCCEClusterV3Read(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func resourceCCEClusterV3Update(d *schema.ResourceData, meta interface{}) error {
+func resourceCCEClusterV3Update(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*Config)
 	cceClient, err := config.CceV3Client(GetRegion(d, config))
 	if err != nil {
-		return fmt.Errorf("Error creating flexibleengine CCE Client: %s", err)
+		return diag.Errorf("Error creating flexibleengine CCE Client: %s", err)
 	}
 
 	if d.HasChange("description") {
@@ -475,7 +499,7 @@ func resourceCCEClusterV3Update(d *schema.ResourceData, meta interface{}) error
 		_, err = clusters.Update(cceClient, d.Id(), updateOpts).Extract()
 
 		if err != nil {
-			return fmt.Errorf("Error updating flexibleengine CCE: %s", err)
+			return diag.Errorf("Error updating flexibleengine CCE: %s", err)
 		}
 	}
 
@@ -485,46 +509,65 @@ func resourceCCEClusterV3Update(d *schema.ResourceData, meta interface{}) error
 		_, err = clusters.Update(cceClient, d.Id(), updateOpts).Extract()
 
 		if err != nil {
-			return fmt.Errorf("Error updating flexibleengine CCE: %s", err)
+			return diag.Errorf("Error updating flexibleengine CCE: %s", err)
 		}
 	}
 
 	if d.HasChange("eip") {
 		eipClient, err := config.NetworkingV1Client(config.GetRegion(d))
 		if err != nil {
-			return fmt.Errorf("error creating networking client: %s", err)
+			return diag.Errorf("error creating networking client: %s", err)
 		}
 
 		oldEip, newEip := d.GetChange("eip")
 		if oldEip.(string) != "" {
 			err = resourceCCEClusterV3EipAction(cceClient, eipClient, d.Id(), oldEip.(string), "unbind")
 			if err != nil {
-				return err
+				return diag.FromErr(err)
 			}
 		}
 		if newEip.(string) != "" {
 			err = resourceCCEClusterV3EipAction(cceClient, eipClient, d.Id(), newEip.(string), "bind")
 			if err != nil {
-				return err
+				return diag.FromErr(err)
 			}
 		}
 	}
 
-	if d.HasChange("hibernate") {
-		if d.Get("hibernate").(bool) {
-			err = resourceClusterHibernate(d, cceClient)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = resourceClusterAwake(d, cceClient)
-			if err != nil {
-				return err
+	// the cluster must be available to be upgraded, so the hibernated cluster is awaked before upgrading
+	// and hibernated again after upgrading
+	oldHibernate, newHibernate := d.GetChange("hibernate")
+	wasHibernated, hibernate := oldHibernate.(bool), newHibernate.(bool)
+	upgrade := d.HasChange("cluster_version")
+	if wasHibernated && (!hibernate || upgrade) {
+		err = resourceClusterAwake(config, d, cceClient)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if upgrade {
+		if diags := resourceClusterUpgrade(config, d, cceClient); diags.HasError() {
+			// the old version is kept in the state so that the upgrade is planned again
+			oldVersion, _ := d.GetChange("cluster_version")
+			d.Set("cluster_version", oldVersion)
+			if wasHibernated && hibernate {
+				if err := resourceClusterHibernate(config, d, cceClient); err != nil {
+					log.Printf("[WARN] error hibernating CCE cluster (%s) after the failed upgrade: %s", d.Id(), err)
+				}
 			}
+			return diags
 		}
 	}
 
-	return resourceCCEClusterV3Read(d, meta)
+	if hibernate && (!wasHibernated || upgrade) {
+		err = resourceClusterHibernate(config, d, cceClient)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diag.FromErr(resourceCCEClusterV3Read(d, meta))
 }
 
 func resourceCCEClusterV3Delete(d *schema.ResourceData, meta interface{}) error {
@@ -547,7 +590,7 @@ func resourceCCEClusterV3Delete(d *schema.ResourceData, meta interface{}) error
 		PollInterval: 10 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 
 	if err != nil {
 		return fmt.Errorf("Error deleting flexibleengine CCE cluster: %s", err)
@@ -602,7 +645,7 @@ func getEipIDbyAddress(client *golangsdk.ServiceClient, address string) (string,
 	return allEips[0].ID, nil
 }
 
-func resourceClusterHibernate(d *schema.ResourceData, cceClient *golangsdk.ServiceClient) error {
+func resourceClusterHibernate(config *Config, d *schema.ResourceData, cceClient *golangsdk.ServiceClient) error {
 	clusterID := d.Id()
 	err := clusters.Operation(cceClient, clusterID, "hibernate").ExtractErr()
 	if err != nil {
@@ -620,14 +663,14 @@ func resourceClusterHibernate(d *schema.ResourceData, cceClient *golangsdk.Servi
 		PollInterval: 20 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("error hibernating CCE cluster: %s", err)
 	}
 	return nil
 }
 
-func resourceClusterAwake(d *schema.ResourceData, cceClient *golangsdk.ServiceClient) error {
+func resourceClusterAwake(config *Config, d *schema.ResourceData, cceClient *golangsdk.ServiceClient) error {
 	clusterID := d.Id()
 	err := clusters.Operation(cceClient, clusterID, "awake").ExtractErr()
 	if err != nil {
@@ -645,13 +688,212 @@ func resourceClusterAwake(d *schema.ResourceData, cceClient *golangsdk.ServiceCl
 		PollInterval: 20 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("error awaking CCE cluster: %s", err)
 	}
 	return nil
 }
 
+// clusterCheckItem is the result of a pre-upgrade check item
+type clusterCheckItem struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// clusterUpgradeTask is the pre-upgrade check task or the upgrade task of a cluster
+type clusterUpgradeTask struct {
+	Metadata struct {
+		UID string `json:"uid"`
+	} `json:"metadata"`
+	Status struct {
+		Phase              string `json:"phase"`
+		Message            string `json:"message"`
+		ClusterCheckResult struct {
+			CheckItemStatus []clusterCheckItem `json:"checkItemStatus"`
+		} `json:"clusterCheckResult"`
+		NodeCheckResult struct {
+			NodeCheckStatus []struct {
+				NodeID          string             `json:"nodeID"`
+				CheckItemStatus []clusterCheckItem `json:"checkItemStatus"`
+			} `json:"nodeCheckStatus"`
+		} `json:"nodeCheckResult"`
+	} `json:"status"`
+}
+
+// resourceClusterUpgrade checks the cluster, upgrades the control plane and then upgrades
+// the nodes in batches, the failed check items are reported as diagnostics.
+func resourceClusterUpgrade(config *Config, d *schema.ResourceData,
+	cceClient *golangsdk.ServiceClient) diag.Diagnostics {
+	clusterID := d.Id()
+	oldVersion, newVersion := d.GetChange("cluster_version")
+	targetVersion := newVersion.(string)
+
+	preCheckOpts := map[string]interface{}{
+		"apiVersion": "v3",
+		"kind":       "PreCheckTask",
+		"spec": map[string]interface{}{
+			"clusterID":      clusterID,
+			"clusterVersion": oldVersion.(string),
+			"targetVersion":  targetVersion,
+		},
+	}
+	log.Printf("[DEBUG] Checking CCE cluster (%s) before upgrading to %s", clusterID, targetVersion)
+	task, err := createClusterUpgradeTask(cceClient, clusterID, "precheck", preCheckOpts)
+	if err != nil {
+		return diag.Errorf("error checking CCE cluster before upgrading: %s", err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		// the failed check items are reported after the check
+		Pending:      []string{"Init", "Running"},
+		Target:       []string{"Success", "Failed"},
+		Refresh:      clusterUpgradeTaskRefreshFunc(cceClient, clusterID, "precheck", task.Metadata.UID, "Error"),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        10 * time.Second,
+		PollInterval: 10 * time.Second,
+	}
+	result, err := waitForState(config, stateConf)
+	if err != nil {
+		return diag.Errorf("error checking CCE cluster before upgrading: %s", err)
+	}
+	if diags := clusterPreCheckDiagnostics(result.(*clusterUpgradeTask)); diags.HasError() {
+		return diags
+	}
+
+	step := 20
+	if v, ok := d.GetOk("upgrade_strategy.0.step"); ok {
+		step = v.(int)
+	}
+	upgradeAction := map[string]interface{}{
+		"targetVersion": targetVersion,
+		"strategy": map[string]interface{}{
+			"type": "inPlaceRollingUpdate",
+			"inPlaceRollingUpdate": map[string]interface{}{
+				"userDefinedStep": step,
+			},
+		},
+	}
+	if v, ok := d.GetOk("upgrade_strategy.0.node_pool_order"); ok {
+		upgradeAction["nodePoolOrder"] = v.(map[string]interface{})
+	}
+	upgradeOpts := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"apiVersion": "v3",
+			"kind":       "UpgradeTask",
+		},
+		"spec": map[string]interface{}{
+			"clusterUpgradeAction": upgradeAction,
+		},
+	}
+	log.Printf("[DEBUG] Upgrading CCE cluster (%s): %#v", clusterID, upgradeOpts)
+	task, err = createClusterUpgradeTask(cceClient, clusterID, "upgrade", upgradeOpts)
+	if err != nil {
+		return diag.Errorf("error upgrading CCE cluster: %s", err)
+	}
+
+	stateConf = &resource.StateChangeConf{
+		// the upgrade task is paused between the batches of the nodes
+		Pending:      []string{"Init", "Queuing", "Running", "Pause"},
+		Target:       []string{"Success"},
+		Refresh:      clusterUpgradeTaskRefreshFunc(cceClient, clusterID, "upgrade", task.Metadata.UID, "Failed"),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        30 * time.Second,
+		PollInterval: 20 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return diag.Errorf("error upgrading CCE cluster: %s", err)
+	}
+
+	log.Printf("[DEBUG] Waiting for CCE cluster (%s) to become available", clusterID)
+	stateConf = &resource.StateChangeConf{
+		// The statuses of pending phase include "Upgrading".
+		Pending:      []string{"PENDING"},
+		Target:       []string{"COMPLETED"},
+		Refresh:      clusterStateRefreshFunc(cceClient, clusterID, []string{"Available"}),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		PollInterval: 10 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return diag.Errorf("error upgrading CCE cluster: %s", err)
+	}
+	return nil
+}
+
+func createClusterUpgradeTask(cceClient *golangsdk.ServiceClient, clusterID, kind string,
+	opts map[string]interface{}) (*clusterUpgradeTask, error) {
+	r := golangsdk.Result{}
+	_, r.Err = cceClient.Post(cceClient.ServiceURL("clusters", clusterID, "operation", kind), opts, &r.Body,
+		&golangsdk.RequestOpts{OkCodes: []int{200, 201}})
+
+	var task clusterUpgradeTask
+	if err := r.ExtractInto(&task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// clusterUpgradeTaskRefreshFunc returns the phase of the pre-upgrade check task or the
+// upgrade task, the failed phase is returned as an error with the message of the task.
+func clusterUpgradeTaskRefreshFunc(cceClient *golangsdk.ServiceClient, clusterID, kind, taskID,
+	failed string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		r := golangsdk.Result{}
+		_, r.Err = cceClient.Get(cceClient.ServiceURL("clusters", clusterID, "operation", kind, "tasks", taskID),
+			&r.Body, &golangsdk.RequestOpts{
+				OkCodes:     []int{200},
+				MoreHeaders: clusters.RequestOpts.MoreHeaders, JSONBody: nil,
+			})
+
+		var task clusterUpgradeTask
+		if err := r.ExtractInto(&task); err != nil {
+			return nil, "ERROR", err
+		}
+		if task.Status.Phase == failed {
+			return &task, task.Status.Phase, fmt.Errorf("the %s task %s failed: %s", kind, taskID, task.Status.Message)
+		}
+		return &task, task.Status.Phase, nil
+	}
+}
+
+func clusterPreCheckDiagnostics(task *clusterUpgradeTask) diag.Diagnostics {
+	var diags diag.Diagnostics
+	path := cty.GetAttrPath("cluster_version")
+	for _, item := range task.Status.ClusterCheckResult.CheckItemStatus {
+		if item.Status == "Failed" {
+			diags = append(diags, diag.Diagnostic{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("the pre-upgrade check %s failed", item.Name),
+				Detail:        item.Message,
+				AttributePath: path,
+			})
+		}
+	}
+	for _, node := range task.Status.NodeCheckResult.NodeCheckStatus {
+		for _, item := range node.CheckItemStatus {
+			if item.Status == "Failed" {
+				diags = append(diags, diag.Diagnostic{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("the pre-upgrade check %s failed on node %s", item.Name, node.NodeID),
+					Detail:        item.Message,
+					AttributePath: path,
+				})
+			}
+		}
+	}
+
+	if task.Status.Phase == "Failed" && len(diags) == 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Error,
+			Summary:       "the pre-upgrade check failed",
+			Detail:        task.Status.Message,
+			AttributePath: path,
+		})
+	}
+	return diags
+}
+
 func clusterStateRefreshFunc(cceClient *golangsdk.ServiceClient, clusterId string,
 	targets []string) resource.StateRefreshFunc {
 	return func() (interface{}, string, error) {
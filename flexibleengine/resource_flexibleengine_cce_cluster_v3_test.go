@@ -1,7 +1,9 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
@@ -9,6 +11,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/chnsz/golangsdk/openstack/cce/v3/clusters"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccCCEClusterV3_basic(t *testing.T) {
@@ -282,3 +285,128 @@ resource "flexibleengine_cce_cluster_v3" "test" {
 }
 `, testAccCCEClusterV3_Base(rName), rName)
 }
+
+func TestAccCCEClusterV3_upgrade(t *testing.T) {
+	var cluster clusters.Clusters
+
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_cce_cluster_v3.test"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: TestAccProviderFactories,
+		CheckDestroy:      testAccCheckCCEClusterV3Destroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCluster_upgrade(rName, "v1.23"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCEClusterV3Exists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "cluster_version", "v1.23"),
+				),
+			},
+			{
+				Config: testAccCluster_upgrade(rName, "v1.25"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCEClusterV3Exists(resourceName, &cluster),
+					resource.TestCheckResourceAttr(resourceName, "cluster_version", "v1.25"),
+					resource.TestCheckResourceAttr(resourceName, "status", "Available"),
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &cluster.Metadata.Id),
+				),
+			},
+		},
+	})
+}
+
+func testAccCluster_upgrade(rName, version string) string {
+	return fmt.Sprintf(`
+%s
+
+resource "flexibleengine_cce_cluster_v3" "test" {
+  name                   = "%s"
+  flavor_id              = "cce.s1.small"
+  cluster_type           = "VirtualMachine"
+  cluster_version        = "%s"
+  vpc_id                 = flexibleengine_vpc_v1.test.id
+  subnet_id              = flexibleengine_vpc_subnet_v1.test.id
+  container_network_type = "overlay_l2"
+
+  upgrade_strategy {
+    step = 10
+  }
+}
+`, testAccCCEClusterV3_Base(rName), rName, version)
+}
+
+func TestMockCCEClusterV3_upgrade(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.23")
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_cluster_v3", meta)
+	driver.state = driver.importState(clusterID)
+	th.AssertEquals(t, "v1.23", driver.state.Attributes["cluster_version"])
+
+	raw := map[string]interface{}{
+		"name":                   "cluster_1",
+		"flavor_id":              "cce.s1.small",
+		"cluster_type":           "VirtualMachine",
+		"cluster_version":        "v1.25",
+		"vpc_id":                 "vpc-0001",
+		"subnet_id":              "subnet-0001",
+		"container_network_type": "overlay_l2",
+		"upgrade_strategy": []interface{}{
+			map[string]interface{}{
+				"step":            10,
+				"node_pool_order": map[string]interface{}{"pool-0001": 1},
+			},
+		},
+	}
+	th.AssertEquals(t, false, driver.plan(raw).RequiresNew())
+	driver.apply(raw)
+	th.AssertEquals(t, clusterID, driver.state.ID)
+	th.AssertEquals(t, "v1.25", driver.state.Attributes["cluster_version"])
+
+	// the nodes are upgraded with the strategy
+	var action map[string]interface{}
+	for _, task := range server.objects["cce_tasks"] {
+		if spec := task["spec"].(map[string]interface{}); spec["strategy"] != nil {
+			action = spec
+		}
+	}
+	strategy := action["strategy"].(map[string]interface{})
+	th.AssertEquals(t, "inPlaceRollingUpdate", strategy["type"])
+	th.AssertEquals(t, float64(10), strategy["inPlaceRollingUpdate"].(map[string]interface{})["userDefinedStep"])
+	th.AssertDeepEquals(t, map[string]interface{}{"pool-0001": float64(1)}, action["nodePoolOrder"])
+
+	// the failed check items are reported as diagnostics
+	raw["cluster_version"] = "v1.23"
+	state, diags := driver.resource.Apply(context.Background(), driver.state, driver.plan(raw), meta)
+	th.AssertEquals(t, 1, len(diags))
+	th.AssertEquals(t, "the pre-upgrade check ClusterVersion failed", diags[0].Summary)
+	th.AssertEquals(t, "can not upgrade from v1.25 to v1.23", diags[0].Detail)
+	th.AssertEquals(t, "v1.25", server.objects["clusters"][clusterID]["spec"].(map[string]interface{})["version"])
+	// the old version is kept so that the upgrade is planned again
+	th.AssertEquals(t, "v1.25", state.Attributes["cluster_version"])
+
+	// the failed upgrade keeps the old version as well
+	server.objects["clusters"][clusterID]["__upgrade_failure"] = "the master nodes are not ready"
+	raw["cluster_version"] = "v1.27"
+	state, diags = driver.resource.Apply(context.Background(), driver.state, driver.plan(raw), meta)
+	th.AssertEquals(t, true, diags.HasError())
+	th.AssertEquals(t, true, strings.Contains(diags[0].Summary, "the master nodes are not ready"))
+	th.AssertEquals(t, "v1.25", state.Attributes["cluster_version"])
+	driver.state = state
+	th.AssertEquals(t, false, driver.plan(raw).Empty())
+
+	// the hibernated cluster is awaked for the upgrade and hibernated again
+	delete(server.objects["clusters"][clusterID], "__upgrade_failure")
+	raw["cluster_version"] = "v1.25"
+	raw["hibernate"] = true
+	driver.apply(raw)
+	th.AssertEquals(t, "Hibernation", server.objects["clusters"][clusterID]["status"].(map[string]interface{})["phase"])
+
+	raw["cluster_version"] = "v1.27"
+	driver.apply(raw)
+	th.AssertEquals(t, "v1.27", driver.state.Attributes["cluster_version"])
+	th.AssertEquals(t, "v1.27", server.objects["clusters"][clusterID]["spec"].(map[string]interface{})["version"])
+	th.AssertEquals(t, "Hibernation", server.objects["clusters"][clusterID]["status"].(map[string]interface{})["phase"])
+}
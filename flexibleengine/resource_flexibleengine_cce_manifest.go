@@ -0,0 +1,504 @@
+package flexibleengine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v2"
+)
+
+// cceManifestFieldManager is the field manager of the server-side apply requests
+const cceManifestFieldManager = "terraform-provider-flexibleengine"
+
+func resourceCCEManifest() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceCCEManifestCreate,
+		ReadContext:   resourceCCEManifestRead,
+		UpdateContext: resourceCCEManifestUpdate,
+		DeleteContext: resourceCCEManifestDelete,
+		CustomizeDiff: resourceCCEManifestCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"cluster_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// the state keeps the fields of the manifest with the values in the cluster, see resourceCCEManifestRead
+			"yaml_body": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Sensitive:        true,
+				ValidateFunc:     validateCCEManifest,
+				DiffSuppressFunc: suppressEquivalentCCEManifest,
+			},
+			"force_conflicts": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"insecure_skip_tls_verify": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"api_version": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"kind": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"uid": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// cceManifest is a Kubernetes object in the manifest
+type cceManifest map[string]interface{}
+
+func (m cceManifest) apiVersion() string {
+	v, _ := m["apiVersion"].(string)
+	return v
+}
+
+func (m cceManifest) kind() string {
+	v, _ := m["kind"].(string)
+	return v
+}
+
+func (m cceManifest) metadata(key string) string {
+	metadata, _ := m["metadata"].(map[string]interface{})
+	v, _ := metadata[key].(string)
+	return v
+}
+
+// parseCCEManifest parses the YAML document of a Kubernetes object, the values are converted to
+// the types of the JSON responses of the Kubernetes API.
+func parseCCEManifest(body string) (cceManifest, error) {
+	var docs []interface{}
+	decoder := yaml.NewDecoder(strings.NewReader(body))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the manifest: %s", err)
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) != 1 {
+		return nil, fmt.Errorf("the manifest must contain exactly one YAML document, got %d", len(docs))
+	}
+
+	data, err := json.Marshal(convertYAMLToJSONValue(docs[0]))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the manifest: %s", err)
+	}
+	var manifest cceManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("the manifest must be a Kubernetes object: %s", err)
+	}
+
+	if manifest.apiVersion() == "" || manifest.kind() == "" || manifest.metadata("name") == "" {
+		return nil, fmt.Errorf("apiVersion, kind and metadata.name are required in the manifest")
+	}
+	return manifest, nil
+}
+
+// convertYAMLToJSONValue converts the maps decoded by yaml.v2 to the maps with string keys
+func convertYAMLToJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[fmt.Sprint(key)] = convertYAMLToJSONValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = convertYAMLToJSONValue(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func validateCCEManifest(v interface{}, k string) ([]string, []error) {
+	if _, err := parseCCEManifest(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%q is invalid: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// suppressEquivalentCCEManifest ignores the differences of the formats and the order of the keys
+func suppressEquivalentCCEManifest(_, old, new string, _ *schema.ResourceData) bool {
+	oldManifest, err := parseCCEManifest(old)
+	if err != nil {
+		return false
+	}
+	newManifest, err := parseCCEManifest(new)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(oldManifest, newManifest)
+}
+
+// projectCCEManifest keeps the fields of the desired object in the live object,
+// the lists with different lengths are kept as a whole.
+func projectCCEManifest(desired, live interface{}) interface{} {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		result := make(map[string]interface{})
+		for key, item := range desiredValue {
+			if liveItem, ok := liveValue[key]; ok {
+				result[key] = projectCCEManifest(item, liveItem)
+			}
+		}
+		return result
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) != len(desiredValue) {
+			return live
+		}
+		result := make([]interface{}, len(liveValue))
+		for i, item := range desiredValue {
+			result[i] = projectCCEManifest(item, liveValue[i])
+		}
+		return result
+	default:
+		return live
+	}
+}
+
+// projectCCEManifestWriteOnlyFields refreshes the write-only fields of the desired object, which are
+// never returned by the Kubernetes API, with the fields where their values are stored. The stringData
+// of the Secrets is merged into data with the values encoded in base64.
+func projectCCEManifestWriteOnlyFields(desired, live, projected cceManifest) {
+	stringData, ok := desired["stringData"].(map[string]interface{})
+	if desired.kind() != "Secret" || !ok {
+		return
+	}
+
+	liveData, _ := live["data"].(map[string]interface{})
+	result := make(map[string]interface{})
+	for key := range stringData {
+		// the keys removed out of Terraform are planned to be added again
+		encoded, ok := liveData[key].(string)
+		if !ok {
+			continue
+		}
+		if value, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			result[key] = string(value)
+		}
+	}
+	projected["stringData"] = result
+}
+
+// resourceCCEManifestCustomizeDiff plans the identity of the object in the manifest,
+// the object is replaced when its apiVersion, kind, namespace or name is changed.
+func resourceCCEManifestCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if !d.NewValueKnown("yaml_body") {
+		return nil
+	}
+	manifest, err := parseCCEManifest(d.Get("yaml_body").(string))
+	if err != nil {
+		return err
+	}
+
+	namespace := manifest.metadata("namespace")
+	if d.Id() != "" {
+		// the namespace of the cluster-scoped objects is ignored, and the namespaced objects
+		// without namespace are in the default namespace
+		oldNamespace := d.Get("namespace").(string)
+		namespaceChanged := oldNamespace != "" && namespace != oldNamespace &&
+			!(namespace == "" && oldNamespace == "default")
+		if namespaceChanged || manifest.apiVersion() != d.Get("api_version").(string) ||
+			manifest.kind() != d.Get("kind").(string) || manifest.metadata("name") != d.Get("name").(string) {
+			if err := d.ForceNew("yaml_body"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, value := range map[string]string{
+		"api_version": manifest.apiVersion(),
+		"kind":        manifest.kind(),
+		"name":        manifest.metadata("name"),
+	} {
+		if d.Get(key).(string) == value {
+			continue
+		}
+		if err := d.SetNew(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type cceAPIResourceList struct {
+	Resources []struct {
+		Name       string `json:"name"`
+		Namespaced bool   `json:"namespaced"`
+		Kind       string `json:"kind"`
+	} `json:"resources"`
+}
+
+// cceManifestPath discovers the resource of the kind and returns the path and the namespace of the object,
+// the namespace is empty for the cluster-scoped objects.
+func cceManifestPath(kubeClient *cceKubernetesClient, apiVersion, kind, namespace, name string) (string, string, error) {
+	groupPath := "/apis/" + apiVersion
+	if !strings.Contains(apiVersion, "/") {
+		groupPath = "/api/" + apiVersion
+	}
+
+	var resourceList cceAPIResourceList
+	if err := kubeClient.Do(http.MethodGet, groupPath, "", nil, &resourceList); err != nil {
+		if isCCEKubernetesStatus(err, http.StatusNotFound) {
+			return "", "", fmt.Errorf("the API version %s is not served by the cluster", apiVersion)
+		}
+		return "", "", err
+	}
+	for _, r := range resourceList.Resources {
+		// the subresources are also listed, e.g. pods/status
+		if r.Kind != kind || strings.Contains(r.Name, "/") {
+			continue
+		}
+		if !r.Namespaced {
+			return fmt.Sprintf("%s/%s/%s", groupPath, r.Name, url.PathEscape(name)), "", nil
+		}
+		if namespace == "" {
+			namespace = "default"
+		}
+		return fmt.Sprintf("%s/namespaces/%s/%s/%s", groupPath, url.PathEscape(namespace), r.Name,
+			url.PathEscape(name)), namespace, nil
+	}
+	return "", "", fmt.Errorf("the kind %s is not served by the API version %s", kind, apiVersion)
+}
+
+func newCCEManifestKubernetesClient(d *schema.ResourceData, meta interface{}) (*cceKubernetesClient, error) {
+	config := meta.(*Config)
+	cceClient, err := config.CceV3Client(GetRegion(d, config))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating flexibleengine CCE client: %s", err)
+	}
+	return getCCEKubernetesClient(config, cceClient, d.Get("cluster_id").(string),
+		d.Get("insecure_skip_tls_verify").(bool))
+}
+
+// applyCCEManifest applies the manifest with server-side apply, the fields which are removed from
+// the manifest are also removed from the object.
+func applyCCEManifest(ctx context.Context, d *schema.ResourceData, meta interface{}, timeout time.Duration) error {
+	kubeClient, err := newCCEManifestKubernetesClient(d, meta)
+	if err != nil {
+		return err
+	}
+	manifest, err := parseCCEManifest(d.Get("yaml_body").(string))
+	if err != nil {
+		return err
+	}
+
+	// the kind may be defined by a CustomResourceDefinition which is not established yet
+	var path, namespace string
+	err = resource.RetryContext(ctx, timeout, func() *resource.RetryError {
+		var err error
+		path, namespace, err = cceManifestPath(kubeClient, manifest.apiVersion(), manifest.kind(),
+			manifest.metadata("namespace"), manifest.metadata("name"))
+		if err != nil {
+			if _, ok := err.(*cceKubernetesStatusError); ok {
+				return resource.NonRetryableError(err)
+			}
+			return resource.RetryableError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"fieldManager": []string{cceManifestFieldManager},
+		"force":        []string{fmt.Sprint(d.Get("force_conflicts").(bool))},
+	}
+	var result cceManifest
+	err = kubeClient.Do(http.MethodPatch, path+"?"+query.Encode(), "application/apply-patch+yaml", manifest, &result)
+	if err != nil {
+		return fmt.Errorf("error applying %s %s: %s", manifest.kind(), manifest.metadata("name"), err)
+	}
+	log.Printf("[DEBUG] Applied %s %s in CCE cluster %s", manifest.kind(), path, d.Get("cluster_id"))
+
+	d.SetId(result.metadata("uid"))
+	d.Set("api_version", manifest.apiVersion())
+	d.Set("kind", manifest.kind())
+	d.Set("name", manifest.metadata("name"))
+	d.Set("namespace", namespace)
+	return nil
+}
+
+func resourceCCEManifestCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if err := applyCCEManifest(ctx, d, meta, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return diag.Errorf("Error creating CCE manifest: %s", err)
+	}
+	return resourceCCEManifestRead(ctx, d, meta)
+}
+
+func resourceCCEManifestRead(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*Config)
+	kubeClient, err := newCCEManifestKubernetesClient(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	kind := d.Get("kind").(string)
+	name := d.Get("name").(string)
+	path, _, err := cceManifestPath(kubeClient, d.Get("api_version").(string), kind, d.Get("namespace").(string), name)
+	if err != nil {
+		return diag.Errorf("Error retrieving CCE manifest %s: %s", d.Id(), err)
+	}
+	var live cceManifest
+	if err := kubeClient.Do(http.MethodGet, path, "", nil, &live); err != nil {
+		if isCCEKubernetesStatus(err, http.StatusNotFound) {
+			log.Printf("[WARN] %s %s is not found in CCE cluster %s", kind, path, d.Get("cluster_id"))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Error retrieving CCE manifest %s: %s", d.Id(), err)
+	}
+	if uid := live.metadata("uid"); uid != d.Id() {
+		log.Printf("[WARN] %s %s is recreated out of Terraform with UID %s", kind, path, uid)
+		d.SetId("")
+		return nil
+	}
+
+	// the fields of the manifest are refreshed with the live object to detect the drift
+	desired, err := parseCCEManifest(d.Get("yaml_body").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	projected := cceManifest(projectCCEManifest(map[string]interface{}(desired),
+		map[string]interface{}(live)).(map[string]interface{}))
+	projectCCEManifestWriteOnlyFields(desired, live, projected)
+	body, err := yaml.Marshal(map[string]interface{}(projected))
+	if err != nil {
+		return diag.Errorf("Error encoding CCE manifest %s: %s", d.Id(), err)
+	}
+
+	d.Set("region", GetRegion(d, config))
+	d.Set("yaml_body", string(body))
+	d.Set("uid", live.metadata("uid"))
+	return nil
+}
+
+func resourceCCEManifestUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if d.HasChanges("yaml_body", "force_conflicts") {
+		if err := applyCCEManifest(ctx, d, meta, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.Errorf("Error updating CCE manifest %s: %s", d.Id(), err)
+		}
+	}
+	return resourceCCEManifestRead(ctx, d, meta)
+}
+
+func resourceCCEManifestDelete(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := meta.(*Config)
+	kubeClient, err := newCCEManifestKubernetesClient(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	path, _, err := cceManifestPath(kubeClient, d.Get("api_version").(string), d.Get("kind").(string),
+		d.Get("namespace").(string), d.Get("name").(string))
+	if err != nil {
+		return diag.Errorf("Error deleting CCE manifest %s: %s", d.Id(), err)
+	}
+	deleteOpts := map[string]interface{}{
+		"apiVersion":        "v1",
+		"kind":              "DeleteOptions",
+		"propagationPolicy": "Background",
+		"preconditions":     map[string]interface{}{"uid": d.Id()},
+	}
+	if err := kubeClient.Do(http.MethodDelete, path, "", deleteOpts, nil); err != nil {
+		if isCCEKubernetesStatus(err, http.StatusNotFound) {
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Error deleting CCE manifest %s: %s", d.Id(), err)
+	}
+
+	// the object is deleted after its finalizers are removed
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{"Deleting"},
+		Target:       []string{"Deleted"},
+		Refresh:      cceManifestStateRefreshFunc(kubeClient, path, d.Id()),
+		Timeout:      d.Timeout(schema.TimeoutDelete),
+		PollInterval: 3 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return diag.Errorf("Error waiting for CCE manifest %s to delete: %s", d.Id(), err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func cceManifestStateRefreshFunc(kubeClient *cceKubernetesClient, path, uid string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		var live cceManifest
+		if err := kubeClient.Do(http.MethodGet, path, "", nil, &live); err != nil {
+			if isCCEKubernetesStatus(err, http.StatusNotFound) {
+				return live, "Deleted", nil
+			}
+			return nil, "", err
+		}
+		// an object with the same name may be created by others after the deletion
+		if live.metadata("uid") != uid {
+			return live, "Deleted", nil
+		}
+		return live, "Deleting", nil
+	}
+}
@@ -0,0 +1,248 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestAccCCEManifest_basic(t *testing.T) {
+	var uid string
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_cce_manifest.test"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckCCEClusterV3Destroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCCEManifest_basic(rName, "bar"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCEManifestExists(resourceName, &uid),
+					resource.TestCheckResourceAttr(resourceName, "api_version", "v1"),
+					resource.TestCheckResourceAttr(resourceName, "kind", "ConfigMap"),
+					resource.TestCheckResourceAttr(resourceName, "name", rName),
+					resource.TestCheckResourceAttr(resourceName, "namespace", "default"),
+					resource.TestCheckResourceAttrSet(resourceName, "uid"),
+				),
+			},
+			{
+				Config: testAccCCEManifest_basic(rName, "baz"),
+				// the object is updated in place
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPtr(resourceName, "uid", &uid),
+					testAccCheckCCEManifestExists(resourceName, &uid),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckCCEManifestExists(n string, uid *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		cceClient, err := config.CceV3Client(OS_REGION_NAME)
+		if err != nil {
+			return fmt.Errorf("Error creating flexibleengine CCE client: %s", err)
+		}
+		kubeClient, err := newCCEKubernetesClient(cceClient, rs.Primary.Attributes["cluster_id"],
+			rs.Primary.Attributes["insecure_skip_tls_verify"] == "true")
+		if err != nil {
+			return err
+		}
+
+		attrs := rs.Primary.Attributes
+		path, _, err := cceManifestPath(kubeClient, attrs["api_version"], attrs["kind"], attrs["namespace"], attrs["name"])
+		if err != nil {
+			return err
+		}
+		var found cceManifest
+		if err := kubeClient.Do(http.MethodGet, path, "", nil, &found); err != nil {
+			return err
+		}
+		if found.metadata("uid") != rs.Primary.ID {
+			return fmt.Errorf("%s %s not found", attrs["kind"], path)
+		}
+
+		*uid = found.metadata("uid")
+		return nil
+	}
+}
+
+func testAccCCEManifest_basic(rName, value string) string {
+	return fmt.Sprintf(`
+%[1]s
+
+resource "flexibleengine_vpc_eip" "test" {
+  publicip {
+    type = "5_bgp"
+  }
+  bandwidth {
+    name       = "%[2]s"
+    size       = 10
+    share_type = "PER"
+  }
+}
+
+resource "flexibleengine_cce_cluster_v3" "test" {
+  name                   = "%[2]s"
+  flavor_id              = "cce.s1.small"
+  cluster_type           = "VirtualMachine"
+  vpc_id                 = flexibleengine_vpc_v1.test.id
+  subnet_id              = flexibleengine_vpc_subnet_v1.test.id
+  container_network_type = "overlay_l2"
+  eip                    = flexibleengine_vpc_eip.test.address
+}
+
+resource "flexibleengine_cce_manifest" "test" {
+  cluster_id = flexibleengine_cce_cluster_v3.test.id
+  yaml_body  = <<EOT
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[2]s
+data:
+  foo: %[3]s
+EOT
+}
+`, testAccCCEClusterV3_Base(rName), rName, value)
+}
+
+func TestMockCCEManifest(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	kube := server.startKubernetes(t, clusterID)
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_manifest", meta)
+	raw := map[string]interface{}{
+		"cluster_id": clusterID,
+		"yaml_body": `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  foo: bar
+  abc: def
+`,
+	}
+	driver.apply(raw)
+	th.AssertEquals(t, "v1", driver.state.Attributes["api_version"])
+	th.AssertEquals(t, "ConfigMap", driver.state.Attributes["kind"])
+	th.AssertEquals(t, "app-config", driver.state.Attributes["name"])
+	th.AssertEquals(t, "default", driver.state.Attributes["namespace"])
+	th.AssertEquals(t, driver.state.ID, driver.state.Attributes["uid"])
+
+	path := "/api/v1/namespaces/default/configmaps/app-config"
+	object := kube.objects[path]
+	th.AssertDeepEquals(t, map[string]interface{}{"foo": "bar", "abc": "def"}, object["data"])
+
+	// the changes out of Terraform are detected and applied again
+	object["data"].(map[string]interface{})["foo"] = "changed"
+	driver.state = driver.refresh()
+	th.AssertEquals(t, true, strings.Contains(driver.state.Attributes["yaml_body"], "foo: changed"))
+	diff := driver.plan(raw)
+	th.AssertEquals(t, false, diff.Empty())
+	th.AssertEquals(t, false, diff.RequiresNew())
+
+	// the fields which are removed from the manifest are removed from the object
+	raw["yaml_body"] = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  foo: bar
+`
+	uid := driver.state.ID
+	driver.apply(raw)
+	th.AssertEquals(t, uid, driver.state.ID)
+	th.AssertDeepEquals(t, map[string]interface{}{"foo": "bar"}, kube.objects[path]["data"])
+
+	// the object is replaced when its name is changed
+	raw["yaml_body"] = strings.Replace(raw["yaml_body"].(string), "app-config", "app-config-2", 1)
+	th.AssertEquals(t, true, driver.plan(raw).RequiresNew())
+
+	driver.destroy()
+	_, ok := kube.objects[path]
+	th.AssertEquals(t, false, ok)
+
+	// the cluster-scoped objects have no namespace
+	driver = newMockResourceDriver(t, "flexibleengine_cce_manifest", meta)
+	driver.apply(map[string]interface{}{
+		"cluster_id": clusterID,
+		"yaml_body": `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pod-reader
+rules:
+  - apiGroups: [""]
+    resources: ["pods"]
+    verbs: ["get", "list"]
+`,
+	})
+	th.AssertEquals(t, "", driver.state.Attributes["namespace"])
+	_, ok = kube.objects["/apis/rbac.authorization.k8s.io/v1/clusterroles/pod-reader"]
+	th.AssertEquals(t, true, ok)
+	driver.destroy()
+
+	// the client of the cluster is reused, the certificates are only issued once
+	var certs int
+	for _, p := range server.paths {
+		if strings.HasSuffix(p, "/clustercert") {
+			certs++
+		}
+	}
+	th.AssertEquals(t, 1, certs)
+}
+
+func TestMockCCEManifest_secret(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	kube := server.startKubernetes(t, clusterID)
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_manifest", meta)
+	raw := map[string]interface{}{
+		"cluster_id": clusterID,
+		"yaml_body": `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secret
+stringData:
+  password: secret
+`,
+	}
+	driver.apply(raw)
+	path := "/api/v1/namespaces/default/secrets/app-secret"
+	th.AssertDeepEquals(t, map[string]interface{}{"password": "c2VjcmV0"}, kube.objects[path]["data"])
+
+	// the write-only stringData is refreshed with data
+	driver.state = driver.refresh()
+	th.AssertEquals(t, true, strings.Contains(driver.state.Attributes["yaml_body"], "password: secret"))
+	th.AssertEquals(t, true, driver.plan(raw).Empty())
+
+	// the changes out of Terraform are detected
+	kube.objects[path]["data"].(map[string]interface{})["password"] = "Y2hhbmdlZA=="
+	driver.state = driver.refresh()
+	th.AssertEquals(t, true, strings.Contains(driver.state.Attributes["yaml_body"], "password: changed"))
+	th.AssertEquals(t, false, driver.plan(raw).Empty())
+
+	driver.apply(raw)
+	th.AssertDeepEquals(t, map[string]interface{}{"password": "c2VjcmV0"}, kube.objects[path]["data"])
+	driver.destroy()
+}
@@ -1,6 +1,7 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -40,8 +41,11 @@ func resourceCCENodePool() *schema.Resource {
 			},
 		},
 
+		CustomizeDiff: resourceCCENodePoolCustomizeDiff,
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(60 * time.Minute),
 			Delete: schema.DefaultTimeout(20 * time.Minute),
 		},
 
@@ -65,10 +69,11 @@ func resourceCCENodePool() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			// the node template arguments are ForceNew unless rolling_update is set,
+			// see resourceCCENodePoolCustomizeDiff
 			"flavor_id": {
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"type": {
 				Type:     schema.TypeString,
@@ -78,29 +83,24 @@ func resourceCCENodePool() *schema.Resource {
 			"root_volume": {
 				Type:     schema.TypeList,
 				Required: true,
-				ForceNew: true,
 				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"size": {
 							Type:     schema.TypeInt,
 							Required: true,
-							ForceNew: true,
 						},
 						"volumetype": {
 							Type:     schema.TypeString,
 							Required: true,
-							ForceNew: true,
 						},
 						"kms_key_id": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 						},
 						"extend_params": {
 							Type:     schema.TypeMap,
 							Optional: true,
-							ForceNew: true,
 							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
 					}},
@@ -108,28 +108,23 @@ func resourceCCENodePool() *schema.Resource {
 			"data_volumes": {
 				Type:     schema.TypeList,
 				Required: true,
-				ForceNew: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"size": {
 							Type:     schema.TypeInt,
 							Required: true,
-							ForceNew: true,
 						},
 						"volumetype": {
 							Type:     schema.TypeString,
 							Required: true,
-							ForceNew: true,
 						},
 						"kms_key_id": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 						},
 						"extend_params": {
 							Type:     schema.TypeMap,
 							Optional: true,
-							ForceNew: true,
 							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
 					}},
@@ -143,19 +138,16 @@ func resourceCCENodePool() *schema.Resource {
 			"os": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 			},
 			"key_pair": {
 				Type:         schema.TypeString,
 				Optional:     true,
-				ForceNew:     true,
 				ExactlyOneOf: []string{"password", "key_pair"},
 			},
 			"password": {
 				Type:         schema.TypeString,
 				Optional:     true,
-				ForceNew:     true,
 				Sensitive:    true,
 				ExactlyOneOf: []string{"password", "key_pair"},
 			},
@@ -192,7 +184,6 @@ func resourceCCENodePool() *schema.Resource {
 			"preinstall": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				StateFunc: func(v interface{}) string {
 					switch v.(type) {
 					case string:
@@ -205,7 +196,6 @@ func resourceCCENodePool() *schema.Resource {
 			"postinstall": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				StateFunc: func(v interface{}) string {
 					switch v.(type) {
 					case string:
@@ -229,7 +219,6 @@ func resourceCCENodePool() *schema.Resource {
 			"runtime": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 				ValidateFunc: validation.StringInSlice([]string{
 					"docker", "containerd",
@@ -247,6 +236,36 @@ func resourceCCENodePool() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			"rolling_update": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_surge": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+						"max_unavailable": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+						"delete_emptydir_data": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+						"insecure_skip_tls_verify": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+					}},
+			},
 			"scale_enable": {
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -322,7 +341,7 @@ func resourceCCENodePoolCreate(d *schema.ResourceData, meta interface{}) error {
 		Delay:      10 * time.Second,
 		MinTimeout: 10 * time.Second,
 	}
-	if _, err = stateCluster.WaitForState(); err != nil {
+	if _, err = waitForState(config, stateCluster); err != nil {
 		return fmt.Errorf("CCE Cluster %s is inactive: %s", clusterid, err)
 	}
 
@@ -381,7 +400,7 @@ func resourceCCENodePoolCreate(d *schema.ResourceData, meta interface{}) error {
 	s, err := nodepools.Create(nodePoolClient, clusterid, createOpts).Extract()
 	if err != nil {
 		if _, ok := err.(golangsdk.ErrDefault403); ok {
-			retryNode, err := recursiveNodePoolCreate(nodePoolClient, createOpts, clusterid, 403)
+			retryNode, err := recursiveNodePoolCreate(config, nodePoolClient, createOpts, clusterid, 403)
 			if err == "fail" {
 				return fmt.Errorf("Error creating Flexibleengine Node Pool")
 			}
@@ -403,7 +422,7 @@ func resourceCCENodePoolCreate(d *schema.ResourceData, meta interface{}) error {
 		Delay:        120 * time.Second,
 		PollInterval: 20 * time.Second,
 	}
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("Error creating Flexibleengine CCE Node Pool: %s", err)
 	}
@@ -490,13 +509,90 @@ func resourceCCENodePoolRead(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func resourceCCENodePoolUpdate(d *schema.ResourceData, meta interface{}) error {
-	config := meta.(*Config)
-	nodePoolClient, err := config.CceV3Client(GetRegion(d, config))
-	if err != nil {
-		return fmt.Errorf("Error creating Flexibleengine CCE client: %s", err)
+// cceNodePoolTemplateKeys are the node template arguments which are rolled out to the nodes
+// batch by batch when rolling_update is set
+var cceNodePoolTemplateKeys = []string{
+	"flavor_id", "os", "root_volume", "data_volumes", "runtime", "key_pair", "password", "preinstall", "postinstall",
+}
+
+// cceNodePoolVolumeKeys are the arguments of root_volume and data_volumes
+var cceNodePoolVolumeKeys = []string{"size", "volumetype", "kms_key_id", "extend_params"}
+
+// resourceCCENodePoolCustomizeDiff replaces the node pool when the node template is changed,
+// unless rolling_update is set, in which case the nodes are replaced batch by batch.
+func resourceCCENodePoolCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if len(d.Get("rolling_update").([]interface{})) > 0 {
+		if d.Get("rolling_update.0.max_surge").(int) == 0 && d.Get("rolling_update.0.max_unavailable").(int) == 0 {
+			return fmt.Errorf("max_surge and max_unavailable of rolling_update can not be both 0")
+		}
+		return nil
+	}
+	if d.Id() == "" {
+		return nil
 	}
 
+	for _, key := range cceNodePoolTemplateKeys {
+		if !d.HasChange(key) {
+			continue
+		}
+		if key != "root_volume" && key != "data_volumes" {
+			if err := d.ForceNew(key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// ForceNew of a list only covers the number of the volumes
+		o, n := d.GetChange(key)
+		if len(o.([]interface{})) != len(n.([]interface{})) {
+			if err := d.ForceNew(key); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := range n.([]interface{}) {
+			for _, volumeKey := range cceNodePoolVolumeKeys {
+				nestedKey := fmt.Sprintf("%s.%d.%s", key, i, volumeKey)
+				if !d.HasChange(nestedKey) {
+					continue
+				}
+				if err := d.ForceNew(nestedKey); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// cceNodePoolUpdateOpts adds the login parameters of the node template to the request body,
+// golangsdk.BuildRequestBody can not build nodes.LoginSpec without the user password.
+type cceNodePoolUpdateOpts struct {
+	nodepools.UpdateOpts
+	Login *nodes.LoginSpec
+}
+
+func (opts cceNodePoolUpdateOpts) ToNodePoolUpdateMap() (map[string]interface{}, error) {
+	b, err := opts.UpdateOpts.ToNodePoolUpdateMap()
+	if err != nil || opts.Login == nil {
+		return b, err
+	}
+
+	login := map[string]interface{}{}
+	if opts.Login.SshKey != "" {
+		login["sshKey"] = opts.Login.SshKey
+	} else {
+		login["userPassword"] = map[string]interface{}{
+			"username": opts.Login.UserPassword.Username,
+			"password": opts.Login.UserPassword.Password,
+		}
+	}
+	spec := b["spec"].(map[string]interface{})
+	spec["nodeTemplate"].(map[string]interface{})["login"] = login
+	return b, nil
+}
+
+func buildCCENodePoolUpdateOpts(d *schema.ResourceData, nodeCount int) cceNodePoolUpdateOpts {
 	updateOpts := nodepools.UpdateOpts{
 		Kind:       "NodePool",
 		ApiVersion: "v3",
@@ -504,7 +600,7 @@ func resourceCCENodePoolUpdate(d *schema.ResourceData, meta interface{}) error {
 			Name: d.Get("name").(string),
 		},
 		Spec: nodepools.UpdateSpec{
-			InitialNodeCount: utils.Int(d.Get("initial_node_count").(int)),
+			InitialNodeCount: utils.Int(nodeCount),
 			Autoscaling: nodepools.AutoscalingSpec{
 				Enable:                d.Get("scale_enable").(bool) || d.Get("scall_enable").(bool),
 				MinNodeCount:          d.Get("min_node_count").(int),
@@ -521,26 +617,215 @@ func resourceCCENodePoolUpdate(d *schema.ResourceData, meta interface{}) error {
 		},
 	}
 
+	// the new node template is used by the nodes created from now on
+	if d.HasChanges(cceNodePoolTemplateKeys...) {
+		template := &updateOpts.Spec.NodeTemplate
+		rootVolume := resourceCCERootVolume(d)
+		loginSpec := buildCCENodePoolLoginSpec(d)
+		template.Flavor = d.Get("flavor_id").(string)
+		template.Os = d.Get("os").(string)
+		template.RootVolume = &rootVolume
+		template.DataVolumes = resourceCCEDataVolume(d)
+		if v, ok := d.GetOk("runtime"); ok {
+			template.RunTime = &nodes.RunTimeSpec{
+				Name: v.(string),
+			}
+		}
+		return cceNodePoolUpdateOpts{UpdateOpts: updateOpts, Login: &loginSpec}
+	}
+
+	return cceNodePoolUpdateOpts{UpdateOpts: updateOpts}
+}
+
+func resourceCCENodePoolUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	nodePoolClient, err := config.CceV3Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating Flexibleengine CCE client: %s", err)
+	}
+
+	clusterid := d.Get("cluster_id").(string)
+	poolNodes, err := listCCENodePoolNodes(nodePoolClient, clusterid, d.Id())
+	if err != nil {
+		return fmt.Errorf("Error retrieving the nodes of Flexibleengine CCE Node Pool %s: %s", d.Id(), err)
+	}
+
+	// the live node count is kept when the autoscaler is enabled, so that the nodes added
+	// or removed by the autoscaler are not reverted unless initial_node_count is changed
+	nodeCount := d.Get("initial_node_count").(int)
+	if (d.Get("scale_enable").(bool) || d.Get("scall_enable").(bool)) && !d.HasChange("initial_node_count") {
+		nodeCount = len(poolNodes)
+	}
+
+	// only the nodes created with an older node template are replaced, a failed rollout
+	// is resumed without replacing the nodes which are already updated
+	var oldNodes []nodes.Nodes
+	if d.HasChanges(cceNodePoolTemplateKeys...) {
+		for _, node := range poolNodes {
+			if isCCENodeOutdated(d, node.Spec) {
+				oldNodes = append(oldNodes, node)
+			}
+		}
+	}
+
+	if len(oldNodes) == 0 {
+		if err := scaleCCENodePool(config, d, nodePoolClient, nodeCount); err != nil {
+			return fmt.Errorf("Error updating Flexibleengine CCE Node Pool: %s", err)
+		}
+	} else if err := rollingUpdateCCENodePool(config, d, nodePoolClient, nodeCount, len(poolNodes), oldNodes); err != nil {
+		// the old node template is kept in the state so that the rollout is resumed by the next apply
+		for _, key := range cceNodePoolTemplateKeys {
+			oldValue, _ := d.GetChange(key)
+			d.Set(key, oldValue)
+		}
+		return fmt.Errorf("Error replacing the nodes of Flexibleengine CCE Node Pool %s: %s", d.Id(), err)
+	}
+
+	return resourceCCENodePoolRead(d, meta)
+}
+
+// scaleCCENodePool updates the node pool with the node count and waits for the nodes to be created
+func scaleCCENodePool(config *Config, d *schema.ResourceData, client *golangsdk.ServiceClient, nodeCount int) error {
 	clusterid := d.Get("cluster_id").(string)
-	_, err = nodepools.Update(nodePoolClient, clusterid, d.Id(), updateOpts).Extract()
+	_, err := nodepools.Update(client, clusterid, d.Id(), buildCCENodePoolUpdateOpts(d, nodeCount)).Extract()
 	if err != nil {
-		return fmt.Errorf("Error updating Flexibleengine Node Node Pool: %s", err)
+		return err
 	}
 
 	stateConf := &resource.StateChangeConf{
-		Pending:    []string{"Synchronizing", "Synchronized", "PENDING"},
-		Target:     []string{"COMPLETED"},
-		Refresh:    waitForCceNodePoolActive(nodePoolClient, clusterid, d.Id()),
-		Timeout:    d.Timeout(schema.TimeoutCreate),
-		Delay:      60 * time.Second,
-		MinTimeout: 10 * time.Second,
+		Pending:      []string{"Synchronizing", "Synchronized"},
+		Target:       []string{""},
+		Refresh:      waitForCceNodePoolActive(client, clusterid, d.Id()),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        20 * time.Second,
+		PollInterval: 20 * time.Second,
+	}
+	_, err = waitForState(config, stateConf)
+	return err
+}
+
+// rollingUpdateCCENodePool replaces the old nodes batch by batch: the node pool is scaled out by
+// max_surge with the new node template, then the old nodes of the batch are drained and deleted,
+// which scales in the node pool again. A batch has max_surge + max_unavailable nodes.
+// nodeCount is the node count after the rollout and currentCount is the node count before it.
+func rollingUpdateCCENodePool(config *Config, d *schema.ResourceData, client *golangsdk.ServiceClient,
+	nodeCount, currentCount int, oldNodes []nodes.Nodes) error {
+	clusterid := d.Get("cluster_id").(string)
+	maxSurge := d.Get("rolling_update.0.max_surge").(int)
+	maxUnavailable := d.Get("rolling_update.0.max_unavailable").(int)
+	drainOpts := cceNodeDrainOpts{
+		InsecureSkipTLSVerify: d.Get("rolling_update.0.insecure_skip_tls_verify").(bool),
+		DeleteEmptyDirData:    d.Get("rolling_update.0.delete_emptydir_data").(bool),
+		Timeout:               d.Timeout(schema.TimeoutUpdate),
+	}
+
+	for len(oldNodes) > 0 {
+		surge := maxSurge
+		if surge > len(oldNodes) {
+			surge = len(oldNodes)
+		}
+		batchSize := surge + maxUnavailable
+		if batchSize > len(oldNodes) {
+			batchSize = len(oldNodes)
+		}
+		batch := oldNodes[:batchSize]
+		oldNodes = oldNodes[batchSize:]
+
+		// the new nodes left by a failed rollout are counted in the surge
+		targetCount := nodeCount + surge
+		if targetCount < currentCount {
+			targetCount = currentCount
+		}
+		if err := scaleCCENodePool(config, d, client, targetCount); err != nil {
+			return fmt.Errorf("error scaling out the node pool: %s", err)
+		}
+		currentCount = targetCount - batchSize
+
+		for _, node := range batch {
+			nodeID := node.Metadata.Id
+			log.Printf("[DEBUG] Replacing the node %s of Flexibleengine CCE Node Pool %s", nodeID, d.Id())
+			if err := drainCCENode(config, client, clusterid, node.Status.PrivateIP, drainOpts); err != nil {
+				return fmt.Errorf("error draining node %s: %s", nodeID, err)
+			}
+		}
+		for _, node := range batch {
+			if err := nodes.Delete(client, clusterid, node.Metadata.Id).ExtractErr(); err != nil {
+				return fmt.Errorf("error deleting node %s: %s", node.Metadata.Id, err)
+			}
+		}
+		for _, node := range batch {
+			stateConf := &resource.StateChangeConf{
+				Pending:      []string{"Deleting"},
+				Target:       []string{"Deleted"},
+				Refresh:      waitForCceNodeDelete(client, clusterid, node.Metadata.Id),
+				Timeout:      d.Timeout(schema.TimeoutUpdate),
+				Delay:        10 * time.Second,
+				PollInterval: 10 * time.Second,
+			}
+			if _, err := waitForState(config, stateConf); err != nil {
+				return fmt.Errorf("error deleting node %s: %s", node.Metadata.Id, err)
+			}
+		}
+	}
+
+	// the node pool is scaled in by max_unavailable after the last batch
+	if err := scaleCCENodePool(config, d, client, nodeCount); err != nil {
+		return fmt.Errorf("error scaling the node pool: %s", err)
 	}
-	_, err = stateConf.WaitForState()
+	return nil
+}
+
+// listCCENodePoolNodes returns the nodes in the node pool
+func listCCENodePoolNodes(client *golangsdk.ServiceClient, clusterID, nodePoolID string) ([]nodes.Nodes, error) {
+	allNodes, err := nodes.List(client, clusterID, nodes.ListOpts{})
 	if err != nil {
-		return fmt.Errorf("Error creating Flexibleengine CCE Node Pool: %s", err)
+		return nil, err
 	}
 
-	return resourceCCENodePoolRead(d, meta)
+	var poolNodes []nodes.Nodes
+	for _, n := range allNodes {
+		if n.Metadata.Annotations["kubernetes.io/node-pool.id"] == nodePoolID {
+			poolNodes = append(poolNodes, n)
+		}
+	}
+	return poolNodes, nil
+}
+
+// isCCENodeOutdated checks whether the node differs from the node template of the node pool in
+// the flavor, OS, volumes, runtime, key pair or install scripts. The password is not returned
+// by the API, so a node is not outdated when only the password is changed.
+func isCCENodeOutdated(d *schema.ResourceData, spec nodes.Spec) bool {
+	if spec.Flavor != d.Get("flavor_id").(string) || spec.Os != d.Get("os").(string) {
+		return true
+	}
+	if spec.Login.SshKey != d.Get("key_pair").(string) {
+		return true
+	}
+	if v, ok := d.GetOk("runtime"); ok && (spec.RunTime == nil || spec.RunTime.Name != v.(string)) {
+		return true
+	}
+
+	volumes := append([]nodes.VolumeSpec{resourceCCERootVolume(d)}, resourceCCEDataVolume(d)...)
+	nodeVolumes := append([]nodes.VolumeSpec{spec.RootVolume}, spec.DataVolumes...)
+	if len(nodeVolumes) != len(volumes) {
+		return true
+	}
+	for i, volume := range volumes {
+		if nodeVolumes[i].Size != volume.Size || nodeVolumes[i].VolumeType != volume.VolumeType {
+			return true
+		}
+	}
+
+	scripts := map[string]string{
+		"alpha.cce/preInstall":  d.Get("preinstall").(string),
+		"alpha.cce/postInstall": d.Get("postinstall").(string),
+	}
+	for key, script := range scripts {
+		if v, _ := spec.ExtendParam[key].(string); v != installScriptEncode(script) {
+			return true
+		}
+	}
+	return false
 }
 
 func resourceCCENodePoolDelete(d *schema.ResourceData, meta interface{}) error {
@@ -563,7 +848,7 @@ func resourceCCENodePoolDelete(d *schema.ResourceData, meta interface{}) error {
 		PollInterval: 20 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("Error deleting Flexibleengine CCE Node Pool: %s", err)
 	}
@@ -601,7 +886,7 @@ func waitForCceNodePoolDelete(cceClient *golangsdk.ServiceClient, clusterId, nod
 	}
 }
 
-func recursiveNodePoolCreate(cceClient *golangsdk.ServiceClient, opts nodepools.CreateOptsBuilder, ClusterID string, errCode int) (*nodepools.NodePool, string) {
+func recursiveNodePoolCreate(config *Config, cceClient *golangsdk.ServiceClient, opts nodepools.CreateOptsBuilder, ClusterID string, errCode int) (*nodepools.NodePool, string) {
 	if errCode == 403 {
 		stateCluster := &resource.StateChangeConf{
 			Target:     []string{"Available"},
@@ -610,14 +895,14 @@ func recursiveNodePoolCreate(cceClient *golangsdk.ServiceClient, opts nodepools.
 			Delay:      15 * time.Second,
 			MinTimeout: 3 * time.Second,
 		}
-		_, stateErr := stateCluster.WaitForState()
+		_, stateErr := waitForState(config, stateCluster)
 		if stateErr != nil {
 			log.Printf("[INFO] Cluster Unavailable %s.\n", stateErr)
 		}
 		s, err := nodepools.Create(cceClient, ClusterID, opts).Extract()
 		if err != nil {
 			if _, ok := err.(golangsdk.ErrDefault403); ok {
-				return recursiveNodePoolCreate(cceClient, opts, ClusterID, 403)
+				return recursiveNodePoolCreate(config, cceClient, opts, ClusterID, 403)
 			} else {
 				return s, "fail"
 			}
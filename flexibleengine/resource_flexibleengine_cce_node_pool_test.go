@@ -1,7 +1,9 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
@@ -9,6 +11,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/chnsz/golangsdk/openstack/cce/v3/nodepools"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccCCENodePool_basic(t *testing.T) {
@@ -128,6 +131,46 @@ func TestAccCCENodePool_securityGroups(t *testing.T) {
 	})
 }
 
+func TestAccCCENodePool_rollingUpdate(t *testing.T) {
+	var nodePool, updated nodepools.NodePool
+
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_cce_node_pool_v3.test"
+	//clusterName here is used to provide the cluster id to fetch cce node pool.
+	clusterName := "flexibleengine_cce_cluster_v3.test"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckCCENodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCCENodePool_rollingUpdate(rName, "s3.large.2", 40),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCENodePoolExists(resourceName, clusterName, &nodePool),
+					resource.TestCheckResourceAttr(resourceName, "flavor_id", "s3.large.2"),
+					resource.TestCheckResourceAttr(resourceName, "current_node_count", "2"),
+				),
+			},
+			{
+				Config: testAccCCENodePool_rollingUpdate(rName, "s3.xlarge.2", 50),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCENodePoolExists(resourceName, clusterName, &updated),
+					func(_ *terraform.State) error {
+						if updated.Metadata.Id != nodePool.Metadata.Id {
+							return fmt.Errorf("Node pool was replaced by %s", updated.Metadata.Id)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttr(resourceName, "flavor_id", "s3.xlarge.2"),
+					resource.TestCheckResourceAttr(resourceName, "root_volume.0.size", "50"),
+					resource.TestCheckResourceAttr(resourceName, "current_node_count", "2"),
+				),
+			},
+		},
+	})
+}
+
 func nodePoolImportStateIdFunc(resourceName string) resource.ImportStateIdFunc {
 	return func(s *terraform.State) (string, error) {
 		rs, ok := s.RootModule().Resources[resourceName]
@@ -401,3 +444,178 @@ resource "flexibleengine_cce_node_pool_v3" "test" {
 }
 `, testAccCCENodePool_Base(rName), rName)
 }
+
+func testAccCCENodePool_rollingUpdate(rName, flavor string, rootSize int) string {
+	return fmt.Sprintf(`
+%s
+
+resource "flexibleengine_cce_node_pool_v3" "test" {
+  cluster_id         = flexibleengine_cce_cluster_v3.test.id
+  name               = "%s"
+  os                 = "EulerOS 2.5"
+  flavor_id          = "%s"
+  availability_zone  = data.flexibleengine_availability_zones.test.names[0]
+  key_pair           = flexibleengine_compute_keypair_v2.test.name
+  initial_node_count = 2
+  type               = "vm"
+
+  root_volume {
+    size       = %d
+    volumetype = "SSD"
+  }
+  data_volumes {
+    size       = 100
+    volumetype = "SSD"
+  }
+
+  rolling_update {
+    max_surge       = 1
+    max_unavailable = 1
+  }
+}
+`, testAccCCENodePool_Base(rName), rName, flavor, rootSize)
+}
+
+func TestMockCCENodePool_rollingUpdate(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	kube := server.startKubernetes(t, clusterID)
+	poolID := server.addCCENodePool("pool_1", 2, map[string]interface{}{
+		"flavor":      "s3.large.2",
+		"az":          "eu-west-0a",
+		"os":          "EulerOS 2.5",
+		"login":       map[string]interface{}{"sshKey": "key-1"},
+		"rootVolume":  map[string]interface{}{"size": float64(40), "volumetype": "SSD"},
+		"dataVolumes": []interface{}{map[string]interface{}{"size": float64(100), "volumetype": "SSD"}},
+	})
+	// addKubernetesNodes adds the nodes of the pool to the Kubernetes cluster with a pod on each node
+	addKubernetesNodes := func(podName string, emptyDir bool) map[string]string {
+		nodeIPs := make(map[string]string)
+		for id, node := range server.objects["cce_nodes"] {
+			ip := node["status"].(map[string]interface{})["privateIP"].(string)
+			nodeIPs[id] = ip
+			kube.addNode(ip, ip)
+			kube.addPod("default", podName+"-"+ip, ip, "ReplicaSet", 0)
+			if emptyDir {
+				kube.pods["default/"+podName+"-"+ip]["spec"].(map[string]interface{})["volumes"] = []interface{}{
+					map[string]interface{}{"name": "cache", "emptyDir": map[string]interface{}{}},
+				}
+			}
+		}
+		return nodeIPs
+	}
+	oldNodes := addKubernetesNodes("web", false)
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_node_pool_v3", meta)
+	driver.state = driver.importState(clusterID + "/" + poolID)
+	th.AssertEquals(t, "s3.large.2", driver.state.Attributes["flavor_id"])
+
+	raw := map[string]interface{}{
+		"cluster_id":         clusterID,
+		"name":               "pool_1",
+		"type":               "vm",
+		"initial_node_count": 2,
+		"flavor_id":          "s3.xlarge.2",
+		"availability_zone":  "eu-west-0a",
+		"os":                 "EulerOS 2.5",
+		"key_pair":           "key-1",
+		"root_volume": []interface{}{
+			map[string]interface{}{"size": 40, "volumetype": "SSD"},
+		},
+		"data_volumes": []interface{}{
+			map[string]interface{}{"size": 100, "volumetype": "SSD"},
+		},
+	}
+	// the node pool is replaced unless rolling_update is set
+	th.AssertEquals(t, true, driver.plan(raw).RequiresNew())
+	raw["flavor_id"] = "s3.large.2"
+	raw["root_volume"] = []interface{}{
+		map[string]interface{}{"size": 50, "volumetype": "SSD"},
+	}
+	th.AssertEquals(t, true, driver.plan(raw).RequiresNew())
+
+	raw["flavor_id"] = "s3.xlarge.2"
+	raw["rolling_update"] = []interface{}{
+		map[string]interface{}{"max_surge": 1, "max_unavailable": 1},
+	}
+	th.AssertEquals(t, false, driver.plan(raw).RequiresNew())
+	driver.apply(raw)
+	th.AssertEquals(t, poolID, driver.state.ID)
+	th.AssertEquals(t, "s3.xlarge.2", driver.state.Attributes["flavor_id"])
+	th.AssertEquals(t, "50", driver.state.Attributes["root_volume.0.size"])
+
+	// the old nodes are drained before being deleted and the pool is scaled out by 1 node at most
+	th.AssertEquals(t, 2, len(server.objects["cce_nodes"]))
+	for _, node := range server.objects["cce_nodes"] {
+		th.AssertEquals(t, "s3.xlarge.2", node["spec"].(map[string]interface{})["flavor"])
+	}
+	for id, ip := range oldNodes {
+		_, ok := server.objects["cce_deleted_nodes"][id]
+		th.AssertEquals(t, true, ok)
+		th.AssertEquals(t, true, kube.nodes[ip]["spec"].(map[string]interface{})["unschedulable"])
+		_, ok = kube.pods["default/web-"+ip]
+		th.AssertEquals(t, false, ok)
+	}
+	th.AssertEquals(t, 3, server.objects["cce_nodepools"][poolID]["__peak_nodes"])
+
+	// the drain fails on the pods with emptyDir volumes unless delete_emptydir_data is set,
+	// and the old node template is kept so that the rollout is resumed by the next apply
+	oldNodes = addKubernetesNodes("cache", true)
+	raw["flavor_id"] = "s3.2xlarge.2"
+	raw["rolling_update"] = []interface{}{
+		map[string]interface{}{"max_surge": 1, "max_unavailable": 0},
+	}
+	state, diags := driver.resource.Apply(context.Background(), driver.state, driver.plan(raw), meta)
+	th.AssertEquals(t, true, diags.HasError())
+	th.AssertEquals(t, true, strings.Contains(diags[0].Summary, "emptyDir"))
+	th.AssertEquals(t, "s3.xlarge.2", state.Attributes["flavor_id"])
+	driver.state = state
+
+	newNodes := make([]string, 0)
+	for id, node := range server.objects["cce_nodes"] {
+		if node["spec"].(map[string]interface{})["flavor"] == "s3.2xlarge.2" {
+			newNodes = append(newNodes, id)
+		}
+	}
+	th.AssertEquals(t, 1, len(newNodes))
+
+	// only the old nodes are replaced when the rollout is resumed
+	raw["rolling_update"] = []interface{}{
+		map[string]interface{}{"max_surge": 1, "max_unavailable": 0, "delete_emptydir_data": true},
+	}
+	th.AssertEquals(t, false, driver.plan(raw).Empty())
+	driver.apply(raw)
+	th.AssertEquals(t, poolID, driver.state.ID)
+	th.AssertEquals(t, "s3.2xlarge.2", driver.state.Attributes["flavor_id"])
+	th.AssertEquals(t, 2, len(server.objects["cce_nodes"]))
+	for _, node := range server.objects["cce_nodes"] {
+		th.AssertEquals(t, "s3.2xlarge.2", node["spec"].(map[string]interface{})["flavor"])
+	}
+	_, ok := server.objects["cce_nodes"][newNodes[0]]
+	th.AssertEquals(t, true, ok)
+	for id, ip := range oldNodes {
+		_, ok := server.objects["cce_deleted_nodes"][id]
+		th.AssertEquals(t, true, ok)
+		_, ok = kube.pods["default/cache-"+ip]
+		th.AssertEquals(t, false, ok)
+	}
+	th.AssertEquals(t, 3, server.objects["cce_nodepools"][poolID]["__peak_nodes"])
+
+	// the nodes added by the autoscaler are kept unless initial_node_count is changed
+	raw["scale_enable"] = true
+	raw["min_node_count"] = 1
+	raw["max_node_count"] = 5
+	driver.apply(raw)
+	pool := server.objects["cce_nodepools"][poolID]
+	pool["spec"].(map[string]interface{})["initialNodeCount"] = float64(3)
+	server.syncCCENodePool(pool)
+
+	raw["name"] = "pool_2"
+	driver.apply(raw)
+	th.AssertEquals(t, "pool_2", driver.state.Attributes["name"])
+	th.AssertEquals(t, 3, len(server.objects["cce_nodes"]))
+
+	raw["initial_node_count"] = 4
+	driver.apply(raw)
+	th.AssertEquals(t, 4, len(server.objects["cce_nodes"]))
+}
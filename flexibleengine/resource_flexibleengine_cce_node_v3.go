@@ -1,11 +1,14 @@
 package flexibleengine
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -29,9 +32,11 @@ func resourceCCENodeV3() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: resourceCCENodeV3Import,
 		},
+		CustomizeDiff: resourceCCENodeV3CustomizeDiff,
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
 			Delete: schema.DefaultTimeout(20 * time.Minute),
 		},
 
@@ -74,11 +79,11 @@ func resourceCCENodeV3() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			// os, preinstall and postinstall are changed by resetting the node
 			"os": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
 			},
 			"key_pair": {
 				Type:     schema.TypeString,
@@ -253,7 +258,6 @@ func resourceCCENodeV3() *schema.Resource {
 			"preinstall": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				StateFunc: func(v interface{}) string {
 					switch v.(type) {
 					case string:
@@ -266,7 +270,6 @@ func resourceCCENodeV3() *schema.Resource {
 			"postinstall": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				StateFunc: func(v interface{}) string {
 					switch v.(type) {
 					case string:
@@ -282,6 +285,32 @@ func resourceCCENodeV3() *schema.Resource {
 				ForceNew: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			"drain_before_delete": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"drain_before_reset": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"drain_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"drain_delete_emptydir_data": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"insecure_skip_tls_verify": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"private_ip": {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -513,7 +542,7 @@ func resourceCCENodeV3Create(d *schema.ResourceData, meta interface{}) error {
 		Delay:        5 * time.Second,
 		PollInterval: 5 * time.Second,
 	}
-	_, err = stateCluster.WaitForState()
+	_, err = waitForState(config, stateCluster)
 	if err != nil {
 		return fmt.Errorf("error waiting for CCE cluster to be Available: %s", err)
 	}
@@ -522,7 +551,7 @@ func resourceCCENodeV3Create(d *schema.ResourceData, meta interface{}) error {
 	s, err := nodes.Create(nodeClient, clusterid, createOpts).Extract()
 	if err != nil {
 		if _, ok := err.(golangsdk.ErrDefault403); ok {
-			retryNode, err := recursiveCreate(nodeClient, createOpts, clusterid, 403)
+			retryNode, err := recursiveCreate(config, nodeClient, createOpts, clusterid, 403)
 			if err == "fail" {
 				return fmt.Errorf("Error creating flexibleengine Node: %s", err)
 			}
@@ -532,7 +561,7 @@ func resourceCCENodeV3Create(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
-	nodeID, err := getResourceIDFromJob(nodeClient, s.Status.JobID, "CreateNode", "CreateNodeVM",
+	nodeID, err := getResourceIDFromJob(config, nodeClient, s.Status.JobID, "CreateNode", "CreateNodeVM",
 		d.Timeout(schema.TimeoutCreate))
 	if err != nil {
 		return err
@@ -548,7 +577,7 @@ func resourceCCENodeV3Create(d *schema.ResourceData, meta interface{}) error {
 		Delay:        20 * time.Second,
 		PollInterval: 20 * time.Second,
 	}
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("Error creating flexibleengine CCE Node: %s", err)
 	}
@@ -661,6 +690,12 @@ func resourceCCENodeV3Update(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
+	if d.HasChanges("os", "preinstall", "postinstall") {
+		if err := resetCCENode(config, d, nodeClient); err != nil {
+			return err
+		}
+	}
+
 	// update tags
 	if d.HasChange("tags") {
 		computeClient, err := config.ComputeV1Client(GetRegion(d, config))
@@ -684,6 +719,13 @@ func resourceCCENodeV3Delete(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error creating flexibleengine CCE client: %s", err)
 	}
 	clusterid := d.Get("cluster_id").(string)
+	if d.Get("drain_before_delete").(bool) {
+		if err := drainCCENode(config, nodeClient, clusterid, d.Get("private_ip").(string),
+			buildCCENodeDrainOpts(d)); err != nil {
+			return fmt.Errorf("Error draining flexibleengine CCE Node %s: %s", d.Id(), err)
+		}
+	}
+
 	err = nodes.Delete(nodeClient, clusterid, d.Id()).ExtractErr()
 	if err != nil {
 		return fmt.Errorf("Error deleting flexibleengine CCE Cluster: %s", err)
@@ -697,7 +739,7 @@ func resourceCCENodeV3Delete(d *schema.ResourceData, meta interface{}) error {
 		PollInterval: 15 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("Error deleting flexibleengine CCE Node: %s", err)
 	}
@@ -706,6 +748,259 @@ func resourceCCENodeV3Delete(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
+// resourceCCENodeV3CustomizeDiff checks the key pair of the node which is reset by the changes of
+// os, preinstall and postinstall, the node is reset with the key pair login only.
+func resourceCCENodeV3CustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() == "" || !d.HasChanges("os", "preinstall", "postinstall") || !d.NewValueKnown("key_pair") {
+		return nil
+	}
+	if d.Get("key_pair").(string) == "" {
+		return fmt.Errorf("key_pair is required to reset the node with the new os, preinstall or postinstall")
+	}
+	return nil
+}
+
+// resetCCENode reinstalls the node with the OS and the install scripts,
+// the ECS server and its IP addresses are kept.
+func resetCCENode(config *Config, d *schema.ResourceData, client *golangsdk.ServiceClient) error {
+	// the key pair is checked before draining the node, the reset fails without it
+	if d.Get("key_pair").(string) == "" {
+		return fmt.Errorf("Error resetting flexibleengine CCE Node %s: key_pair is required", d.Id())
+	}
+
+	clusterid := d.Get("cluster_id").(string)
+	if d.Get("drain_before_reset").(bool) {
+		if err := drainCCENode(config, client, clusterid, d.Get("private_ip").(string),
+			buildCCENodeDrainOpts(d)); err != nil {
+			return fmt.Errorf("Error draining flexibleengine CCE Node %s: %s", d.Id(), err)
+		}
+	}
+
+	spec := nodes.AddNodeSpec{
+		Os:    d.Get("os").(string),
+		Login: nodes.LoginSpec{SshKey: d.Get("key_pair").(string)},
+		Name:  d.Get("name").(string),
+		K8sOptions: &nodes.K8sOptions{
+			Labels:  resourceCCENodeK8sTags(d),
+			Taints:  resourceCCETaint(d),
+			MaxPods: d.Get("max_pods").(int),
+		},
+		Lifecycle: &nodes.Lifecycle{
+			Preinstall:  installScriptEncode(d.Get("preinstall").(string)),
+			PostInstall: installScriptEncode(d.Get("postinstall").(string)),
+		},
+	}
+	if v, ok := d.GetOk("runtime"); ok {
+		spec.RuntimeConfig = &nodes.RuntimeConfig{
+			Runtime: &nodes.RunTimeSpec{
+				Name: v.(string),
+			},
+		}
+	}
+	resetOpts := nodes.ResetOpts{
+		Kind:       "List",
+		ApiVersion: "v3",
+		NodeList: []nodes.ResetNode{
+			{
+				NodeID: d.Id(),
+				Spec:   spec,
+			},
+		},
+	}
+
+	resp, err := nodes.Reset(client, clusterid, resetOpts).ExtractAddNode()
+	if err != nil {
+		return fmt.Errorf("Error resetting flexibleengine CCE Node %s: %s", d.Id(), err)
+	}
+	if err := waitForCCEJobSuccess(config, client, resp.JobID, d.Timeout(schema.TimeoutUpdate)); err != nil {
+		return fmt.Errorf("Error resetting flexibleengine CCE Node %s: %s", d.Id(), err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{"Build", "Installing", "PENDING"},
+		Target:       []string{"Active"},
+		Refresh:      waitForCceNodeActive(client, clusterid, d.Id()),
+		Timeout:      d.Timeout(schema.TimeoutUpdate),
+		Delay:        20 * time.Second,
+		PollInterval: 20 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error resetting flexibleengine CCE Node %s: %s", d.Id(), err)
+	}
+	return nil
+}
+
+type cceKubernetesNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Addresses []struct {
+				Type    string `json:"type"`
+				Address string `json:"address"`
+			} `json:"addresses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type cceKubernetesPodList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string            `json:"name"`
+			Namespace         string            `json:"namespace"`
+			Annotations       map[string]string `json:"annotations"`
+			DeletionTimestamp string            `json:"deletionTimestamp"`
+			OwnerReferences   []struct {
+				Kind string `json:"kind"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		Spec struct {
+			Volumes []struct {
+				EmptyDir interface{} `json:"emptyDir"`
+			} `json:"volumes"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// cceNodeDrainOpts are the options to drain a CCE node through the Kubernetes API of the cluster
+type cceNodeDrainOpts struct {
+	// the server certificate of the Kubernetes API is not verified if set
+	InsecureSkipTLSVerify bool
+	// the pods with emptyDir volumes are evicted if set, otherwise the drain fails on them
+	DeleteEmptyDirData bool
+	Timeout            time.Duration
+}
+
+func buildCCENodeDrainOpts(d *schema.ResourceData) cceNodeDrainOpts {
+	return cceNodeDrainOpts{
+		InsecureSkipTLSVerify: d.Get("insecure_skip_tls_verify").(bool),
+		DeleteEmptyDirData:    d.Get("drain_delete_emptydir_data").(bool),
+		Timeout:               time.Duration(d.Get("drain_timeout").(int)) * time.Second,
+	}
+}
+
+// drainCCENode cordons the node with the private IP and evicts its pods through the Kubernetes API
+// of the cluster. The evictions respect the PodDisruptionBudgets and are retried until the timeout.
+func drainCCENode(config *Config, client *golangsdk.ServiceClient, clusterID, privateIP string,
+	opts cceNodeDrainOpts) error {
+	kubeClient, err := newCCEKubernetesClient(client, clusterID, opts.InsecureSkipTLSVerify)
+	if err != nil {
+		return err
+	}
+
+	// the name of the Kubernetes node is looked up by the private IP
+	var nodeList cceKubernetesNodeList
+	if err := kubeClient.Do(http.MethodGet, "/api/v1/nodes", "", nil, &nodeList); err != nil {
+		return err
+	}
+	var nodeName string
+	for _, node := range nodeList.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == "InternalIP" && address.Address == privateIP {
+				nodeName = node.Metadata.Name
+			}
+		}
+	}
+	if nodeName == "" {
+		log.Printf("[WARN] the node %s is not found in the Kubernetes cluster %s, skip draining", privateIP, clusterID)
+		return nil
+	}
+
+	// the node is cordoned to not schedule the evicted pods to it again
+	cordon := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": true,
+		},
+	}
+	err = kubeClient.Do(http.MethodPatch, "/api/v1/nodes/"+nodeName, "application/merge-patch+json", cordon, nil)
+	if err != nil {
+		return fmt.Errorf("error cordoning the node: %s", err)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{"Draining"},
+		Target:       []string{"Drained"},
+		Refresh:      evictCCENodePods(kubeClient, nodeName, opts.DeleteEmptyDirData),
+		Timeout:      opts.Timeout,
+		PollInterval: 5 * time.Second,
+	}
+	_, err = waitForState(config, stateConf)
+	return err
+}
+
+// evictCCENodePods evicts the pods on the node except the pods of DaemonSets and the static pods,
+// the evictions refused by the PodDisruptionBudgets are retried in the next refresh. As kubectl drain,
+// no pod is evicted if a pod has emptyDir volumes and deleteEmptyDirData is not set.
+func evictCCENodePods(kubeClient *cceKubernetesClient, nodeName string,
+	deleteEmptyDirData bool) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		var podList cceKubernetesPodList
+		path := "/api/v1/pods?fieldSelector=" + url.QueryEscape("spec.nodeName="+nodeName)
+		if err := kubeClient.Do(http.MethodGet, path, "", nil, &podList); err != nil {
+			return nil, "", err
+		}
+
+		pods := podList.Items[:0]
+		for _, pod := range podList.Items {
+			metadata := pod.Metadata
+			if _, ok := metadata.Annotations["kubernetes.io/config.mirror"]; ok ||
+				pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+				continue
+			}
+			if len(metadata.OwnerReferences) > 0 && metadata.OwnerReferences[0].Kind == "DaemonSet" {
+				continue
+			}
+			if !deleteEmptyDirData {
+				for _, volume := range pod.Spec.Volumes {
+					if volume.EmptyDir != nil {
+						return nil, "", fmt.Errorf("the data of the emptyDir volumes of pod %s/%s would be deleted "+
+							"by the eviction", metadata.Namespace, metadata.Name)
+					}
+				}
+			}
+			pods = append(pods, pod)
+		}
+
+		var pending []string
+		for _, pod := range pods {
+			metadata := pod.Metadata
+			podName := metadata.Namespace + "/" + metadata.Name
+			pending = append(pending, podName)
+			if metadata.DeletionTimestamp != "" {
+				continue
+			}
+
+			eviction := map[string]interface{}{
+				"apiVersion": "policy/v1",
+				"kind":       "Eviction",
+				"metadata": map[string]interface{}{
+					"name":      metadata.Name,
+					"namespace": metadata.Namespace,
+				},
+			}
+			evictionPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/eviction", metadata.Namespace, metadata.Name)
+			err := kubeClient.Do(http.MethodPost, evictionPath, "", eviction, nil)
+			switch {
+			case err == nil || isCCEKubernetesStatus(err, http.StatusNotFound):
+			case isCCEKubernetesStatus(err, http.StatusTooManyRequests):
+				log.Printf("[DEBUG] The eviction of pod %s is refused, will retry: %s", podName, err)
+			default:
+				return nil, "", fmt.Errorf("error evicting pod %s: %s", podName, err)
+			}
+		}
+
+		if len(pending) == 0 {
+			return podList, "Drained", nil
+		}
+		log.Printf("[DEBUG] Waiting for the pods %v to be evicted from node %s", pending, nodeName)
+		return podList, "Draining", nil
+	}
+}
+
 func waitForCceNodeActive(cceClient *golangsdk.ServiceClient, clusterId, nodeId string) resource.StateRefreshFunc {
 	return func() (interface{}, string, error) {
 		n, err := nodes.Get(cceClient, clusterId, nodeId).Extract()
@@ -749,7 +1044,7 @@ func waitForClusterAvailable(cceClient *golangsdk.ServiceClient, clusterId strin
 	}
 }
 
-func recursiveCreate(cceClient *golangsdk.ServiceClient, opts nodes.CreateOptsBuilder, ClusterID string, errCode int) (*nodes.Nodes, string) {
+func recursiveCreate(config *Config, cceClient *golangsdk.ServiceClient, opts nodes.CreateOptsBuilder, ClusterID string, errCode int) (*nodes.Nodes, string) {
 	if errCode == 403 {
 		stateCluster := &resource.StateChangeConf{
 			Target:       []string{"Available"},
@@ -758,7 +1053,7 @@ func recursiveCreate(cceClient *golangsdk.ServiceClient, opts nodes.CreateOptsBu
 			Delay:        5 * time.Second,
 			PollInterval: 5 * time.Second,
 		}
-		_, stateErr := stateCluster.WaitForState()
+		_, stateErr := waitForState(config, stateCluster)
 		if stateErr != nil {
 			log.Printf("[INFO] Cluster Unavailable %s.\n", stateErr)
 		}
@@ -894,7 +1189,26 @@ func waitForJobStatus(cceClient *golangsdk.ServiceClient, jobID string) resource
 	}
 }
 
-func getResourceIDFromJob(client *golangsdk.ServiceClient, jobID, jobType, subJobType string,
+func waitForCCEJobSuccess(config *Config, client *golangsdk.ServiceClient, jobID string, timeout time.Duration) error {
+	stateJob := &resource.StateChangeConf{
+		Pending:      []string{"Initializing", "Running"},
+		Target:       []string{"Success"},
+		Refresh:      waitForJobStatus(client, jobID),
+		Timeout:      timeout,
+		Delay:        10 * time.Second,
+		PollInterval: 10 * time.Second,
+	}
+	if v, err := waitForState(config, stateJob); err != nil {
+		if job, ok := v.(*nodes.Job); ok {
+			return fmt.Errorf("Error waiting for job (%s) to become success: %s, reason: %s",
+				jobID, err, job.Status.Reason)
+		}
+		return fmt.Errorf("Error waiting for job (%s) to become success: %s", jobID, err)
+	}
+	return nil
+}
+
+func getResourceIDFromJob(config *Config, client *golangsdk.ServiceClient, jobID, jobType, subJobType string,
 	timeout time.Duration) (string, error) {
 
 	stateJob := &resource.StateChangeConf{
@@ -906,7 +1220,7 @@ func getResourceIDFromJob(client *golangsdk.ServiceClient, jobID, jobType, subJo
 		PollInterval: 20 * time.Second,
 	}
 
-	v, err := stateJob.WaitForState()
+	v, err := waitForState(config, stateJob)
 	if err != nil {
 		if job, ok := v.(*nodes.Job); ok {
 			return "", fmt.Errorf("Error waiting for job (%s) to become success: %s, reason: %s",
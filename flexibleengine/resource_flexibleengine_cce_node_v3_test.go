@@ -1,7 +1,10 @@
 package flexibleengine
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
@@ -9,6 +12,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/chnsz/golangsdk/openstack/cce/v3/nodes"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccCCENodeV3_basic(t *testing.T) {
@@ -87,6 +91,43 @@ func TestAccCCENodeV3_volumes_encryption(t *testing.T) {
 	})
 }
 
+func TestAccCCENodeV3_reset(t *testing.T) {
+	var node nodes.Nodes
+
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_cce_node_v3.node_1"
+	clusterName := "flexibleengine_cce_cluster_v3.cluster_1"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccCCEKeyPairPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckCCENodeV3Destroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCCENodeV3_reset(rName, "EulerOS 2.5", "echo hello"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCCENodeV3Exists(resourceName, clusterName, &node),
+					resource.TestCheckResourceAttr(resourceName, "os", "EulerOS 2.5"),
+					resource.TestCheckResourceAttr(resourceName, "drain_before_reset", "true"),
+					resource.TestCheckResourceAttr(resourceName, "drain_before_delete", "true"),
+					resource.TestCheckResourceAttr(resourceName, "drain_timeout", "600"),
+				),
+			},
+			{
+				// the node is reset in place and keeps its ECS server and private IP
+				Config: testAccCCENodeV3_reset(rName, "EulerOS 2.9", "echo world"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &node.Metadata.Id),
+					resource.TestCheckResourceAttrPtr(resourceName, "server_id", &node.Status.ServerID),
+					resource.TestCheckResourceAttrPtr(resourceName, "private_ip", &node.Status.PrivateIP),
+					resource.TestCheckResourceAttr(resourceName, "os", "EulerOS 2.9"),
+					resource.TestCheckResourceAttr(resourceName, "status", "Active"),
+				),
+			},
+		},
+	})
+}
+
 func testAccCCENodeImportStateIdFunc() resource.ImportStateIdFunc {
 	return func(s *terraform.State) (string, error) {
 		cluster, ok := s.RootModule().Resources["flexibleengine_cce_cluster_v3.cluster_1"]
@@ -176,7 +217,7 @@ func testAccCheckCCENodeV3Exists(n string, cluster string, node *nodes.Nodes) re
 	}
 }
 
-func testAccCCENodeV3_base(rName string) string {
+func testAccCCENodeV3_network(rName string) string {
 	return fmt.Sprintf(`
 data "flexibleengine_availability_zones" "test" {}
 
@@ -199,9 +240,15 @@ resource "flexibleengine_vpc_subnet_v1" "test" {
 resource "flexibleengine_compute_keypair_v2" "test" {
   name = "%[1]s"
 }
+`, rName)
+}
+
+func testAccCCENodeV3_base(rName string) string {
+	return fmt.Sprintf(`
+%[1]s
 
 resource "flexibleengine_cce_cluster_v3" "cluster_1" {
-  name         = "%[1]s"
+  name         = "%[2]s"
   cluster_type = "VirtualMachine"
   flavor_id    = "cce.s1.small"
   vpc_id       = flexibleengine_vpc_v1.test.id
@@ -209,7 +256,7 @@ resource "flexibleengine_cce_cluster_v3" "cluster_1" {
 
   container_network_type = "overlay_l2"
 }
-`, rName)
+`, testAccCCENodeV3_network(rName), rName)
 }
 
 func testAccCCENodeV3_basic(rName string) string {
@@ -303,3 +350,168 @@ resource "flexibleengine_cce_node_v3" "node_1" {
 }
 `, testAccCCENodeV3_base(rName), rName, rName)
 }
+
+func testAccCCENodeV3_reset(rName, os, postinstall string) string {
+	return fmt.Sprintf(`
+%[1]s
+
+resource "flexibleengine_vpc_eip" "test" {
+  publicip {
+    type = "5_bgp"
+  }
+  bandwidth {
+    name       = "%[2]s"
+    size       = 10
+    share_type = "PER"
+  }
+}
+
+resource "flexibleengine_cce_cluster_v3" "cluster_1" {
+  name                   = "%[2]s"
+  cluster_type           = "VirtualMachine"
+  flavor_id              = "cce.s1.small"
+  vpc_id                 = flexibleengine_vpc_v1.test.id
+  subnet_id              = flexibleengine_vpc_subnet_v1.test.id
+  container_network_type = "overlay_l2"
+  eip                    = flexibleengine_vpc_eip.test.address
+}
+
+resource "flexibleengine_cce_node_v3" "node_1" {
+  cluster_id        = flexibleengine_cce_cluster_v3.cluster_1.id
+  name              = "%[2]s"
+  flavor_id         = "s3.large.2"
+  availability_zone = data.flexibleengine_availability_zones.test.names[0]
+  key_pair          = flexibleengine_compute_keypair_v2.test.name
+  os                = "%[3]s"
+  postinstall       = "%[4]s"
+
+  drain_before_reset  = true
+  drain_before_delete = true
+  drain_timeout       = 600
+
+  root_volume {
+    size       = 40
+    volumetype = "SSD"
+  }
+  data_volumes {
+    size       = 100
+    volumetype = "SSD"
+  }
+}`, testAccCCENodeV3_network(rName), rName, os, postinstall)
+}
+
+func TestMockCCENodeV3_resetAndDrain(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	kube := server.startKubernetes(t, clusterID)
+	nodeID := server.addCCENode("node_1", "192.168.0.20", map[string]interface{}{
+		"flavor":      "s3.large.2",
+		"az":          "eu-west-0a",
+		"os":          "EulerOS 2.5",
+		"login":       map[string]interface{}{"sshKey": "key-1"},
+		"rootVolume":  map[string]interface{}{"size": float64(40), "volumetype": "SSD"},
+		"dataVolumes": []interface{}{map[string]interface{}{"size": float64(100), "volumetype": "SSD"}},
+	})
+	kube.addNode("192.168.0.20", "192.168.0.20")
+	kube.addNode("192.168.0.30", "192.168.0.30")
+	// the first eviction of web-1 is refused by its PodDisruptionBudget
+	kube.addPod("default", "web-1", "192.168.0.20", "ReplicaSet", 1)
+	kube.addPod("default", "web-2", "192.168.0.30", "ReplicaSet", 0)
+	kube.addPod("kube-system", "everest-csi-driver", "192.168.0.20", "DaemonSet", 0)
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_node_v3", meta)
+	driver.state = driver.importState(clusterID + "/" + nodeID)
+	th.AssertEquals(t, "EulerOS 2.5", driver.state.Attributes["os"])
+
+	raw := map[string]interface{}{
+		"cluster_id":        clusterID,
+		"name":              "node_1",
+		"flavor_id":         "s3.large.2",
+		"availability_zone": "eu-west-0a",
+		"os":                "EulerOS 2.9",
+		"key_pair":          "key-1",
+		"postinstall":       "echo hello",
+		"root_volume": []interface{}{
+			map[string]interface{}{"size": 40, "volumetype": "SSD"},
+		},
+		"data_volumes": []interface{}{
+			map[string]interface{}{"size": 100, "volumetype": "SSD"},
+		},
+		"drain_before_reset":  true,
+		"drain_before_delete": true,
+		"drain_timeout":       60,
+	}
+	// the node is reset in place with the new OS and scripts
+	th.AssertEquals(t, false, driver.plan(raw).RequiresNew())
+	driver.apply(raw)
+	th.AssertEquals(t, nodeID, driver.state.ID)
+	th.AssertEquals(t, "EulerOS 2.9", driver.state.Attributes["os"])
+
+	node := server.objects["cce_nodes"][nodeID]
+	th.AssertEquals(t, 1, node["__resets"])
+	lifecycle := node["__lifecycle"].(map[string]interface{})
+	th.AssertEquals(t, base64.StdEncoding.EncodeToString([]byte("echo hello")), lifecycle["postInstall"])
+
+	// the pods are evicted before resetting the node except the pods of DaemonSets
+	th.AssertEquals(t, true, kube.nodes["192.168.0.20"]["spec"].(map[string]interface{})["unschedulable"])
+	th.AssertEquals(t, nil, kube.nodes["192.168.0.30"]["spec"].(map[string]interface{})["unschedulable"])
+	th.AssertDeepEquals(t, []string{"default/web-1"}, kube.evicted)
+	_, ok := kube.pods["kube-system/everest-csi-driver"]
+	th.AssertEquals(t, true, ok)
+
+	// the pods with emptyDir volumes are evicted only if drain_delete_emptydir_data is set
+	kube.addPod("default", "web-3", "192.168.0.20", "ReplicaSet", 2)
+	kube.addPod("default", "cache-1", "192.168.0.20", "StatefulSet", 0)
+	kube.pods["default/cache-1"]["spec"].(map[string]interface{})["volumes"] = []interface{}{
+		map[string]interface{}{"name": "cache", "emptyDir": map[string]interface{}{}},
+	}
+	_, diags := driver.resource.Apply(context.Background(), driver.state, &terraform.InstanceDiff{Destroy: true}, meta)
+	th.AssertEquals(t, true, diags.HasError())
+	th.AssertEquals(t, true, strings.Contains(diags[0].Summary, "emptyDir"))
+	th.AssertDeepEquals(t, []string{"default/web-1"}, kube.evicted)
+
+	raw["drain_delete_emptydir_data"] = true
+	driver.apply(raw)
+	driver.destroy()
+	_, ok = server.objects["cce_deleted_nodes"][nodeID]
+	th.AssertEquals(t, true, ok)
+	th.AssertDeepEquals(t, []string{"default/web-1", "default/cache-1", "default/web-3"}, kube.evicted)
+}
+
+func TestMockCCENodeV3_resetWithoutKeyPair(t *testing.T) {
+	server, meta := newMockTest(t)
+	clusterID := server.addCCECluster("cluster_1", "v1.25")
+	nodeID := server.addCCENode("node_1", "192.168.0.20", map[string]interface{}{
+		"flavor":      "s3.large.2",
+		"az":          "eu-west-0a",
+		"os":          "EulerOS 2.5",
+		"login":       map[string]interface{}{"userPassword": map[string]interface{}{"username": "root"}},
+		"rootVolume":  map[string]interface{}{"size": float64(40), "volumetype": "SSD"},
+		"dataVolumes": []interface{}{map[string]interface{}{"size": float64(100), "volumetype": "SSD"}},
+	})
+
+	driver := newMockResourceDriver(t, "flexibleengine_cce_node_v3", meta)
+	driver.state = driver.importState(clusterID + "/" + nodeID)
+	th.AssertEquals(t, "", driver.state.Attributes["key_pair"])
+
+	// the node is reset with the key pair login only
+	raw := map[string]interface{}{
+		"cluster_id":        clusterID,
+		"name":              "node_1",
+		"flavor_id":         "s3.large.2",
+		"availability_zone": "eu-west-0a",
+		"os":                "EulerOS 2.9",
+		"key_pair":          "",
+		"root_volume": []interface{}{
+			map[string]interface{}{"size": 40, "volumetype": "SSD"},
+		},
+		"data_volumes": []interface{}{
+			map[string]interface{}{"size": 100, "volumetype": "SSD"},
+		},
+	}
+	state := driver.state.DeepCopy()
+	state.RawConfig = driver.rawConfig(raw)
+	_, err := driver.resource.Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), meta)
+	th.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), "key_pair is required"))
+	th.AssertEquals(t, 0, server.objects["cce_nodes"][nodeID]["__resets"])
+}
@@ -25,6 +25,14 @@ func TestAccComputeV2BmsInstance_basic(t *testing.T) {
 						"flexibleengine_compute_bms_server_v2.instance_1", "availability_zone", OS_AVAILABILITY_ZONE),
 				),
 			},
+			{
+				ResourceName:      "flexibleengine_compute_bms_server_v2.instance_1",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"admin_pass", "stop_before_destroy",
+				},
+			},
 			{
 				Config: testAccComputeV2BmsInstance_update,
 				Check: resource.ComposeTestCheckFunc(
@@ -0,0 +1,376 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chnsz/golangsdk"
+	"github.com/chnsz/golangsdk/openstack/ecs/v1/cloudservers"
+	"github.com/chnsz/golangsdk/openstack/imageservice/v2/images"
+	"github.com/chnsz/golangsdk/openstack/ims/v2/cloudimages"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceComputeInstanceSnapshot snapshots the system disk and the data disks of an instance
+// as a system image and data images, which can be referenced by the block_device of new instances.
+func resourceComputeInstanceSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceComputeInstanceSnapshotCreate,
+		Read:   resourceComputeInstanceSnapshotRead,
+		Delete: resourceComputeInstanceSnapshotDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceComputeInstanceSnapshotImportState,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"instance_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"include_data_disks": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  true,
+			},
+			"image_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"data_images": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"volume_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"image_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// snapshotDataImageName returns the name of the data image of the volume
+func snapshotDataImageName(name string, index int) string {
+	return fmt.Sprintf("%s-data-%d", name, index)
+}
+
+func resourceComputeInstanceSnapshotCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	ecsClient, err := config.ComputeV1Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine compute v1 client: %s", err)
+	}
+	imsClient, err := config.ImageV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine image client: %s", err)
+	}
+
+	instanceID := d.Get("instance_id").(string)
+	server, err := cloudservers.Get(ecsClient, instanceID).Extract()
+	if err != nil {
+		return fmt.Errorf("Error retrieving compute instance %s: %s", instanceID, err)
+	}
+
+	name := d.Get("name").(string)
+	var dataImages []cloudimages.DataImage
+	if d.Get("include_data_disks").(bool) {
+		for _, volume := range server.VolumeAttached {
+			if volume.BootIndex == "0" {
+				continue
+			}
+			dataImages = append(dataImages, cloudimages.DataImage{
+				Name:     snapshotDataImageName(name, len(dataImages)+1),
+				VolumeId: volume.ID,
+			})
+		}
+	}
+
+	createOpts := cloudimages.CreateByServerOpts{
+		Name:        name,
+		Description: d.Get("description").(string),
+		InstanceId:  instanceID,
+		DataImages:  dataImages,
+	}
+	log.Printf("[DEBUG] Create compute instance snapshot options: %#v", createOpts)
+
+	job, err := cloudimages.CreateImageByServer(imsClient, createOpts).ExtractJobResponse()
+	if err != nil {
+		return fmt.Errorf("Error creating the snapshot of compute instance %s: %s", instanceID, err)
+	}
+
+	jobStatus, err := waitForIMSJobSuccess(config, imsClient, d.Timeout(schema.TimeoutCreate), job.JobID)
+	if err != nil {
+		return fmt.Errorf("Error waiting for the snapshot of compute instance %s to become available: %s",
+			instanceID, err)
+	}
+	d.SetId(jobStatus.Entities.ImageID)
+
+	// the data images are created by the sub jobs, which are matched by the image names
+	dataImageIDs := make(map[string]string)
+	for _, subJob := range jobStatus.Entities.SubJobsResult {
+		dataImageIDs[subJob.Entities.ImageName] = subJob.Entities.ImageID
+	}
+	dataImageList := make([]map[string]interface{}, len(dataImages))
+	for i, dataImage := range dataImages {
+		dataImageID, ok := dataImageIDs[dataImage.Name]
+		if !ok {
+			return fmt.Errorf("Error retrieving the data image of volume %s from job %s", dataImage.VolumeId, job.JobID)
+		}
+		dataImageList[i] = map[string]interface{}{
+			"volume_id": dataImage.VolumeId,
+			"image_id":  dataImageID,
+			"name":      dataImage.Name,
+		}
+	}
+	if err := d.Set("data_images", dataImageList); err != nil {
+		return fmt.Errorf("Error setting data_images: %s", err)
+	}
+
+	return resourceComputeInstanceSnapshotRead(d, meta)
+}
+
+// imsJobStatus is the status of an IMS job, the data images are created by the sub jobs
+type imsJobStatus struct {
+	Status     string `json:"status"`
+	ErrorCode  string `json:"error_code"`
+	FailReason string `json:"fail_reason"`
+	Entities   struct {
+		ImageID       string `json:"image_id"`
+		SubJobsResult []struct {
+			Status   string `json:"status"`
+			Entities struct {
+				ImageID   string `json:"image_id"`
+				ImageName string `json:"image_name"`
+			} `json:"entities"`
+		} `json:"sub_jobs_result"`
+	} `json:"entities"`
+}
+
+// waitForIMSJobSuccess waits for the IMS job to succeed and returns the job status
+func waitForIMSJobSuccess(config *Config, imsClient *golangsdk.ServiceClient, timeout time.Duration,
+	jobID string) (*imsJobStatus, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{"INIT", "RUNNING"},
+		Target:       []string{"SUCCESS"},
+		Refresh:      imsJobStateRefreshFunc(imsClient, jobID),
+		Timeout:      timeout,
+		Delay:        5 * time.Second,
+		PollInterval: 5 * time.Second,
+	}
+	job, err := waitForState(config, stateConf)
+	if err != nil {
+		return nil, err
+	}
+	return job.(*imsJobStatus), nil
+}
+
+func imsJobStateRefreshFunc(imsClient *golangsdk.ServiceClient, jobID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		// the jobs are queried by the v1 API: v1/{project_id}/jobs/{job_id}
+		jobURL := imsClient.Endpoint + "v1/" + imsClient.ProjectID + "/jobs/" + jobID
+		job := new(imsJobStatus)
+		_, err := imsClient.Get(jobURL, job, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if job.Status == "FAIL" {
+			return job, job.Status, fmt.Errorf("job %s failed with code %s: %s", jobID, job.ErrorCode, job.FailReason)
+		}
+		return job, job.Status, nil
+	}
+}
+
+// getSnapshotImage returns the image of the snapshot, nil is returned if it does not exist
+func getSnapshotImage(imsClient *golangsdk.ServiceClient, id string) (*cloudimages.Image, error) {
+	pages, err := cloudimages.List(imsClient, cloudimages.ListOpts{ID: id}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("Error retrieving image %s: %s", id, err)
+	}
+	allImages, err := cloudimages.ExtractImages(pages)
+	if err != nil {
+		return nil, fmt.Errorf("Error retrieving image %s: %s", id, err)
+	}
+	if len(allImages) == 0 {
+		return nil, nil
+	}
+	return &allImages[0], nil
+}
+
+// parseImageDataOrigin returns the ID of the instance or volume which the image is created from,
+// the origin is in the format of "instance,<instance_id>" or "volume,<volume_id>".
+func parseImageDataOrigin(origin, kind string) string {
+	if parts := strings.SplitN(origin, ",", 2); len(parts) == 2 && parts[0] == kind {
+		return parts[1]
+	}
+	return ""
+}
+
+func resourceComputeInstanceSnapshotRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	region := GetRegion(d, config)
+	imsClient, err := config.ImageV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine image client: %s", err)
+	}
+
+	img, err := getSnapshotImage(imsClient, d.Id())
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		log.Printf("[WARN] compute instance snapshot %s is not found, removing it from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	log.Printf("[DEBUG] Retrieved compute instance snapshot %s: %#v", d.Id(), img)
+
+	d.Set("region", region)
+	d.Set("name", img.Name)
+	d.Set("description", img.Description)
+	d.Set("image_id", img.ID)
+	d.Set("status", img.Status)
+	if instanceID := parseImageDataOrigin(img.DataOrigin, "instance"); instanceID != "" {
+		d.Set("instance_id", instanceID)
+	}
+
+	// the data images which are deleted out of band are removed
+	dataImageList := make([]map[string]interface{}, 0)
+	for _, raw := range d.Get("data_images").([]interface{}) {
+		dataImageID := raw.(map[string]interface{})["image_id"].(string)
+		dataImage, err := getSnapshotImage(imsClient, dataImageID)
+		if err != nil {
+			return err
+		}
+		if dataImage == nil {
+			log.Printf("[WARN] data image %s of compute instance snapshot %s is not found", dataImageID, d.Id())
+			continue
+		}
+		dataImageList = append(dataImageList, map[string]interface{}{
+			"volume_id": parseImageDataOrigin(dataImage.DataOrigin, "volume"),
+			"image_id":  dataImage.ID,
+			"name":      dataImage.Name,
+		})
+	}
+	if err := d.Set("data_images", dataImageList); err != nil {
+		return fmt.Errorf("Error setting data_images: %s", err)
+	}
+
+	return nil
+}
+
+// resourceComputeInstanceSnapshotImportState imports the snapshot by the ID of the system image,
+// followed by the IDs of the data images separated by commas.
+func resourceComputeInstanceSnapshotImportState(d *schema.ResourceData,
+	_ interface{}) ([]*schema.ResourceData, error) {
+	ids := strings.Split(d.Id(), ",")
+	dataImageList := make([]map[string]interface{}, 0, len(ids)-1)
+	for _, id := range ids[1:] {
+		dataImageList = append(dataImageList, map[string]interface{}{"image_id": id})
+	}
+	d.SetId(ids[0])
+	d.Set("include_data_disks", true)
+	if err := d.Set("data_images", dataImageList); err != nil {
+		return nil, fmt.Errorf("Error setting data_images: %s", err)
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceComputeInstanceSnapshotDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	imsClient, err := config.ImageV2Client(GetRegion(d, config))
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine image client: %s", err)
+	}
+
+	imageIDs := []string{}
+	for _, raw := range d.Get("data_images").([]interface{}) {
+		imageIDs = append(imageIDs, raw.(map[string]interface{})["image_id"].(string))
+	}
+	imageIDs = append(imageIDs, d.Id())
+
+	var errs []string
+	for _, id := range imageIDs {
+		log.Printf("[DEBUG] Deleting image %s of compute instance snapshot %s", id, d.Id())
+		if err := images.Delete(imsClient, id).Err; err != nil {
+			if _, ok := err.(golangsdk.ErrDefault404); !ok {
+				errs = append(errs, fmt.Sprintf("%s: %s", id, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error deleting the images of compute instance snapshot %s: %s", d.Id(), strings.Join(errs, "; "))
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"DELETING"},
+		Target:     []string{"DELETED"},
+		Refresh:    snapshotImagesDeleteRefreshFunc(imsClient, imageIDs),
+		Timeout:    d.Timeout(schema.TimeoutDelete),
+		Delay:      5 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+	if _, err := waitForState(config, stateConf); err != nil {
+		return fmt.Errorf("Error waiting for the images of compute instance snapshot %s to be deleted: %s", d.Id(), err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// snapshotImagesDeleteRefreshFunc returns "DELETING" until all the images are deleted
+func snapshotImagesDeleteRefreshFunc(imsClient *golangsdk.ServiceClient, imageIDs []string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		for _, id := range imageIDs {
+			img, err := getSnapshotImage(imsClient, id)
+			if err != nil {
+				return nil, "", err
+			}
+			if img != nil {
+				return img, "DELETING", nil
+			}
+		}
+		return imageIDs, "DELETED", nil
+	}
+}
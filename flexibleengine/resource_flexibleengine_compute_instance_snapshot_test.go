@@ -0,0 +1,190 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
+	"github.com/chnsz/golangsdk/openstack/imageservice/v2/images"
+	th "github.com/chnsz/golangsdk/testhelper"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccComputeInstanceSnapshot_basic(t *testing.T) {
+	var instance servers.Server
+	resourceName := "flexibleengine_compute_instance_snapshot.snapshot_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccCheckComputeInstanceSnapshotDestroy,
+			testAccCheckComputeV2InstanceDestroy,
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeInstanceSnapshot_basic(false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "name", "snapshot_1"),
+					resource.TestCheckResourceAttr(resourceName, "status", "active"),
+					resource.TestCheckResourceAttr(resourceName, "data_images.#", "1"),
+					resource.TestCheckResourceAttrPair(resourceName, "data_images.0.volume_id",
+						"flexibleengine_blockstorage_volume_v2.volume_1", "id"),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateIdFunc: testAccComputeInstanceSnapshotImportStateIdFunc(resourceName),
+			},
+			{
+				Config: testAccComputeInstanceSnapshot_basic(true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists("flexibleengine_compute_instance_v2.restored", &instance),
+					resource.TestCheckResourceAttr("flexibleengine_compute_instance_v2.restored", "volume_attached.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+// testAccComputeInstanceSnapshotImportStateIdFunc returns the IDs of the system image and the data images
+func testAccComputeInstanceSnapshotImportStateIdFunc(name string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", name)
+		}
+		return fmt.Sprintf("%s,%s", rs.Primary.ID, rs.Primary.Attributes["data_images.0.image_id"]), nil
+	}
+}
+
+func testAccCheckComputeInstanceSnapshotDestroy(s *terraform.State) error {
+	config := testAccProvider.Meta().(*Config)
+	imageClient, err := config.ImageV2Client(OS_REGION_NAME)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine image client: %s", err)
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "flexibleengine_compute_instance_snapshot" {
+			continue
+		}
+
+		if _, err := images.Get(imageClient, rs.Primary.ID).Extract(); err == nil {
+			return fmt.Errorf("Compute instance snapshot still exists")
+		}
+	}
+
+	return nil
+}
+
+func testAccComputeInstanceSnapshot_basic(restore bool) string {
+	var restored string
+	if restore {
+		restored = fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "restored" {
+  name              = "restored"
+  security_groups   = ["default"]
+  availability_zone = "%s"
+
+  block_device {
+    uuid                  = flexibleengine_compute_instance_snapshot.snapshot_1.image_id
+    source_type           = "image"
+    destination_type      = "volume"
+    volume_size           = 40
+    boot_index            = 0
+    delete_on_termination = true
+  }
+
+  block_device {
+    uuid                  = flexibleengine_compute_instance_snapshot.snapshot_1.data_images[0].image_id
+    source_type           = "image"
+    destination_type      = "volume"
+    volume_size           = 10
+    boot_index            = -1
+    delete_on_termination = true
+  }
+
+  network {
+    uuid = "%s"
+  }
+}
+`, OS_AVAILABILITY_ZONE, OS_NETWORK_ID)
+	}
+
+	return fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name              = "instance_1"
+  image_id          = "%s"
+  security_groups   = ["default"]
+  availability_zone = "%s"
+
+  network {
+    uuid = "%s"
+  }
+}
+
+resource "flexibleengine_blockstorage_volume_v2" "volume_1" {
+  name              = "volume_1"
+  size              = 10
+  availability_zone = "%s"
+}
+
+resource "flexibleengine_compute_volume_attach_v2" "va_1" {
+  instance_id = flexibleengine_compute_instance_v2.instance_1.id
+  volume_id   = flexibleengine_blockstorage_volume_v2.volume_1.id
+}
+
+resource "flexibleengine_compute_instance_snapshot" "snapshot_1" {
+  name        = "snapshot_1"
+  instance_id = flexibleengine_compute_volume_attach_v2.va_1.instance_id
+}
+%s
+`, OS_IMAGE_ID, OS_AVAILABILITY_ZONE, OS_NETWORK_ID, OS_AVAILABILITY_ZONE, restored)
+}
+
+func TestMockComputeInstanceSnapshot(t *testing.T) {
+	server, meta := newMockTest(t)
+	instance := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+	instance.apply(mockComputeInstanceConfig("instance_1"))
+
+	// attach a data disk to the instance
+	volume := server.createObject("volumes", map[string]interface{}{"name": "volume_1", "size": 10})
+	volume["status"] = "in-use"
+	object := server.objects["servers"][instance.state.ID]
+	object["volumes"] = append(object["volumes"].([]string), volume["id"].(string))
+
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_snapshot", meta)
+	driver.apply(map[string]interface{}{
+		"name":        "snapshot_1",
+		"instance_id": instance.state.ID,
+		"description": "created by mock test",
+	})
+
+	state := driver.state
+	th.AssertEquals(t, state.ID, state.Attributes["image_id"])
+	th.AssertEquals(t, "active", state.Attributes["status"])
+	th.AssertEquals(t, "1", state.Attributes["data_images.#"])
+	th.AssertEquals(t, volume["id"], state.Attributes["data_images.0.volume_id"])
+	th.AssertEquals(t, "snapshot_1-data-1", state.Attributes["data_images.0.name"])
+	dataImageID := state.Attributes["data_images.0.image_id"]
+	th.AssertEquals(t, "volume,"+volume["id"].(string), server.objects["images"][dataImageID]["__data_origin"])
+
+	// the snapshot is imported with its data images
+	imported := driver.importState(state.ID + "," + dataImageID)
+	for _, k := range []string{"name", "instance_id", "description", "include_data_disks", "data_images.#",
+		"data_images.0.volume_id", "data_images.0.image_id", "data_images.0.name"} {
+		th.AssertEquals(t, state.Attributes[k], imported.Attributes[k])
+	}
+
+	// the data image deleted out of band is removed from the state
+	delete(server.objects["images"], dataImageID)
+	th.AssertEquals(t, "0", driver.refresh().Attributes["data_images.#"])
+
+	driver.destroy()
+	th.AssertEquals(t, 1, len(server.objects["images"]))
+	instance.destroy()
+}
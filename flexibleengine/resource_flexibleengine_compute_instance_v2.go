@@ -2,7 +2,9 @@ package flexibleengine
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -34,6 +36,8 @@ func resourceComputeInstanceV2() *schema.Resource {
 			State: resourceComputeInstanceV2ImportState,
 		},
 
+		CustomizeDiff: resourceComputeInstanceV2CustomizeDiff,
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(30 * time.Minute),
 			Update: schema.DefaultTimeout(30 * time.Minute),
@@ -53,18 +57,23 @@ func resourceComputeInstanceV2() *schema.Resource {
 				Required: true,
 				ForceNew: false,
 			},
+			// image_id and image_name are ForceNew unless rebuild_on_image_change is true,
+			// see resourceComputeInstanceV2CustomizeDiff
 			"image_id": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 			},
 			"image_name": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 			},
+			"rebuild_on_image_change": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"flavor_id": {
 				Type:         schema.TypeString,
 				Optional:     true,
@@ -110,42 +119,38 @@ func resourceComputeInstanceV2() *schema.Resource {
 				ForceNew: true,
 				Computed: true,
 			},
+			// the NICs are attached and detached in place, see customizeInstanceNetworksDiff
 			"network": {
 				Type:     schema.TypeList,
 				Optional: true,
-				ForceNew: true,
+				Computed: true,
 				MaxItems: 12,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"uuid": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 							Computed: true,
 						},
 						"name": {
 							Type:       schema.TypeString,
 							Optional:   true,
-							ForceNew:   true,
 							Computed:   true,
 							Deprecated: "use uuid instead",
 						},
 						"port": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 							Computed: true,
 						},
 						"fixed_ip_v4": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 							Computed: true,
 						},
 						"fixed_ip_v6": {
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
 							Computed: true,
 						},
 						"mac": {
@@ -332,6 +337,47 @@ func resourceComputeInstanceV2() *schema.Resource {
 				Optional: true,
 				Computed: true,
 			},
+			"power_action": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					"ON", "OFF", "REBOOT", "FORCE-OFF", "FORCE-REBOOT",
+				}, false),
+			},
+			"detect_power_drift": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"charging_mode": schemaChargingMode(),
+			"period_unit":   schemaPeriodUnit(),
+			"period":        schemaPeriod(),
+			"auto_renew":    schemaAutoRenew(),
+			"spot_price": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"charging_mode", "block_device", "personality", "scheduler_hints"},
+			},
+			"system_disk_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"spot_duration": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"spot_price"},
+				ValidateFunc: validation.IntBetween(1, 6),
+			},
+			"interruption_policy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"spot_price"},
+				ValidateFunc: validation.StringInSlice([]string{"immediate"}, false),
+			},
 			"tags": {
 				Type:     schema.TypeMap,
 				Optional: true,
@@ -361,6 +407,10 @@ func resourceComputeInstanceV2() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"power_state": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 			"volume_attached": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -424,7 +474,7 @@ func resourceComputeInstanceV2Create(d *schema.ResourceData, meta interface{}) e
 	}
 
 	// Build a []servers.Network to pass into the create options.
-	networks, err := expandInstanceNetworks(d, meta)
+	networks, err := expandInstanceNetworks(d, config)
 	if err != nil {
 		return err
 	}
@@ -478,49 +528,70 @@ func resourceComputeInstanceV2Create(d *schema.ResourceData, meta interface{}) e
 
 	// If a block_device is used, use the bootfromvolume.Create function as it allows an empty ImageRef.
 	// Otherwise, use the normal servers.Create function.
-	var server *servers.Server
-	if _, ok := d.GetOk("block_device"); ok {
-		server, err = bootfromvolume.Create(computeClient, createOpts).Extract()
+	var serverID string
+	if isSpotInstance(d) {
+		serverID, err = createSpotComputeInstance(d, config, imageId, flavorId, networks)
+		if err != nil {
+			return err
+		}
+	} else if isPrePaid(d) {
+		serverID, err = createPrePaidComputeInstance(d, config, imageId, flavorId, networks)
+		if err != nil {
+			return err
+		}
 	} else {
-		server, err = servers.Create(computeClient, createOpts).Extract()
-	}
-
-	if err != nil {
-		return fmt.Errorf("Error creating FlexibleEngine server: %s", err)
+		var server *servers.Server
+		if _, ok := d.GetOk("block_device"); ok {
+			server, err = bootfromvolume.Create(computeClient, createOpts).Extract()
+		} else {
+			server, err = servers.Create(computeClient, createOpts).Extract()
+		}
+		if err != nil {
+			return fmt.Errorf("Error creating FlexibleEngine server: %s", err)
+		}
+		serverID = server.ID
 	}
-	log.Printf("[INFO] Instance ID: %s", server.ID)
+	log.Printf("[INFO] Instance ID: %s", serverID)
 
 	// Store the ID now
-	d.SetId(server.ID)
+	d.SetId(serverID)
 
 	// Wait for the instance to become running so we can get some attributes
 	// that aren't available until later.
 	log.Printf(
 		"[DEBUG] Waiting for instance (%s) to become running",
-		server.ID)
+		serverID)
 
 	stateConf := &resource.StateChangeConf{
 		Pending:    []string{"BUILD"},
 		Target:     []string{"ACTIVE"},
-		Refresh:    computeV2StateRefreshFunc(computeClient, server.ID),
+		Refresh:    computeV2StateRefreshFunc(computeClient, serverID),
 		Timeout:    d.Timeout(schema.TimeoutCreate),
 		Delay:      10 * time.Second,
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf(
 			"Error waiting for instance (%s) to become ready: %s",
-			server.ID, err)
+			serverID, err)
+	}
+
+	// the ECS API which creates the spot and prePaid instances does not accept the metadata
+	if (isSpotInstance(d) || isPrePaid(d)) && hasFilledOpt(d, "metadata") {
+		metadataOpts := servers.MetadataOpts(resourceComputeMetadataV2(d))
+		if _, err := servers.UpdateMetadata(computeClient, serverID, metadataOpts).Extract(); err != nil {
+			return fmt.Errorf("Error setting FlexibleEngine server (%s) metadata: %s", serverID, err)
+		}
 	}
 
 	if hasFilledOpt(d, "auto_recovery") {
 		ar := d.Get("auto_recovery").(bool)
 		log.Printf("[DEBUG] Set auto recovery of instance to %t", ar)
-		err = setAutoRecoveryForInstance(d, meta, server.ID, ar)
+		err = setAutoRecoveryForInstance(d, config, serverID, ar)
 		if err != nil {
-			log.Printf("[WARN] Error setting auto recovery of instance:%s, err=%s", server.ID, err)
+			log.Printf("[WARN] Error setting auto recovery of instance:%s, err=%s", serverID, err)
 		}
 	}
 
@@ -532,9 +603,21 @@ func resourceComputeInstanceV2Create(d *schema.ResourceData, meta interface{}) e
 
 		tagmap := d.Get("tags").(map[string]interface{})
 		log.Printf("[DEBUG] Setting tags(key/value): %v", tagmap)
-		err = setTagsForInstance(ecsClient, server.ID, tagmap)
+		err = setTagsForInstance(ecsClient, serverID, tagmap)
 		if err != nil {
-			log.Printf("[WARN] Error setting tags(key/value) of instance:%s, err=%s", server.ID, err)
+			log.Printf("[WARN] Error setting tags(key/value) of instance:%s, err=%s", serverID, err)
+		}
+	}
+
+	// the new instance is running, so only the actions which stop it are done
+	if action := d.Get("power_action").(string); action != "" && powerStateOfAction(action) == "OFF" {
+		ecsClient, err := config.ComputeV1Client(GetRegion(d, config))
+		if err != nil {
+			return fmt.Errorf("Error creating FlexibleEngine compute v1 client: %s", err)
+		}
+		err = doInstancePowerAction(config, ecsClient, computeClient, serverID, action, d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
 		}
 	}
 
@@ -555,6 +638,9 @@ func resourceComputeInstanceV2Read(d *schema.ResourceData, meta interface{}) err
 	}
 
 	server, err := cloudservers.Get(ecsClient, d.Id()).Extract()
+	if checkSpotInstanceReclaimed(d, server, err) {
+		return nil
+	}
 	if err != nil {
 		return CheckDeleted(d, err, "server")
 	}
@@ -566,9 +652,26 @@ func resourceComputeInstanceV2Read(d *schema.ResourceData, meta interface{}) err
 	d.Set("name", server.Name)
 	d.Set("status", server.Status)
 
+	// the instance stopped or started by hand is not a drift unless detect_power_drift is true
+	powerState := computePowerState(server.Status)
+	d.Set("power_state", powerState)
+	if action := d.Get("power_action").(string); action != "" && d.Get("detect_power_drift").(bool) {
+		if (powerState == "ON" || powerState == "OFF") && powerState != powerStateOfAction(action) {
+			log.Printf("[DEBUG] the power state of instance %s is %s, expected by power_action %s",
+				d.Id(), powerState, action)
+			d.Set("power_action", powerState)
+		}
+	}
+
 	flavorInfo := server.Flavor
 	d.Set("flavor_id", flavorInfo.ID)
 	d.Set("flavor_name", flavorInfo.Name)
+	d.Set("charging_mode", normalizeChargingMode(server.Metadata.ChargingMode))
+	if isPrePaid(d) {
+		if err := setPrePaidInfo(d, config, d.Id()); err != nil {
+			return err
+		}
+	}
 
 	// Set the instance's image information appropriately
 	if err := setInstanceImageInfo(d, imsClient, server.Image.ID); err != nil {
@@ -583,7 +686,7 @@ func resourceComputeInstanceV2Read(d *schema.ResourceData, meta interface{}) err
 	}
 
 	// Get the instance network and address information
-	networks, err := flattenInstanceNetworks(d, meta, server)
+	networks, err := flattenInstanceNetworks(d, config, server)
 	if err != nil {
 		return err
 	}
@@ -683,13 +786,13 @@ func resourceComputeInstanceV2Read(d *schema.ResourceData, meta interface{}) err
 	}
 	d.Set("metadata", novaResp.Metadata)
 
-	ar, err := resourceECSAutoRecoveryV1Read(d, meta, d.Id())
+	ar, err := resourceECSAutoRecoveryV1Read(d, config, d.Id())
 	if err != nil && !isResourceNotFound(err) {
 		return fmt.Errorf("Error reading auto recovery of instance:%s, err=%s", d.Id(), err)
 	}
 	d.Set("auto_recovery", ar)
 
-	tags, err := resourceECSTagsV1Read(d, meta, d.Id())
+	tags, err := resourceECSTagsV1Read(d, config, d.Id())
 	if err != nil && !isResourceNotFound(err) {
 		return fmt.Errorf("Error reading tags of instance:%s, err=%s", d.Id(), err)
 	}
@@ -715,6 +818,21 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 		}
 	}
 
+	// the system disk is rebuilt first, so the other changes are applied to the new OS
+	rebuilt := false
+	if d.HasChanges("image_id", "image_name") {
+		if err := rebuildComputeInstanceV2(d, config); err != nil {
+			return err
+		}
+		rebuilt = true
+	}
+
+	if d.HasChange("network") {
+		if err := updateInstanceNetworks(d, config); err != nil {
+			return err
+		}
+	}
+
 	if d.HasChange("metadata") {
 		oldMetadata, newMetadata := d.GetChange("metadata")
 		var metadataToDelete []string
@@ -786,7 +904,8 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 		}
 	}
 
-	if d.HasChange("admin_pass") {
+	// the password has been set by rebuilding
+	if d.HasChange("admin_pass") && !rebuilt {
 		if newPwd, ok := d.Get("admin_pass").(string); ok {
 			err := servers.ChangeAdminPassword(computeClient, d.Id(), newPwd).ExtractErr()
 			if err != nil {
@@ -829,7 +948,7 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 			MinTimeout: 3 * time.Second,
 		}
 
-		_, err = stateConf.WaitForState()
+		_, err = waitForState(config, stateConf)
 		if err != nil {
 			return fmt.Errorf("Error waiting for instance (%s) to resize: %s", d.Id(), err)
 		}
@@ -850,7 +969,7 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 			MinTimeout: 3 * time.Second,
 		}
 
-		_, err = stateConf.WaitForState()
+		_, err = waitForState(config, stateConf)
 		if err != nil {
 			return fmt.Errorf("Error waiting for instance (%s) to confirm resize: %s", d.Id(), err)
 		}
@@ -859,7 +978,7 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 	if d.HasChange("auto_recovery") {
 		ar := d.Get("auto_recovery").(bool)
 		log.Printf("[DEBUG] Update auto recovery of instance to %t", ar)
-		err = setAutoRecoveryForInstance(d, meta, d.Id(), ar)
+		err = setAutoRecoveryForInstance(d, config, d.Id(), ar)
 		if err != nil {
 			return fmt.Errorf("Error updating auto recovery of instance:%s, err:%s", d.Id(), err)
 		}
@@ -892,6 +1011,28 @@ func resourceComputeInstanceV2Update(d *schema.ResourceData, meta interface{}) e
 		}
 	}
 
+	if d.HasChanges("charging_mode", "auto_renew") {
+		if err := updateChargingMode(d, config, []string{d.Id()}); err != nil {
+			return err
+		}
+	}
+
+	if action := d.Get("power_action").(string); action != "" && d.HasChanges("power_action", "detect_power_drift") {
+		// the power state is also converged once detect_power_drift is enabled, but never rebooted
+		if !d.HasChange("power_action") {
+			action = powerStateOfAction(action)
+		}
+
+		ecsClient, err := config.ComputeV1Client(GetRegion(d, config))
+		if err != nil {
+			return fmt.Errorf("Error creating FlexibleEngine compute v1 client: %s", err)
+		}
+		err = doInstancePowerAction(config, ecsClient, computeClient, d.Id(), action, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+	}
+
 	return resourceComputeInstanceV2Read(d, meta)
 }
 
@@ -916,7 +1057,7 @@ func resourceComputeInstanceV2Delete(d *schema.ResourceData, meta interface{}) e
 				MinTimeout: 3 * time.Second,
 			}
 			log.Printf("[DEBUG] Waiting for instance (%s) to stop", d.Id())
-			_, err = stopStateConf.WaitForState()
+			_, err = waitForState(config, stopStateConf)
 			if err != nil {
 				log.Printf("[WARN] Error waiting for instance (%s) to stop: %s, proceeding to delete", d.Id(), err)
 			}
@@ -924,7 +1065,11 @@ func resourceComputeInstanceV2Delete(d *schema.ResourceData, meta interface{}) e
 	}
 
 	log.Printf("[DEBUG] Deleting FlexibleEngine Instance %s", d.Id())
-	err = servers.Delete(computeClient, d.Id()).ExtractErr()
+	if isPrePaid(d) {
+		err = unsubscribePrePaid(d, config, []string{d.Id()})
+	} else {
+		err = servers.Delete(computeClient, d.Id()).ExtractErr()
+	}
 	if err != nil {
 		return fmt.Errorf("Error deleting FlexibleEngine server: %s", err)
 	}
@@ -941,7 +1086,7 @@ func resourceComputeInstanceV2Delete(d *schema.ResourceData, meta interface{}) e
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf(
 			"Error waiting for instance (%s) to delete: %s",
@@ -952,6 +1097,113 @@ func resourceComputeInstanceV2Delete(d *schema.ResourceData, meta interface{}) e
 	return nil
 }
 
+// resourceComputeInstanceV2CustomizeDiff plans the changes of the charging mode, the spot and prePaid instances,
+// the image and the NICs.
+func resourceComputeInstanceV2CustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if err := customizeChargingModeDiff(d); err != nil {
+		return err
+	}
+	if err := customizeCloudServerInstanceDiff(d); err != nil {
+		return err
+	}
+	if d.Id() == "" {
+		return nil
+	}
+
+	if err := customizeInstanceImageDiff(d); err != nil {
+		return err
+	}
+	return customizeInstanceNetworksDiff(d)
+}
+
+// customizeInstanceImageDiff replaces the instance when the image is changed,
+// unless rebuild_on_image_change is true, in which case the OS is changed in place.
+func customizeInstanceImageDiff(d *schema.ResourceDiff) error {
+	if !d.Get("rebuild_on_image_change").(bool) {
+		for _, key := range []string{"image_id", "image_name"} {
+			if d.HasChange(key) {
+				if err := d.ForceNew(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// the image ID is looked up again by the new name when it is not specified
+	if d.HasChange("image_name") && !d.HasChange("image_id") {
+		return d.SetNewComputed("image_id")
+	}
+	return nil
+}
+
+// rebuildComputeInstanceV2 changes the OS of the instance to the new image, the server ID,
+// NICs, EIPs and data volumes are kept.
+func rebuildComputeInstanceV2(d *schema.ResourceData, config *Config) error {
+	region := GetRegion(d, config)
+	ecsClient, err := config.ComputeV1Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine compute v1 client: %s", err)
+	}
+	imsClient, err := config.ImageV2Client(region)
+	if err != nil {
+		return fmt.Errorf("Error creating FlexibleEngine image client: %s", err)
+	}
+
+	imageID, err := getInstanceImageID(imsClient, d)
+	if err != nil {
+		return err
+	}
+	if imageID == "" {
+		return fmt.Errorf("the image of instance %s which boots from a volume can not be changed", d.Id())
+	}
+
+	changeOpts := map[string]interface{}{
+		"imageid": imageID,
+		// the instance is stopped before changing the OS and started afterwards
+		"mode": "withStopServer",
+	}
+	if v := d.Get("admin_pass").(string); v != "" {
+		changeOpts["adminpass"] = v
+	}
+	if v := d.Get("key_pair").(string); v != "" {
+		changeOpts["keyname"] = v
+	}
+	// the state only stores the hash of user_data, so read it from the configuration
+	if raw := d.GetRawConfig(); raw.IsKnown() && !raw.IsNull() {
+		if v := raw.GetAttr("user_data"); v.IsKnown() && !v.IsNull() && v.AsString() != "" {
+			changeOpts["metadata"] = map[string]interface{}{"user_data": encodeUserData(v.AsString())}
+		}
+	}
+
+	log.Printf("[DEBUG] Changing the OS of instance %s to image %s", d.Id(), imageID)
+	r := golangsdk.Result{}
+	_, r.Err = ecsClient.Post(ecsClient.ServiceURL("cloudservers", d.Id(), "changeos"),
+		map[string]interface{}{"os-change": changeOpts}, &r.Body, &golangsdk.RequestOpts{
+			OkCodes: []int{200},
+		})
+	if r.Err != nil {
+		return fmt.Errorf("Error changing the OS of FlexibleEngine instance %s: %s", d.Id(), r.Err)
+	}
+
+	jobID, err := navigateValue(r.Body, []string{"job_id"}, nil)
+	if err != nil {
+		return fmt.Errorf("Error getting the job of changing the OS: %s", err)
+	}
+	if _, err := waitForECSJobSuccess(config, ecsClient, d.Timeout(schema.TimeoutUpdate), jobID.(string)); err != nil {
+		return fmt.Errorf("Error waiting for the OS of instance %s to be changed: %s", d.Id(), err)
+	}
+	return nil
+}
+
+// encodeUserData encodes the user data with base64 if it is not encoded yet
+func encodeUserData(userData string) string {
+	if _, err := base64.StdEncoding.DecodeString(userData); err != nil {
+		return base64.StdEncoding.EncodeToString([]byte(userData))
+	}
+	return userData
+}
+
 func resourceComputeInstanceV2ImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	config := meta.(*Config)
 	ecsClient, err := config.ComputeV1Client(GetRegion(d, config))
@@ -964,7 +1216,7 @@ func resourceComputeInstanceV2ImportState(d *schema.ResourceData, meta interface
 		return nil, CheckDeleted(d, err, "compute instance")
 	}
 
-	allNetworks, _ := flattenComputeNetworks(d, meta, server)
+	allNetworks, _ := flattenComputeNetworks(d, config, server)
 	log.Printf("[DEBUG] flatten Instance Networks: %#v", allNetworks)
 
 	if err := d.Set("network", allNetworks); err != nil {
@@ -1,7 +1,9 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -12,6 +14,7 @@ import (
 	"github.com/chnsz/golangsdk/openstack/compute/v2/servers"
 	"github.com/chnsz/golangsdk/openstack/networking/v2/extensions/security/groups"
 	"github.com/chnsz/golangsdk/pagination"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccComputeV2Instance_basic(t *testing.T) {
@@ -297,6 +300,157 @@ func TestAccComputeV2Instance_auto_recovery(t *testing.T) {
 	})
 }
 
+func TestAccComputeV2Instance_powerAction(t *testing.T) {
+	var instance servers.Server
+	resourceName := "flexibleengine_compute_instance_v2.instance_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeV2Instance_powerAction("OFF"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance),
+					resource.TestCheckResourceAttr(resourceName, "power_action", "OFF"),
+					resource.TestCheckResourceAttr(resourceName, "power_state", "OFF"),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_powerAction("ON"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance),
+					resource.TestCheckResourceAttr(resourceName, "power_state", "ON"),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_powerAction("FORCE-REBOOT"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance),
+					resource.TestCheckResourceAttr(resourceName, "power_state", "ON"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccComputeV2Instance_networks(t *testing.T) {
+	var instance_1, instance_2 servers.Server
+	resourceName := "flexibleengine_compute_instance_v2.instance_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeV2Instance_networks(false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_1),
+					resource.TestCheckResourceAttr(resourceName, "network.#", "1"),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_networks(true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_2),
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &instance_1.ID),
+					resource.TestCheckResourceAttr(resourceName, "network.#", "2"),
+					resource.TestCheckResourceAttrPair(resourceName, "network.1.uuid",
+						"flexibleengine_vpc_subnet_v1.subnet_2", "id"),
+					resource.TestCheckResourceAttr(resourceName, "network.1.fixed_ip_v4", "192.168.10.20"),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_networks(false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_2),
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &instance_1.ID),
+					resource.TestCheckResourceAttr(resourceName, "network.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccComputeV2Instance_prePaid(t *testing.T) {
+	var instance_1, instance_2 servers.Server
+	resourceName := "flexibleengine_compute_instance_v2.instance_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeV2Instance_prePaid("postPaid"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_1),
+					resource.TestCheckResourceAttr(resourceName, "charging_mode", "postPaid"),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_prePaid("prePaid"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_2),
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &instance_1.ID),
+					resource.TestCheckResourceAttr(resourceName, "charging_mode", "prePaid"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccComputeV2Instance_spot(t *testing.T) {
+	var instance servers.Server
+	resourceName := "flexibleengine_compute_instance_v2.instance_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeV2Instance_spot,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance),
+					resource.TestCheckResourceAttr(resourceName, "charging_mode", "spot"),
+					resource.TestCheckResourceAttr(resourceName, "spot_duration", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccComputeV2Instance_rebuildOnImageChange(t *testing.T) {
+	var instance_1, instance_2 servers.Server
+	resourceName := "flexibleengine_compute_instance_v2.instance_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeV2InstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccComputeV2Instance_rebuildOnImageChange("data.flexibleengine_images_image.ubuntu.id"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_1),
+				),
+			},
+			{
+				Config: testAccComputeV2Instance_rebuildOnImageChange("data.flexibleengine_images_image.centos.id"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckComputeV2InstanceExists(resourceName, &instance_2),
+					resource.TestCheckResourceAttrPair(resourceName, "image_id",
+						"data.flexibleengine_images_image.centos", "id"),
+					resource.TestCheckResourceAttrPtr(resourceName, "id", &instance_1.ID),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckComputeV2InstanceDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	computeClient, err := config.ComputeV2Client(OS_REGION_NAME)
@@ -969,3 +1123,457 @@ resource "flexibleengine_compute_instance_v2" "instance_1" {
   auto_recovery = true
 }
 `, OS_AVAILABILITY_ZONE, OS_NETWORK_ID)
+
+func testAccComputeV2Instance_powerAction(action string) string {
+	return fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name = "instance_1"
+  security_groups = ["default"]
+  availability_zone = "%s"
+  power_action = "%s"
+  network {
+    uuid = "%s"
+  }
+}
+`, OS_AVAILABILITY_ZONE, action, OS_NETWORK_ID)
+}
+
+func testAccComputeV2Instance_rebuildOnImageChange(imageID string) string {
+	return fmt.Sprintf(`
+data "flexibleengine_images_image" "ubuntu" {
+  name        = "OBS Ubuntu 20.04"
+  most_recent = true
+}
+
+data "flexibleengine_images_image" "centos" {
+  name        = "OBS CentOS 7.9"
+  most_recent = true
+}
+
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name                    = "instance_1"
+  image_id                = %s
+  security_groups         = ["default"]
+  availability_zone       = "%s"
+  admin_pass              = "Terraform@123"
+  user_data               = "#!/bin/bash\necho hello"
+  rebuild_on_image_change = true
+
+  network {
+    uuid = "%s"
+  }
+}
+`, imageID, OS_AVAILABILITY_ZONE, OS_NETWORK_ID)
+}
+
+func testAccComputeV2Instance_networks(secondary bool) string {
+	var secondaryNetwork string
+	if secondary {
+		secondaryNetwork = `
+  network {
+    uuid        = flexibleengine_vpc_subnet_v1.subnet_2.id
+    fixed_ip_v4 = "192.168.10.20"
+  }`
+	}
+
+	return fmt.Sprintf(`
+data "flexibleengine_vpc_subnet_v1" "subnet_1" {
+  id = "%s"
+}
+
+resource "flexibleengine_vpc_subnet_v1" "subnet_2" {
+  name       = "subnet_2"
+  vpc_id     = data.flexibleengine_vpc_subnet_v1.subnet_1.vpc_id
+  cidr       = "192.168.10.0/24"
+  gateway_ip = "192.168.10.1"
+}
+
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name              = "instance_1"
+  image_id          = "%s"
+  security_groups   = ["default"]
+  availability_zone = "%s"
+
+  network {
+    uuid = "%s"
+  }
+%s
+}
+`, OS_NETWORK_ID, OS_IMAGE_ID, OS_AVAILABILITY_ZONE, OS_NETWORK_ID, secondaryNetwork)
+}
+
+func testAccComputeV2Instance_prePaid(chargingMode string) string {
+	return fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name              = "instance_1"
+  image_id          = "%s"
+  security_groups   = ["default"]
+  availability_zone = "%s"
+
+  charging_mode = "%s"
+  period_unit   = "month"
+  period        = 1
+  auto_renew    = "false"
+
+  network {
+    uuid = "%s"
+  }
+}
+`, OS_IMAGE_ID, OS_AVAILABILITY_ZONE, chargingMode, OS_NETWORK_ID)
+}
+
+var testAccComputeV2Instance_spot = fmt.Sprintf(`
+resource "flexibleengine_compute_instance_v2" "instance_1" {
+  name              = "instance_1"
+  image_id          = "%s"
+  security_groups   = ["default"]
+  availability_zone = "%s"
+
+  system_disk_type    = "SAS"
+  spot_price          = "0.05"
+  spot_duration       = 1
+  interruption_policy = "immediate"
+
+  network {
+    uuid = "%s"
+  }
+}
+`, OS_IMAGE_ID, OS_AVAILABILITY_ZONE, OS_NETWORK_ID)
+
+// mockComputeInstanceConfig returns the configuration of an instance in the network named network-id
+func mockComputeInstanceConfig(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":            name,
+		"image_id":        mockImageID,
+		"flavor_id":       mockFlavorID,
+		"security_groups": []interface{}{"default"},
+		"network": []interface{}{
+			map[string]interface{}{"uuid": "network-id"},
+		},
+	}
+}
+
+func TestMockComputeInstanceV2(t *testing.T) {
+	config := map[string]interface{}{
+		"name":              "instance_1",
+		"image_id":          mockImageID,
+		"flavor_id":         mockFlavorID,
+		"availability_zone": mockAZ,
+		"security_groups":   []interface{}{"default"},
+		"metadata":          map[string]interface{}{"foo": "bar"},
+		"tags":              map[string]interface{}{"owner": "terraform"},
+		"network": []interface{}{
+			map[string]interface{}{"uuid": "network-id", "fixed_ip_v4": "192.168.0.10"},
+		},
+	}
+	updated := make(map[string]interface{})
+	for k, v := range config {
+		updated[k] = v
+	}
+	updated["name"] = "instance_2"
+	updated["metadata"] = map[string]interface{}{"abc": "def"}
+	updated["tags"] = map[string]interface{}{"owner": "ci"}
+
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_compute_instance_v2",
+		steps: []mockResourceStep{
+			{
+				config: config,
+				attrs: map[string]string{
+					"status":         "ACTIVE",
+					"image_name":     mockImageName,
+					"access_ip_v4":   "192.168.0.10",
+					"network.0.uuid": "network-id",
+					"tags.owner":     "terraform",
+				},
+			},
+			{
+				config: updated,
+				attrs: map[string]string{
+					"name":         "instance_2",
+					"metadata.abc": "def",
+					"metadata.foo": "",
+					"tags.owner":   "ci",
+				},
+			},
+		},
+		importAttrs: []string{"name", "network.0.port"},
+		collections: []string{"servers", "ports"},
+	})
+}
+
+func TestMockComputeInstanceV2_prePaid(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+
+	vpc := server.createObject("vpcs", map[string]interface{}{"name": "vpc_1"})
+	subnet := server.createObject("subnets", map[string]interface{}{"name": "subnet_1", "vpc_id": vpc["id"]})
+	server.createObject("security-groups", map[string]interface{}{"name": "default"})
+
+	config := map[string]interface{}{
+		"name":             "instance_1",
+		"image_id":         mockImageID,
+		"flavor_id":        mockFlavorID,
+		"security_groups":  []interface{}{"default"},
+		"system_disk_type": "SAS",
+		"charging_mode":    "prePaid",
+		"period_unit":      "year",
+		"period":           1,
+		"auto_renew":       "true",
+		"network": []interface{}{
+			map[string]interface{}{"uuid": subnet["id"]},
+		},
+	}
+
+	// the prePaid instance is created by the ECS API with the arguments it accepts
+	config["personality"] = []interface{}{map[string]interface{}{"file": "/tmp/foo", "content": "bar"}}
+	_, err := driver.resource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	th.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), "personality"))
+	delete(config, "personality")
+
+	// the instance is subscribed when it is created instead of being changed from postPaid
+	driver.apply(config)
+	th.AssertEquals(t, "prePaid", driver.state.Attributes["charging_mode"])
+	th.AssertEquals(t, "true", driver.state.Attributes["auto_renew"])
+	instance := server.objects["servers"][driver.state.ID]
+	extendParam := instance["extendparam"].(map[string]interface{})
+	th.AssertEquals(t, "prePaid", extendParam["chargingMode"])
+	th.AssertEquals(t, "year", extendParam["periodType"])
+	th.AssertEquals(t, "true", extendParam["isAutoPay"])
+	th.AssertEquals(t, float64(3), instance["period_type"])
+
+	// the period is only used by the order
+	config["period"] = 2
+	_, err = driver.resource.Diff(context.Background(), driver.state, terraform.NewResourceConfigRaw(config), meta)
+	th.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), "can not be changed"))
+	config["period"] = 1
+
+	imported := driver.importState(driver.state.ID)
+	for _, k := range []string{"charging_mode", "period_unit", "period"} {
+		th.AssertEquals(t, driver.state.Attributes[k], imported.Attributes[k])
+	}
+
+	driver.destroy()
+	th.AssertEquals(t, 0, len(server.objects["servers"]))
+	th.AssertEquals(t, 0, len(server.objects["ports"]))
+}
+
+func TestMockComputeInstanceV2_power(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+
+	config := mockComputeInstanceConfig("instance_1")
+	config["power_action"] = "OFF"
+	driver.apply(config)
+	th.AssertEquals(t, "OFF", driver.state.Attributes["power_state"])
+
+	// the instance started by hand is not a drift by default
+	server.objects["servers"][driver.state.ID]["status"] = "ACTIVE"
+	driver.state = driver.refresh()
+	th.AssertEquals(t, "ON", driver.state.Attributes["power_state"])
+	th.AssertEquals(t, true, driver.plan(config).Empty())
+
+	// the power state is converged once detect_power_drift is enabled
+	config["detect_power_drift"] = true
+	driver.apply(config)
+	th.AssertEquals(t, "OFF", driver.state.Attributes["power_state"])
+
+	// and the instance started by hand is a drift
+	server.objects["servers"][driver.state.ID]["status"] = "ACTIVE"
+	driver.state = driver.refresh()
+	th.AssertEquals(t, "ON", driver.state.Attributes["power_action"])
+	th.AssertEquals(t, false, driver.plan(config).Empty())
+	driver.apply(config)
+	th.AssertEquals(t, "SHUTOFF", server.objects["servers"][driver.state.ID]["status"])
+
+	// the stopped instance is started for REBOOT
+	config["power_action"] = "REBOOT"
+	driver.apply(config)
+	th.AssertEquals(t, "ON", driver.state.Attributes["power_state"])
+
+	config["power_action"] = "FORCE-REBOOT"
+	driver.apply(config)
+	th.AssertEquals(t, 1, server.objects["servers"][driver.state.ID]["reboots"])
+
+	driver.destroy()
+}
+
+func TestMockComputeInstanceV2_networks(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+
+	config := map[string]interface{}{
+		"name":            "instance_1",
+		"image_id":        mockImageID,
+		"flavor_id":       mockFlavorID,
+		"security_groups": []interface{}{"default"},
+		"network": []interface{}{
+			map[string]interface{}{"uuid": "network-1"},
+		},
+	}
+	driver.apply(config)
+	id := driver.state.ID
+	primaryPort := driver.state.Attributes["network.0.port"]
+
+	// the NICs are attached in place
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": "network-1"},
+		map[string]interface{}{"uuid": "network-2"},
+		map[string]interface{}{"uuid": "network-3", "fixed_ip_v4": "192.168.3.10"},
+	}
+	diff := driver.plan(config)
+	th.AssertEquals(t, false, diff.RequiresNew())
+	// the attributes of the new NICs are not planned, they are unknown until the NICs are attached
+	th.AssertEquals(t, true, diff.Attributes["network.1.mac"] == nil)
+	th.AssertEquals(t, true, diff.Attributes["network.1.fixed_ip_v4"] == nil)
+	th.AssertEquals(t, "192.168.3.10", diff.Attributes["network.2.fixed_ip_v4"].New)
+	driver.apply(config)
+	th.AssertEquals(t, id, driver.state.ID)
+	th.AssertEquals(t, 3, len(server.objects["servers"][id]["ports"].([]string)))
+	th.AssertEquals(t, primaryPort, driver.state.Attributes["network.0.port"])
+	th.AssertEquals(t, "network-2", driver.state.Attributes["network.1.uuid"])
+	th.AssertEquals(t, "192.168.3.10", driver.state.Attributes["network.2.fixed_ip_v4"])
+	thirdPort := driver.state.Attributes["network.2.port"]
+
+	// the NIC in the middle is detached and the others are kept
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": "network-1"},
+		map[string]interface{}{"uuid": "network-3", "fixed_ip_v4": "192.168.3.10"},
+	}
+	diff = driver.plan(config)
+	th.AssertEquals(t, false, diff.RequiresNew())
+	th.AssertEquals(t, thirdPort, diff.Attributes["network.1.port"].New)
+	driver.apply(config)
+	th.AssertEquals(t, id, driver.state.ID)
+	th.AssertEquals(t, 2, len(server.objects["servers"][id]["ports"].([]string)))
+	th.AssertEquals(t, thirdPort, driver.state.Attributes["network.1.port"])
+
+	// the state is stable regardless of the order of the addresses
+	for i := 0; i < 3; i++ {
+		th.AssertEquals(t, thirdPort, driver.refresh().Attributes["network.1.port"])
+	}
+
+	// the primary NIC can not be changed in place
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": "network-3", "fixed_ip_v4": "192.168.3.10"},
+	}
+	th.AssertEquals(t, true, driver.plan(config).RequiresNew())
+
+	// the primary NIC may be changed when it refers to a network which is not created yet
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": mockUnknownValue},
+		map[string]interface{}{"uuid": "network-3", "fixed_ip_v4": "192.168.3.10"},
+	}
+	th.AssertEquals(t, true, driver.plan(config).RequiresNew())
+
+	// the primary NIC is kept when only the other NICs are not known yet
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": "network-1"},
+		map[string]interface{}{"uuid": mockUnknownValue},
+	}
+	th.AssertEquals(t, false, driver.plan(config).RequiresNew())
+
+	// removing all the network blocks removes the primary NIC as well
+	delete(config, "network")
+	th.AssertEquals(t, true, driver.plan(config).RequiresNew())
+
+	// the primary NIC is never detached in place
+	config["network"] = []interface{}{
+		map[string]interface{}{"uuid": "network-3", "fixed_ip_v4": "192.168.3.10"},
+	}
+	diff = driver.plan(config)
+	for _, attr := range diff.Attributes {
+		attr.RequiresNew = false
+	}
+	_, diags := driver.resource.Apply(context.Background(), driver.state, diff, meta)
+	th.AssertEquals(t, true, diags.HasError())
+	th.AssertEquals(t, true, strings.Contains(diags[0].Summary, "can not be detached"))
+	th.AssertEquals(t, 2, len(server.objects["servers"][id]["ports"].([]string)))
+
+	driver.destroy()
+	th.AssertEquals(t, 0, len(server.objects["ports"]))
+}
+
+func TestMockComputeInstanceV2_rebuild(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+
+	newImageID := "b1f5d5a0-0c4b-4b6a-9a0e-4f1c5d0a0002"
+	server.objects["images"][newImageID] = map[string]interface{}{
+		"id":         newImageID,
+		"name":       "golden-image-v2",
+		"status":     "active",
+		"visibility": "private",
+		"min_disk":   40,
+	}
+
+	config := mockComputeInstanceConfig("instance_1")
+	delete(config, "image_id")
+	config["image_name"] = mockImageName
+	config["admin_pass"] = "Password@123"
+	config["user_data"] = "#!/bin/bash\necho hello"
+	driver.apply(config)
+	id := driver.state.ID
+
+	// the instance is replaced by default
+	config["image_name"] = "golden-image-v2"
+	th.AssertEquals(t, true, driver.plan(config).RequiresNew())
+
+	config["rebuild_on_image_change"] = true
+	th.AssertEquals(t, false, driver.plan(config).RequiresNew())
+	driver.apply(config)
+	th.AssertEquals(t, id, driver.state.ID)
+	th.AssertEquals(t, newImageID, driver.state.Attributes["image_id"])
+
+	opts := server.objects["servers"][id]["os-change"].(map[string]interface{})
+	th.AssertEquals(t, "Password@123", opts["adminpass"])
+	th.AssertEquals(t, "withStopServer", opts["mode"])
+	userData := opts["metadata"].(map[string]interface{})["user_data"]
+	th.AssertEquals(t, "IyEvYmluL2Jhc2gKZWNobyBoZWxsbw==", userData)
+
+	driver.destroy()
+}
+
+func TestMockComputeInstanceV2_spot(t *testing.T) {
+	server, meta := newMockTest(t)
+	driver := newMockResourceDriver(t, "flexibleengine_compute_instance_v2", meta)
+
+	vpc := server.createObject("vpcs", map[string]interface{}{"name": "vpc_1"})
+	subnet := server.createObject("subnets", map[string]interface{}{"name": "subnet_1", "vpc_id": vpc["id"]})
+	server.createObject("security-groups", map[string]interface{}{"name": "default"})
+
+	config := map[string]interface{}{
+		"name":                "instance_1",
+		"image_id":            mockImageID,
+		"flavor_id":           mockFlavorID,
+		"security_groups":     []interface{}{"default"},
+		"metadata":            map[string]interface{}{"foo": "bar"},
+		"spot_price":          "0.05",
+		"spot_duration":       2,
+		"interruption_policy": "immediate",
+		"network": []interface{}{
+			map[string]interface{}{"uuid": subnet["id"], "fixed_ip_v4": "192.168.0.10"},
+		},
+	}
+
+	// the system disk type is required by the ECS API
+	_, err := driver.resource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	th.AssertEquals(t, true, err != nil && strings.Contains(err.Error(), "system_disk_type"))
+
+	config["system_disk_type"] = "GPSSD"
+	driver.apply(config)
+	th.AssertEquals(t, "spot", driver.state.Attributes["charging_mode"])
+	th.AssertEquals(t, "192.168.0.10", driver.state.Attributes["network.0.fixed_ip_v4"])
+
+	instance := server.objects["servers"][driver.state.ID]
+	extendParam := instance["extendparam"].(map[string]interface{})
+	th.AssertEquals(t, "0.05", extendParam["spotPrice"])
+	th.AssertEquals(t, float64(2), extendParam["spot_duration_hours"])
+	th.AssertEquals(t, "immediate", extendParam["interruption_policy"])
+	th.AssertEquals(t, "GPSSD", instance["root_volume"].(map[string]interface{})["volumetype"])
+	th.AssertEquals(t, "bar", instance["metadata"].(map[string]interface{})["foo"])
+
+	// the reclaimed instance is removed from the state
+	instance["status"] = "DELETED"
+	th.AssertEquals(t, true, driver.refresh() == nil)
+}
@@ -33,6 +33,10 @@ func resourceCssClusterV1() *schema.Resource {
 		Update: resourceCssClusterV1Update,
 		Delete: resourceCssClusterV1Delete,
 
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(60 * time.Minute),
 			Update: schema.DefaultTimeout(60 * time.Minute),
@@ -134,6 +138,7 @@ func resourceCssClusterV1() *schema.Resource {
 						"availability_zone": {
 							Type:     schema.TypeString,
 							Optional: true,
+							Computed: true,
 							ForceNew: true,
 						},
 					},
@@ -860,6 +865,24 @@ func fillCssClusterV1ReadRespBody(body interface{}) interface{} {
 		result["updated"] = nil
 	}
 
+	if v, ok := val["vpcId"]; ok {
+		result["vpcId"] = v
+	} else {
+		result["vpcId"] = nil
+	}
+
+	if v, ok := val["subnetId"]; ok {
+		result["subnetId"] = v
+	} else {
+		result["subnetId"] = nil
+	}
+
+	if v, ok := val["securityGroupId"]; ok {
+		result["securityGroupId"] = v
+	} else {
+		result["securityGroupId"] = nil
+	}
+
 	return result
 }
 
@@ -929,6 +952,24 @@ func fillCssClusterV1ReadRespInstances(value interface{}) interface{} {
 			val["type"] = nil
 		}
 
+		if v, ok := item["specCode"]; ok {
+			val["specCode"] = v
+		} else {
+			val["specCode"] = nil
+		}
+
+		if v, ok := item["azCode"]; ok {
+			val["azCode"] = v
+		} else {
+			val["azCode"] = nil
+		}
+
+		if v, ok := item["volume"]; ok {
+			val["volume"] = v
+		} else {
+			val["volume"] = nil
+		}
+
 		result[i] = val
 	}
 
@@ -999,9 +1040,62 @@ func setCssClusterV1Properties(d *schema.ResourceData, response map[string]inter
 		return fmt.Errorf("Error setting Cluster:nodes number, err: %s", err)
 	}
 
+	v, err = flattenCssClusterV1NodeConfig(response)
+	if err != nil {
+		return fmt.Errorf("Error reading Cluster:node_config, err: %s", err)
+	}
+	if err = d.Set("node_config", v); err != nil {
+		return fmt.Errorf("Error setting Cluster:node_config, err: %s", err)
+	}
+
 	return nil
 }
 
+// flattenCssClusterV1NodeConfig builds node_config from the first instance of the cluster,
+// all the instances share the same flavor, volume and availability zone.
+func flattenCssClusterV1NodeConfig(response map[string]interface{}) (interface{}, error) {
+	instances, err := navigateValue(response, []string{"read", "instances"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	instanceList, ok := instances.([]interface{})
+	if !ok || len(instanceList) == 0 {
+		return nil, nil
+	}
+	instance := instanceList[0].(map[string]interface{})
+
+	networkInfo := map[string]interface{}{}
+	for k, path := range map[string]string{
+		"vpc_id":            "vpcId",
+		"subnet_id":         "subnetId",
+		"security_group_id": "securityGroupId",
+	} {
+		v, err := navigateValue(response, []string{"read", path}, nil)
+		if err != nil {
+			return nil, err
+		}
+		networkInfo[k] = v
+	}
+
+	var volumes []interface{}
+	if volume, ok := instance["volume"].(map[string]interface{}); ok {
+		volumes = []interface{}{
+			map[string]interface{}{
+				"size":        volume["size"],
+				"volume_type": volume["type"],
+			},
+		}
+	}
+
+	nodeConfig := map[string]interface{}{
+		"flavor":            instance["specCode"],
+		"availability_zone": instance["azCode"],
+		"network_info":      []interface{}{networkInfo},
+		"volume":            volumes,
+	}
+	return []interface{}{nodeConfig}, nil
+}
+
 func flattenCssClusterV1Nodes(d interface{}, arrayIndex map[string]int, currentValue interface{}) (interface{}, error) {
 	n := 0
 	hasInitValue := true
@@ -44,6 +44,14 @@ func TestAccCssClusterV1_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(resourceName, "tags.key", "value"),
 				),
 			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"password",
+				},
+			},
 			{
 				Config: testAccCssClusterV1_update(randName),
 				Check: resource.ComposeTestCheckFunc(
@@ -22,6 +22,10 @@ func resourceDdsInstanceV3() *schema.Resource {
 		Update: resourceDdsInstanceV3Update,
 		Delete: resourceDdsInstanceV3Delete,
 
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(30 * time.Minute),
 			Delete: schema.DefaultTimeout(30 * time.Minute),
@@ -437,6 +441,17 @@ func resourceDdsInstanceV3Read(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error setting nodes of DDS instance, err: %s", err)
 	}
 
+	err = d.Set("flavor", flattenDdsInstanceV3Flavor(d, instance))
+	if err != nil {
+		return fmt.Errorf("Error setting flavor of DDS instance, err: %s", err)
+	}
+	for _, group := range instance.Groups {
+		if len(group.Nodes) > 0 {
+			d.Set("availability_zone", group.Nodes[0].AvailabilityZone)
+			break
+		}
+	}
+
 	// save tags
 	if resourceTags, err := tags.Get(client, "instances", d.Id()).Extract(); err == nil {
 		tagmap := tagsToMap(resourceTags.Tags)
@@ -517,3 +532,54 @@ func flattenDdsInstanceV3Nodes(dds instances.InstanceResponse) interface{} {
 	}
 	return nodesList
 }
+
+// flattenDdsInstanceV3Flavor builds the flavor from the groups of the instance, the storage type
+// is not returned by the API and is kept from the state.
+func flattenDdsInstanceV3Flavor(d *schema.ResourceData, dds instances.InstanceResponse) interface{} {
+	// keep the order of the flavors in the state, so that the list is not reordered
+	var flavorTypes []string
+	storages := make(map[string]interface{})
+	for _, raw := range d.Get("flavor").([]interface{}) {
+		flavor := raw.(map[string]interface{})
+		flavorType := flavor["type"].(string)
+		flavorTypes = append(flavorTypes, flavorType)
+		storages[flavorType] = flavor["storage"]
+	}
+	flavorTypes = append(flavorTypes, "mongos", "shard", "config", "replica")
+
+	flavors := make(map[string]map[string]interface{})
+	for _, group := range dds.Groups {
+		if len(group.Nodes) == 0 {
+			continue
+		}
+		flavor, ok := flavors[group.Type]
+		if !ok {
+			flavor = map[string]interface{}{
+				"type":      group.Type,
+				"num":       0,
+				"storage":   storages[group.Type],
+				"spec_code": group.Nodes[0].SpecCode,
+			}
+			if size, err := strconv.Atoi(group.Volume.Size); err == nil {
+				flavor["size"] = size
+			}
+			flavors[group.Type] = flavor
+		}
+
+		// the num of mongos is the number of nodes, the others are the number of groups
+		if group.Type == "mongos" {
+			flavor["num"] = flavor["num"].(int) + len(group.Nodes)
+		} else {
+			flavor["num"] = flavor["num"].(int) + 1
+		}
+	}
+
+	flavorList := make([]map[string]interface{}, 0, len(flavors))
+	for _, flavorType := range flavorTypes {
+		if flavor, ok := flavors[flavorType]; ok {
+			flavorList = append(flavorList, flavor)
+			delete(flavors, flavorType)
+		}
+	}
+	return flavorList
+}
@@ -24,6 +24,10 @@ func resourceListenerV2() *schema.Resource {
 		Update: resourceListenerUpdate,
 		Delete: resourceListenerDelete,
 
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
 			Update: schema.DefaultTimeout(10 * time.Minute),
@@ -265,6 +269,9 @@ func resourceListenerRead(d *schema.ResourceData, meta interface{}) error {
 	if mErr.ErrorOrNil() != nil {
 		return mErr
 	}
+	if len(listener.Loadbalancers) > 0 {
+		d.Set("loadbalancer_id", listener.Loadbalancers[0].ID)
+	}
 
 	// fetch tags
 	if resourceTags, err := tags.Get(lbClient, "listeners", d.Id()).Extract(); err == nil {
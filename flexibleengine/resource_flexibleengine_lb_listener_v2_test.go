@@ -31,6 +31,14 @@ func TestAccLBV2Listener_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(resourceName, "transparent_client_ip_enable", "true"),
 				),
 			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"admin_state_up",
+				},
+			},
 			{
 				Config: testAccLBV2ListenerConfig_tags(rName),
 				Check: resource.ComposeTestCheckFunc(
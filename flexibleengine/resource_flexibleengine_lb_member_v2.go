@@ -3,6 +3,7 @@ package flexibleengine
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -18,6 +19,10 @@ func resourceMemberV2() *schema.Resource {
 		Update: resourceMemberV2Update,
 		Delete: resourceMemberV2Delete,
 
+		Importer: &schema.ResourceImporter{
+			State: resourceMemberV2ImportState,
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
 			Update: schema.DefaultTimeout(10 * time.Minute),
@@ -263,3 +268,13 @@ func resourceMemberV2Delete(d *schema.ResourceData, meta interface{}) error {
 
 	return nil
 }
+
+func resourceMemberV2ImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid format specified for member, must be <pool_id>/<member_id>")
+	}
+	d.SetId(parts[1])
+	d.Set("pool_id", parts[0])
+	return []*schema.ResourceData{d}, nil
+}
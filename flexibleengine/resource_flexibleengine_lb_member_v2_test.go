@@ -26,6 +26,12 @@ func TestAccLBV2Member_basic(t *testing.T) {
 					testAccCheckLBV2MemberExists("flexibleengine_lb_member_v2.member_2", &member_2),
 				),
 			},
+			{
+				ResourceName:      "flexibleengine_lb_member_v2.member_1",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateIdFunc: testAccLBV2MemberImportStateIdFunc("flexibleengine_lb_member_v2.member_1"),
+			},
 			{
 				Config:             testAccLBV2MemberConfig_update,
 				ExpectNonEmptyPlan: true, // Because admin_state_up remains false, unfinished elb?
@@ -38,6 +44,20 @@ func TestAccLBV2Member_basic(t *testing.T) {
 	})
 }
 
+func testAccLBV2MemberImportStateIdFunc(memberRes string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		member, ok := s.RootModule().Resources[memberRes]
+		if !ok {
+			return "", fmt.Errorf("LB member not found: %s", memberRes)
+		}
+		poolID := member.Primary.Attributes["pool_id"]
+		if poolID == "" || member.Primary.ID == "" {
+			return "", fmt.Errorf("resource not found: %s/%s", poolID, member.Primary.ID)
+		}
+		return fmt.Sprintf("%s/%s", poolID, member.Primary.ID), nil
+	}
+}
+
 func testAccCheckLBV2MemberDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	lbClient, err := config.ElbV2Client(OS_REGION_NAME)
@@ -18,6 +18,10 @@ func resourceMonitorV2() *schema.Resource {
 		Update: resourceMonitorV2Update,
 		Delete: resourceMonitorV2Delete,
 
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
 			Update: schema.DefaultTimeout(10 * time.Minute),
@@ -178,7 +182,11 @@ func resourceMonitorV2Read(d *schema.ResourceData, meta interface{}) error {
 	d.Set("admin_state_up", monitor.AdminStateUp)
 	d.Set("name", monitor.Name)
 	d.Set("port", monitor.MonitorPort)
+	d.Set("expected_codes", monitor.ExpectedCodes)
 	d.Set("region", GetRegion(d, config))
+	if len(monitor.Pools) > 0 {
+		d.Set("pool_id", monitor.Pools[0].ID)
+	}
 
 	return nil
 }
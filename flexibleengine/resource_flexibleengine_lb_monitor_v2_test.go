@@ -31,6 +31,11 @@ func TestAccLBV2Monitor_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(resourceName, "port", "8888"),
 				),
 			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 			{
 				Config: testAccLBV2MonitorConfig_update(rand),
 				Check: resource.ComposeTestCheckFunc(
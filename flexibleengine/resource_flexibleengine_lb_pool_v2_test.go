@@ -24,6 +24,11 @@ func TestAccLBV2Pool_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("flexibleengine_lb_pool_v2.pool_1", "name", "pool_1"),
 				),
 			},
+			{
+				ResourceName:      "flexibleengine_lb_pool_v2.pool_1",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 			{
 				Config: testAccLBV2PoolConfig_update,
 				Check: resource.ComposeTestCheckFunc(
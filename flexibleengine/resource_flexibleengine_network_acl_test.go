@@ -32,6 +32,11 @@ func TestAccNetworkACL_basic(t *testing.T) {
 					testAccCheckFWFirewallPortCount(&fwGroup, 1),
 				),
 			},
+			{
+				ResourceName:      resourceKey,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 			{
 				Config: testAccNetworkACL_basic_update(rName),
 				Check: resource.ComposeTestCheckFunc(
@@ -12,6 +12,7 @@ import (
 
 func TestAccNetworkingV2Router_basic(t *testing.T) {
 	var router routers.Router
+	resourceName := "flexibleengine_networking_router_v2.router_1"
 
 	resource.Test(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheckDeprecated(t) },
@@ -21,14 +22,21 @@ func TestAccNetworkingV2Router_basic(t *testing.T) {
 			{
 				Config: testAccNetworkingV2Router_basic,
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckNetworkingV2RouterExists("flexibleengine_networking_router_v2.router_1", &router),
+					testAccCheckNetworkingV2RouterExists(resourceName, &router),
 				),
 			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"value_specs",
+				},
+			},
 			{
 				Config: testAccNetworkingV2Router_update,
 				Check: resource.ComposeTestCheckFunc(
-					resource.TestCheckResourceAttr(
-						"flexibleengine_networking_router_v2.router_1", "name", "router_2"),
+					resource.TestCheckResourceAttr(resourceName, "name", "router_2"),
 				),
 			},
 		},
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/chnsz/golangsdk/openstack/networking/v2/extensions/security/groups"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccNetworkingV2SecGroup_basic(t *testing.T) {
@@ -160,3 +161,31 @@ resource "flexibleengine_networking_secgroup_v2" "secgroup_1" {
 }
 `, rName)
 }
+
+func TestMockNetworkingSecGroupV2(t *testing.T) {
+	config := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                 name,
+			"description":          "created by terraform",
+			"delete_default_rules": true,
+		}
+	}
+
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_networking_secgroup_v2",
+		steps: []mockResourceStep{
+			{
+				config: config("secgroup_1"),
+				check: func(t *testing.T, server *mockServer, _ *terraform.InstanceState) {
+					th.AssertEquals(t, 0, len(server.objects["security-group-rules"]))
+				},
+			},
+			{
+				config: config("secgroup_2"),
+				attrs:  map[string]string{"name": "secgroup_2"},
+			},
+		},
+		importAttrs: []string{"name"},
+		collections: []string{"security-groups"},
+	})
+}
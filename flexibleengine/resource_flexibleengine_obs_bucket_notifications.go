@@ -73,7 +73,7 @@ func resourceObsBucketNotifications() *schema.Resource {
 
 func resourceObsBucketNotificationCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return diag.Errorf("Error creating OBS client: %s", err)
 	}
@@ -134,7 +134,7 @@ func buildEvents(events []interface{}) []obs.EventType {
 
 func resourceObsBucketNotificationRead(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return diag.Errorf("Error creating OBS client: %s", err)
 	}
@@ -173,7 +173,7 @@ func resourceObsBucketNotificationRead(_ context.Context, d *schema.ResourceData
 
 func resourceObsBucketNotificationDelete(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return diag.Errorf("Error creating OBS client: %s", err)
 	}
@@ -19,6 +19,10 @@ func resourceObsBucketObject() *schema.Resource {
 		Update: resourceObsBucketObjectPut,
 		Delete: resourceObsBucketObjectDelete,
 
+		Importer: &schema.ResourceImporter{
+			State: resourceObsBucketObjectImportState,
+		},
+
 		Schema: map[string]*schema.Schema{
 			"bucket": {
 				Type:     schema.TypeString,
@@ -98,7 +102,7 @@ func resourceObsBucketObjectPut(d *schema.ResourceData, meta interface{}) error
 	var err error
 
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 	}
@@ -211,7 +215,7 @@ func putFileToObject(obsClient *obs.ObsClient, d *schema.ResourceData) (*obs.Put
 
 func resourceObsBucketObjectRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 	}
@@ -262,7 +266,7 @@ func resourceObsBucketObjectRead(d *schema.ResourceData, meta interface{}) error
 
 func resourceObsBucketObjectDelete(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
-	obsClient, err := config.ObjectStorageClient(GetRegion(d, config))
+	obsClient, err := objectStorageClient(config, GetRegion(d, config))
 	if err != nil {
 		return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 	}
@@ -282,3 +286,15 @@ func resourceObsBucketObjectDelete(d *schema.ResourceData, meta interface{}) err
 
 	return nil
 }
+
+// resourceObsBucketObjectImportState imports the object by <bucket>/<key>, the key may contain slashes
+func resourceObsBucketObjectImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid format specified for OBS bucket object, must be <bucket>/<key>")
+	}
+	d.SetId(parts[1])
+	d.Set("bucket", parts[0])
+	d.Set("key", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
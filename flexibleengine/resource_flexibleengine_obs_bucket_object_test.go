@@ -74,13 +74,22 @@ func TestAccObsBucketObject_content(t *testing.T) {
 						"flexibleengine_obs_bucket_object.object", "size", "19"),
 				),
 			},
+			{
+				ResourceName:      "flexibleengine_obs_bucket_object.object",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateIdFunc: testAccObsBucketObjectImportStateIdFunc("flexibleengine_obs_bucket_object.object"),
+				ImportStateVerifyIgnore: []string{
+					"content",
+				},
+			},
 		},
 	})
 }
 
 func testAccCheckObsBucketObjectDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
-	obsClient, err := config.ObjectStorageClient(OS_REGION_NAME)
+	obsClient, err := objectStorageClient(config, OS_REGION_NAME)
 	if err != nil {
 		return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 	}
@@ -131,7 +140,7 @@ func testAccCheckObsBucketObjectExists(n string) resource.TestCheckFunc {
 		}
 
 		config := testAccProvider.Meta().(*Config)
-		obsClient, err := config.ObjectStorageClient(OS_REGION_NAME)
+		obsClient, err := objectStorageClient(config, OS_REGION_NAME)
 		if err != nil {
 			return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 		}
@@ -176,6 +185,20 @@ resource "flexibleengine_obs_bucket_object" "object" {
 `, randInt, source)
 }
 
+func testAccObsBucketObjectImportStateIdFunc(objectRes string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		object, ok := s.RootModule().Resources[objectRes]
+		if !ok {
+			return "", fmt.Errorf("OBS bucket object not found: %s", objectRes)
+		}
+		bucket := object.Primary.Attributes["bucket"]
+		if bucket == "" || object.Primary.ID == "" {
+			return "", fmt.Errorf("resource not found: %s/%s", bucket, object.Primary.ID)
+		}
+		return fmt.Sprintf("%s/%s", bucket, object.Primary.ID), nil
+	}
+}
+
 func testAccObsBucketObjectConfigContent(randInt int) string {
 	return fmt.Sprintf(`
 resource "flexibleengine_obs_bucket" "object_bucket" {
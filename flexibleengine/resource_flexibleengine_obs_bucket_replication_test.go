@@ -53,7 +53,7 @@ func TestAccObsBucketReplication_basic(t *testing.T) {
 
 func testAccCheckObsBucketReplicationDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
-	obsClient, err := config.ObjectStorageClientWithSignature(OS_REGION_NAME)
+	obsClient, err := objectStorageClientWithSignature(config, OS_REGION_NAME)
 	if err != nil {
 		return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 	}
@@ -83,7 +83,7 @@ func testAccCheckObsBucketReplicationExists(n string) resource.TestCheckFunc {
 		}
 
 		config := testAccProvider.Meta().(*Config)
-		obsClient, err := config.ObjectStorageClientWithSignature(OS_REGION_NAME)
+		obsClient, err := objectStorageClientWithSignature(config, OS_REGION_NAME)
 		if err != nil {
 			return fmt.Errorf("Error creating FlexibleEngine OBS client: %s", err)
 		}
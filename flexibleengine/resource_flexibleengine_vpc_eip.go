@@ -1,6 +1,7 @@
 package flexibleengine
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -24,8 +25,13 @@ func resourceVpcEIPV1() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		CustomizeDiff: func(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+			return customizeChargingModeDiff(d)
+		},
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
 			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
@@ -93,6 +99,11 @@ func resourceVpcEIPV1() *schema.Resource {
 			},
 			"tags": tagsSchema(),
 
+			"charging_mode": schemaChargingMode(),
+			"period_unit":   schemaPeriodUnit(),
+			"period":        schemaPeriod(),
+			"auto_renew":    schemaAutoRenew(),
+
 			"address": {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -127,14 +138,14 @@ func resourceVpcEIPV1Create(d *schema.ResourceData, meta interface{}) error {
 	log.Printf("[DEBUG] Waiting for EIP %#v to become available.", eIP)
 
 	timeout := d.Timeout(schema.TimeoutCreate)
-	err = waitForEIPActive(networkingClient, eIP.ID, timeout)
+	err = waitForEIPActive(config, networkingClient, eIP.ID, timeout)
 	if err != nil {
 		return fmt.Errorf(
 			"Error waiting for EIP (%s) to become ready: %s",
 			eIP.ID, err)
 	}
 
-	err = bindToPort(d, eIP.ID, networkingClient, timeout)
+	err = bindToPort(config, d, eIP.ID, networkingClient, timeout)
 	if err != nil {
 		return fmt.Errorf("Error binding eip:%s to port: %s", eIP.ID, err)
 	}
@@ -152,6 +163,13 @@ func resourceVpcEIPV1Create(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
+	// the EIP is created in pay-per-use mode and then changed to prepaid
+	if isPrePaid(d) {
+		if err := changeToPrePaid(d, config, []string{eIP.ID}, timeout); err != nil {
+			return err
+		}
+	}
+
 	return resourceVpcEIPV1Read(d, meta)
 }
 
@@ -162,7 +180,8 @@ func resourceVpcEIPV1Read(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error creating networking client: %s", err)
 	}
 
-	eIP, err := eips.Get(networkingClient, d.Id()).Extract()
+	r := eips.Get(networkingClient, d.Id())
+	eIP, err := r.Extract()
 	if err != nil {
 		return CheckDeleted(d, err, "eIP")
 	}
@@ -171,6 +190,24 @@ func resourceVpcEIPV1Read(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error fetching bandwidth: %s", err)
 	}
 
+	// the billing info is only returned for the prepaid EIP
+	var billing struct {
+		IP struct {
+			BillingInfo string `json:"billing_info"`
+		} `json:"publicip"`
+	}
+	if err := r.ExtractInto(&billing); err != nil {
+		return fmt.Errorf("Error extracting the billing info of EIP %s: %s", d.Id(), err)
+	}
+	if billing.IP.BillingInfo != "" {
+		d.Set("charging_mode", chargingModePrePaid)
+		if err := setPrePaidInfo(d, config, d.Id()); err != nil {
+			return err
+		}
+	} else {
+		d.Set("charging_mode", chargingModePostPaid)
+	}
+
 	// Set public ip
 	publicIP := []map[string]string{
 		{
@@ -269,6 +306,12 @@ func resourceVpcEIPV1Update(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
+	if d.HasChanges("charging_mode", "auto_renew") {
+		if err := updateChargingMode(d, config, []string{d.Id()}); err != nil {
+			return err
+		}
+	}
+
 	return resourceVpcEIPV1Read(d, meta)
 }
 
@@ -280,21 +323,29 @@ func resourceVpcEIPV1Delete(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	timeout := d.Timeout(schema.TimeoutDelete)
-	err = unbindToPort(d, d.Id(), networkingClient, timeout)
+	err = unbindToPort(config, d, d.Id(), networkingClient, timeout)
 	if err != nil {
 		return fmt.Errorf("Error unbinding eip:%s to port: %s", d.Id(), err)
 	}
 
+	// the prepaid EIP is deleted by the unsubscription order
+	prePaid := isPrePaid(d)
+	if prePaid {
+		if err := unsubscribePrePaid(d, config, []string{d.Id()}); err != nil {
+			return err
+		}
+	}
+
 	stateConf := &resource.StateChangeConf{
 		Pending:    []string{"ACTIVE"},
 		Target:     []string{"DELETED"},
-		Refresh:    waitForEIPDelete(networkingClient, d.Id()),
+		Refresh:    waitForEIPDelete(networkingClient, d.Id(), !prePaid),
 		Timeout:    timeout,
 		Delay:      5 * time.Second,
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
+	_, err = waitForState(config, stateConf)
 	if err != nil {
 		return fmt.Errorf("Error deleting EIP: %s", err)
 	}
@@ -328,7 +379,7 @@ func resourceBandWidth(d *schema.ResourceData) eips.BandwidthOpts {
 	return bandwidth
 }
 
-func bindToPort(d *schema.ResourceData, eipID string, networkingClient *golangsdk.ServiceClient, timeout time.Duration) error {
+func bindToPort(config *Config, d *schema.ResourceData, eipID string, networkingClient *golangsdk.ServiceClient, timeout time.Duration) error {
 	publicIPRaw := d.Get("publicip").([]interface{})
 	rawMap := publicIPRaw[0].(map[string]interface{})
 	portID, ok := rawMap["port_id"]
@@ -344,10 +395,10 @@ func bindToPort(d *schema.ResourceData, eipID string, networkingClient *golangsd
 	if err != nil {
 		return err
 	}
-	return waitForEIPActive(networkingClient, eipID, timeout)
+	return waitForEIPActive(config, networkingClient, eipID, timeout)
 }
 
-func unbindToPort(d *schema.ResourceData, eipID string, networkingClient *golangsdk.ServiceClient, timeout time.Duration) error {
+func unbindToPort(config *Config, d *schema.ResourceData, eipID string, networkingClient *golangsdk.ServiceClient, timeout time.Duration) error {
 	publicIPRaw := d.Get("publicip").([]interface{})
 	rawMap := publicIPRaw[0].(map[string]interface{})
 	portID, ok := rawMap["port_id"]
@@ -363,7 +414,7 @@ func unbindToPort(d *schema.ResourceData, eipID string, networkingClient *golang
 	if err != nil {
 		return err
 	}
-	return waitForEIPActive(networkingClient, eipID, timeout)
+	return waitForEIPActive(config, networkingClient, eipID, timeout)
 }
 
 func getEIPStatus(networkingClient *golangsdk.ServiceClient, eId string) resource.StateRefreshFunc {
@@ -382,7 +433,8 @@ func getEIPStatus(networkingClient *golangsdk.ServiceClient, eId string) resourc
 	}
 }
 
-func waitForEIPActive(networkingClient *golangsdk.ServiceClient, eipID string, timeout time.Duration) error {
+func waitForEIPActive(config *Config, networkingClient *golangsdk.ServiceClient, eipID string,
+	timeout time.Duration) error {
 	stateConf := &resource.StateChangeConf{
 		Target:     []string{"ACTIVE"},
 		Refresh:    getEIPStatus(networkingClient, eipID),
@@ -391,7 +443,7 @@ func waitForEIPActive(networkingClient *golangsdk.ServiceClient, eipID string, t
 		MinTimeout: 3 * time.Second,
 	}
 
-	_, err := stateConf.WaitForState()
+	_, err := waitForState(config, stateConf)
 	return err
 }
 
@@ -408,7 +460,7 @@ func normalizeEIPStatus(status string) string {
 	return ret
 }
 
-func waitForEIPDelete(networkingClient *golangsdk.ServiceClient, eId string) resource.StateRefreshFunc {
+func waitForEIPDelete(networkingClient *golangsdk.ServiceClient, eId string, deleting bool) resource.StateRefreshFunc {
 	return func() (interface{}, string, error) {
 		log.Printf("[DEBUG] Attempting to delete EIP %s.\n", eId)
 
@@ -421,6 +473,11 @@ func waitForEIPDelete(networkingClient *golangsdk.ServiceClient, eId string) res
 			return e, "ACTIVE", err
 		}
 
+		if !deleting {
+			log.Printf("[DEBUG] EIP %s is still being unsubscribed.\n", eId)
+			return e, "ACTIVE", nil
+		}
+
 		err = eips.Delete(networkingClient, eId).ExtractErr()
 		if err != nil {
 			if _, ok := err.(golangsdk.ErrDefault404); ok {
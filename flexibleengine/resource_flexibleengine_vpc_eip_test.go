@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/chnsz/golangsdk/openstack/networking/v1/eips"
+	th "github.com/chnsz/golangsdk/testhelper"
 )
 
 func TestAccVpcV1EIP_basic(t *testing.T) {
@@ -51,6 +52,40 @@ func TestAccVpcV1EIP_basic(t *testing.T) {
 	})
 }
 
+func TestAccVpcV1EIP_prePaid(t *testing.T) {
+	var eip eips.PublicIp
+	rName := fmt.Sprintf("tf-acc-test-%s", acctest.RandString(5))
+	resourceName := "flexibleengine_vpc_eip.eip_1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckVpcV1EIPDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccVpcV1EIP_prePaid(rName, "false"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckVpcV1EIPExists(resourceName, &eip),
+					resource.TestCheckResourceAttr(resourceName, "charging_mode", "prePaid"),
+					resource.TestCheckResourceAttr(resourceName, "auto_renew", "false"),
+				),
+			},
+			{
+				Config: testAccVpcV1EIP_prePaid(rName, "true"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckVpcV1EIPExists(resourceName, &eip),
+					resource.TestCheckResourceAttr(resourceName, "auto_renew", "true"),
+				),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
 func testAccCheckVpcV1EIPDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	networkingClient, err := config.NetworkingV1Client(OS_REGION_NAME)
@@ -137,3 +172,81 @@ resource "flexibleengine_vpc_eip" "eip_1" {
 }
 `, rName)
 }
+
+func testAccVpcV1EIP_prePaid(rName, autoRenew string) string {
+	return fmt.Sprintf(`
+resource "flexibleengine_vpc_eip" "eip_1" {
+  publicip {
+    type = "5_bgp"
+  }
+  bandwidth {
+    share_type  = "PER"
+    name        = "%s"
+    size        = 5
+    charge_mode = "bandwidth"
+  }
+
+  charging_mode = "prePaid"
+  period_unit   = "month"
+  period        = 1
+  auto_renew    = "%s"
+}
+`, rName, autoRenew)
+}
+
+func TestMockVpcEIPV1(t *testing.T) {
+	config := func(name string, size int) map[string]interface{} {
+		return map[string]interface{}{
+			"publicip": []interface{}{
+				map[string]interface{}{"type": "5_bgp"},
+			},
+			"bandwidth": []interface{}{
+				map[string]interface{}{"name": name, "size": size, "share_type": "PER", "charge_mode": "traffic"},
+			},
+		}
+	}
+
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_vpc_eip",
+		steps: []mockResourceStep{
+			{
+				config: config("bandwidth_1", 5),
+				attrs:  map[string]string{"status": "UNBOUND"},
+			},
+			{
+				config: config("bandwidth_2", 10),
+				attrs:  map[string]string{"bandwidth.0.size": "10"},
+			},
+		},
+		importAttrs: []string{"address"},
+		collections: []string{"publicips", "bandwidths"},
+	})
+}
+
+func TestMockVpcEIPV1_prePaid(t *testing.T) {
+	testMockResource(t, mockResourceCase{
+		resource: "flexibleengine_vpc_eip",
+		steps: []mockResourceStep{
+			{
+				config: map[string]interface{}{
+					"publicip": []interface{}{
+						map[string]interface{}{"type": "5_bgp"},
+					},
+					"bandwidth": []interface{}{
+						map[string]interface{}{"name": "bandwidth_1", "size": 5, "share_type": "PER", "charge_mode": "bandwidth"},
+					},
+					"charging_mode": "prePaid",
+					"period_unit":   "month",
+					"period":        1,
+					"auto_renew":    "true",
+				},
+				attrs: map[string]string{"charging_mode": "prePaid", "auto_renew": "true"},
+				check: func(t *testing.T, server *mockServer, state *terraform.InstanceState) {
+					th.AssertEquals(t, "1", server.objects["publicips"][state.ID]["charging_mode"])
+				},
+			},
+		},
+		importAttrs: []string{"charging_mode", "period_unit", "period", "auto_renew"},
+		collections: []string{"publicips", "bandwidths"},
+	})
+}
@@ -0,0 +1,172 @@
+package flexibleengine
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay   = 1 * time.Second
+	defaultRetryMaxDelay    = 60 * time.Second
+	defaultRetryMaxDuration = 10 * time.Minute
+)
+
+// backoffPolicy describes how long retryRoundTripper waits between two attempts
+// and how much time it may spend retrying a single request in total.
+type backoffPolicy struct {
+	MaxRetries  int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      bool
+	MaxDuration time.Duration
+}
+
+func defaultBackoffPolicy(maxRetries int) *backoffPolicy {
+	return &backoffPolicy{
+		MaxRetries:  maxRetries,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		Jitter:      true,
+		MaxDuration: defaultRetryMaxDuration,
+	}
+}
+
+// delay returns the time to wait before the retry number `attempt` (starting from 0).
+// The exponential delay is capped by MaxDelay, and "full jitter" is applied when Jitter is enabled.
+func (p *backoffPolicy) delay(attempt int) time.Duration {
+	backoff := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if backoff > float64(p.MaxDelay) || math.IsInf(backoff, 0) {
+		backoff = float64(p.MaxDelay)
+	}
+
+	if p.Jitter && backoff > 0 {
+		backoff = rand.Float64() * backoff
+	}
+	return time.Duration(backoff)
+}
+
+// retryRoundTripper satisfies the http.RoundTripper interface and retries the requests
+// which were throttled (429), failed with a server error (5xx) or hit a reset connection.
+type retryRoundTripper struct {
+	Rt     http.RoundTripper
+	Policy *backoffPolicy
+}
+
+// RoundTrip performs a round-trip HTTP request and retries it according to the backoff policy.
+func (rrt *retryRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	// the request can not be sent again if the body is not rewindable
+	if rrt.Policy == nil || rrt.Policy.MaxRetries <= 0 || (request.Body != nil && request.GetBody == nil) {
+		return rrt.Rt.RoundTrip(request)
+	}
+
+	start := time.Now()
+	req := request
+	for attempt := 0; ; attempt++ {
+		response, err := rrt.Rt.RoundTrip(req)
+		if attempt >= rrt.Policy.MaxRetries || !shouldRetryRequest(req.Method, response, err) {
+			return response, err
+		}
+
+		wait := rrt.Policy.delay(attempt)
+		if after, ok := parseRetryAfter(response); ok {
+			wait = after
+		}
+
+		if elapsed := time.Since(start); elapsed+wait > rrt.Policy.MaxDuration {
+			log.Printf("[DEBUG] the retry budget (%s) of %s %s is exhausted", rrt.Policy.MaxDuration,
+				req.Method, req.URL)
+			return response, err
+		}
+
+		if err != nil {
+			log.Printf("[WARN] %s %s failed: %s, retry number %d after %s",
+				req.Method, req.URL, err, attempt+1, wait)
+		} else {
+			log.Printf("[WARN] %s %s received status code %d, retry number %d after %s",
+				req.Method, req.URL, response.StatusCode, attempt+1, wait)
+			// drain and close the body so that the connection can be reused
+			_, _ = io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+
+		req = request.Clone(request.Context())
+		if request.GetBody != nil {
+			body, bodyErr := request.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req.Body = body
+		}
+	}
+}
+
+// shouldRetryRequest reports whether a request is worth sending again.
+// 429 and 503 mean that the request was not handled, so they are retried for all methods.
+// Other server errors and reset connections are only retried for idempotent methods,
+// as the request may have been applied already.
+func shouldRetryRequest(method string, response *http.Response, err error) bool {
+	if err != nil {
+		return isConnectionResetError(err) && isIdempotentMethod(method)
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotentMethod(method)
+	}
+	return false
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isConnectionResetError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	return strings.Contains(err.Error(), "connection reset by peer")
+}
+
+// parseRetryAfter returns the delay carried by the Retry-After header, which can be
+// either a number of seconds or an HTTP date.
+func parseRetryAfter(response *http.Response) (time.Duration, bool) {
+	if response == nil {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(response.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package flexibleengine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestRetryRoundTripper_throttled(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := http.Client{
+		Transport: &retryRoundTripper{
+			Rt: http.DefaultTransport,
+			Policy: &backoffPolicy{
+				MaxRetries:  5,
+				BaseDelay:   time.Hour,
+				MaxDelay:    time.Hour,
+				MaxDuration: time.Minute,
+			},
+		},
+	}
+
+	// the Retry-After header overrides the (huge) base delay
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"name":"test"}`))
+	resp, err := client.Do(req)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, resp.StatusCode)
+	th.AssertEquals(t, 3, count)
+}
+
+func TestRetryRoundTripper_budget(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	policy := &backoffPolicy{
+		MaxRetries:  10,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    100 * time.Millisecond,
+		MaxDuration: 350 * time.Millisecond,
+	}
+	client := http.Client{
+		Transport: &retryRoundTripper{Rt: http.DefaultTransport, Policy: policy},
+	}
+
+	// a GET request is retried until the retry budget is exhausted
+	resp, err := client.Get(server.URL)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusBadGateway, resp.StatusCode)
+	th.AssertEquals(t, 4, count)
+
+	// a POST request is not retried for 502, as it may have been applied already
+	count = 0
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusBadGateway, resp.StatusCode)
+	th.AssertEquals(t, 1, count)
+}
+
+func TestBackoffPolicy_delay(t *testing.T) {
+	policy := &backoffPolicy{
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+	}
+
+	th.AssertEquals(t, time.Second, policy.delay(0))
+	th.AssertEquals(t, 4*time.Second, policy.delay(2))
+	th.AssertEquals(t, 10*time.Second, policy.delay(10))
+	th.AssertEquals(t, 10*time.Second, policy.delay(1000))
+
+	policy.Jitter = true
+	for i := 0; i < 10; i++ {
+		if d := policy.delay(3); d < 0 || d > 8*time.Second {
+			t.Fatalf("the jittered delay %s is out of range", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := parseRetryAfter(resp)
+	th.AssertEquals(t, false, ok)
+
+	resp.Header.Set("Retry-After", "120")
+	wait, ok := parseRetryAfter(resp)
+	th.AssertEquals(t, true, ok)
+	th.AssertEquals(t, 2*time.Minute, wait)
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	wait, ok = parseRetryAfter(resp)
+	th.AssertEquals(t, true, ok)
+	th.AssertEquals(t, time.Duration(0), wait)
+}
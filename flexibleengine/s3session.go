@@ -53,7 +53,7 @@ func computeS3conn(c *Config, region string) (*s3.S3, error) {
 }
 
 func newS3Session(c *Config, osDebug bool) (*session.Session, error) {
-	if c.AccessKey == "" || c.SecretKey == "" {
+	if creds, err := currentCredentials(c); err != nil || creds.AccessKey == "" || creds.SecretKey == "" {
 		return nil, fmt.Errorf("missing credentials for Swift S3 Provider, need access_key and secret_key values for provider")
 	}
 
@@ -0,0 +1,174 @@
+package flexibleengine
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultSharedConfigFile = "~/.flexibleengine/config"
+	defaultProfileName      = "default"
+)
+
+// sharedProfile is a named profile in the shared config file
+type sharedProfile struct {
+	Region        string `yaml:"region" ini:"region"`
+	AuthURL       string `yaml:"auth_url" ini:"auth_url"`
+	DomainID      string `yaml:"domain_id" ini:"domain_id"`
+	DomainName    string `yaml:"domain_name" ini:"domain_name"`
+	TenantID      string `yaml:"tenant_id" ini:"tenant_id"`
+	TenantName    string `yaml:"tenant_name" ini:"tenant_name"`
+	AccessKey     string `yaml:"access_key" ini:"access_key"`
+	SecretKey     string `yaml:"secret_key" ini:"secret_key"`
+	SecurityToken string `yaml:"security_token" ini:"security_token"`
+	UserID        string `yaml:"user_id" ini:"user_id"`
+	UserName      string `yaml:"user_name" ini:"user_name"`
+	Password      string `yaml:"password" ini:"password"`
+}
+
+// sharedConfig is the YAML format of the shared config file, which likes:
+//
+//	current: sandbox
+//	profiles:
+//	  sandbox:
+//	    region: eu-west-0
+//	    access_key: xxx
+//	    secret_key: xxx
+type sharedConfig struct {
+	Current  string                   `yaml:"current"`
+	Profiles map[string]sharedProfile `yaml:"profiles"`
+}
+
+// loadSharedProfile reads the profile from a YAML or INI shared config file.
+// The INI file uses the profile names as section names.
+func loadSharedProfile(path, name string) (*sharedProfile, error) {
+	if path == "" {
+		path = defaultSharedConfigFile
+	}
+	profilePath, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading shared config file %s: %s", profilePath, err)
+	}
+
+	if isINIConfig(profilePath, data) {
+		return parseINIProfile(data, name)
+	}
+	return parseYAMLProfile(data, name)
+}
+
+func isINIConfig(path string, data []byte) bool {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".ini" || ext == ".conf" {
+		return true
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		// the first valid line of an INI file must be a section
+		return strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")
+	}
+	return false
+}
+
+func parseINIProfile(data []byte, name string) (*sharedProfile, error) {
+	if name == "" {
+		name = defaultProfileName
+	}
+
+	cfg, err := ini.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing shared config file: %s", err)
+	}
+
+	section, err := cfg.GetSection(name)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s was not found in shared config file", name)
+	}
+
+	var profile sharedProfile
+	if err := section.MapTo(&profile); err != nil {
+		return nil, fmt.Errorf("error parsing profile %s: %s", name, err)
+	}
+	return &profile, nil
+}
+
+func parseYAMLProfile(data []byte, name string) (*sharedProfile, error) {
+	var cfg sharedConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing shared config file: %s", err)
+	}
+
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" {
+		name = defaultProfileName
+	}
+
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s was not found in shared config file", name)
+	}
+	return &profile, nil
+}
+
+// applySharedProfile fills the settings which were specified neither in the provider block
+// nor by the environment variables. The credentials of the profile are used as a whole, they
+// are skipped if any authentication method has been configured.
+func applySharedProfile(c *Config, profile *sharedProfile) {
+	type profileField struct {
+		target *string
+		value  string
+	}
+	fields := []profileField{
+		{&c.Region, profile.Region},
+		{&c.IdentityEndpoint, profile.AuthURL},
+	}
+
+	if hasExplicitCredentials(c) {
+		log.Printf("[DEBUG] the credentials of the shared profile %s are skipped", c.Profile)
+	} else {
+		fields = append(fields, []profileField{
+			{&c.DomainID, profile.DomainID},
+			{&c.DomainName, profile.DomainName},
+			{&c.TenantID, profile.TenantID},
+			{&c.TenantName, profile.TenantName},
+			{&c.UserID, profile.UserID},
+			{&c.Username, profile.UserName},
+			{&c.Password, profile.Password},
+			{&c.AccessKey, profile.AccessKey},
+			{&c.SecretKey, profile.SecretKey},
+			{&c.SecurityToken, profile.SecurityToken},
+		}...)
+	}
+
+	for _, f := range fields {
+		if *f.target == "" {
+			*f.target = f.value
+		}
+	}
+
+	log.Printf("[DEBUG] the shared profile %s was loaded", c.Profile)
+}
+
+// hasExplicitCredentials reports whether an authentication method is configured by the provider block
+// or the environment variables: AK/SK, token, password, assume_role or a credential source.
+func hasExplicitCredentials(c *Config) bool {
+	ext := getConfigExtension(c)
+	return c.AccessKey != "" || c.SecretKey != "" || c.Token != "" || c.Password != "" ||
+		c.AssumeRoleAgency != "" || ext.CredentialProcess != "" || ext.CredentialFile != ""
+}
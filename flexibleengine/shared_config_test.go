@@ -0,0 +1,125 @@
+package flexibleengine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	th "github.com/chnsz/golangsdk/testhelper"
+)
+
+func TestLoadSharedProfile_yaml(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := `
+current: sandbox
+profiles:
+  sandbox:
+    region: eu-west-0
+    access_key: sandbox-ak
+    secret_key: sandbox-sk
+  production:
+    region: eu-west-0
+    tenant_name: eu-west-0_prod
+    user_name: admin
+    password: secret
+`
+	th.AssertNoErr(t, os.WriteFile(path, []byte(content), 0600))
+
+	// the current profile is used if the profile name is not specified
+	profile, err := loadSharedProfile(path, "")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "sandbox-ak", profile.AccessKey)
+
+	profile, err = loadSharedProfile(path, "production")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "eu-west-0_prod", profile.TenantName)
+	th.AssertEquals(t, "admin", profile.UserName)
+
+	_, err = loadSharedProfile(path, "unknown")
+	th.AssertEquals(t, true, err != nil)
+}
+
+func TestLoadSharedProfile_ini(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := `
+# the profiles of FlexibleEngine
+[default]
+region     = eu-west-0
+access_key = default-ak
+secret_key = default-sk
+auth_url   = https://iam.eu-west-0.example.internal/v3
+`
+	th.AssertNoErr(t, os.WriteFile(path, []byte(content), 0600))
+
+	profile, err := loadSharedProfile(path, "")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "eu-west-0", profile.Region)
+	th.AssertEquals(t, "default-ak", profile.AccessKey)
+	th.AssertEquals(t, "https://iam.eu-west-0.example.internal/v3", profile.AuthURL)
+}
+
+func TestApplySharedProfile(t *testing.T) {
+	profile := &sharedProfile{
+		Region:     "eu-west-0",
+		TenantName: "eu-west-0_dev",
+		AccessKey:  "profile-ak",
+		SecretKey:  "profile-sk",
+	}
+
+	// the explicit settings take precedence over the profile
+	cfg := &Config{
+		Region:    "eu-west-1",
+		AccessKey: "explicit-ak",
+		SecretKey: "explicit-sk",
+	}
+	applySharedProfile(cfg, profile)
+	th.AssertEquals(t, "eu-west-1", cfg.Region)
+	th.AssertEquals(t, "explicit-ak", cfg.AccessKey)
+	th.AssertEquals(t, "explicit-sk", cfg.SecretKey)
+
+	// the settings which are not specified are filled by the profile
+	cfg = &Config{}
+	applySharedProfile(cfg, profile)
+	th.AssertEquals(t, "eu-west-0", cfg.Region)
+	th.AssertEquals(t, "eu-west-0_dev", cfg.TenantName)
+	th.AssertEquals(t, "profile-ak", cfg.AccessKey)
+	th.AssertEquals(t, "profile-sk", cfg.SecretKey)
+}
+
+func TestApplySharedProfile_credentials(t *testing.T) {
+	profile := &sharedProfile{
+		Region:     "eu-west-0",
+		DomainName: "profile-domain",
+		TenantName: "eu-west-0_dev",
+		UserName:   "profile-user",
+		Password:   "profile-password",
+	}
+
+	// the password of the profile is not mixed into the explicit AK/SK
+	cfg := &Config{AccessKey: "explicit-ak", SecretKey: "explicit-sk"}
+	applySharedProfile(cfg, profile)
+	th.AssertEquals(t, "eu-west-0", cfg.Region)
+	th.AssertEquals(t, "", cfg.Username)
+	th.AssertEquals(t, "", cfg.Password)
+	th.AssertEquals(t, "", cfg.DomainName)
+	th.AssertEquals(t, "", cfg.TenantName)
+
+	// nor into the token, assume_role or the credential source
+	for _, cfg := range []*Config{
+		{Token: "explicit-token"},
+		{AssumeRoleAgency: "agency"},
+		{Metadata: &configExtension{CredentialProcess: "print-credentials"}},
+	} {
+		applySharedProfile(cfg, profile)
+		th.AssertEquals(t, "eu-west-0", cfg.Region)
+		th.AssertEquals(t, "", cfg.Username)
+		th.AssertEquals(t, "", cfg.Password)
+	}
+
+	// the credentials of the profile are used as a whole otherwise
+	cfg = &Config{}
+	applySharedProfile(cfg, profile)
+	th.AssertEquals(t, "profile-user", cfg.Username)
+	th.AssertEquals(t, "profile-password", cfg.Password)
+	th.AssertEquals(t, "profile-domain", cfg.DomainName)
+}
@@ -63,6 +63,53 @@ func GetRegion(d *schema.ResourceData, config *Config) string {
 	return config.Region
 }
 
+// GetProjectConfig returns the config scoped to the region and the project_id that was specified in the resource.
+// If a project_id was not set, the config of the region is returned and the project is resolved by the region name.
+func GetProjectConfig(d *schema.ResourceData, config *Config) (*Config, error) {
+	config, err := getRegionConfig(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, ok := d.GetOk("project_id")
+	if !ok {
+		return config, nil
+	}
+
+	return projectConfig(config, GetRegion(d, config), projectID.(string))
+}
+
+// getRegionConfig returns the config whose endpoints are generated from endpoint_template for the region
+// of the resource, the provider-level config is returned if the template is not specified.
+func getRegionConfig(d *schema.ResourceData, config *Config) (*Config, error) {
+	return regionConfig(config, GetRegion(d, config))
+}
+
+// projectImportIDSeparator separates the project from the import ID of a resource in another project,
+// it is not used by the import IDs which are separated by slashes or commas.
+const projectImportIDSeparator = "::"
+
+// parseProjectImportID accepts the ID in the format of <project_id>::<import_id> to import a resource
+// in another project, the import ID is left to the importer of the resource.
+func parseProjectImportID(d *schema.ResourceData) {
+	parts := strings.SplitN(d.Id(), projectImportIDSeparator, 2)
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" && !strings.ContainsAny(parts[0], "/,") {
+		d.Set("project_id", parts[0])
+		d.SetId(parts[1])
+	}
+}
+
+// waitForState waits for the target status of stateConf, the Delay, MinTimeout and PollInterval are
+// replaced by the StateWaitInterval of the config if it is not zero.
+func waitForState(config *Config, stateConf *resource.StateChangeConf) (interface{}, error) {
+	if interval := getConfigExtension(config).StateWaitInterval; interval > 0 {
+		stateConf.Delay = interval
+		stateConf.MinTimeout = interval
+		stateConf.PollInterval = interval
+	}
+	return stateConf.WaitForState()
+}
+
 func checkForRetryableError(err error) *resource.RetryError {
 	switch errCode := err.(type) {
 	case golangsdk.ErrDefault500: